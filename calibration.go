@@ -0,0 +1,417 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+)
+
+/**
+ * Calibration fitting
+ *   When the same units are logged at several setpoints of the test environment,
+ *   we can fit a linear correction (corrected = offset + gain * measured) per sensor
+ *   by least squares on the mean reading at each setpoint. Units failing only
+ *   because of a constant bias can then be recalibrated instead of downgraded.
+ */
+
+// A setpoint is one run of the test environment at known reference values
+type Setpoint struct {
+	Ref     ReferenceInterface
+	Sensors []SensorInterface
+}
+
+type CalibrationResult struct {
+	SensorName string
+	SensorType string
+	Offset     float64
+	Gain       float64
+
+	// Setpoints the sensor was logged at, by their index in the log (from 0)
+	Setpoints []int
+
+	// Residuals (reference - corrected mean) for each setpoint the sensor was logged at
+	Residuals []float64
+
+	// Ratings for each setpoint the sensor was logged at, before and after correction
+	RatingsBefore []Rating
+	RatingsAfter  []Rating
+}
+
+/**
+ * Fitting a linear correction mapping the measured values onto the reference values
+ */
+func FitLinearCalibration(measured []float64, reference []float64) (float64, float64, error) {
+	nbrPoints := len(measured)
+	if nbrPoints == 0 || nbrPoints != len(reference) {
+		return 0, 0, errors.New(Translate("Error while fitting the calibration: measured and reference points don't match"))
+	}
+
+	var sumX, sumY float64
+	for i := 0; i < nbrPoints; i++ {
+		sumX += measured[i]
+		sumY += reference[i]
+	}
+	avgX := sumX / float64(nbrPoints)
+	avgY := sumY / float64(nbrPoints)
+
+	var sxx, sxy float64
+	for i := 0; i < nbrPoints; i++ {
+		sxx += math.Pow(measured[i]-avgX, 2)
+		sxy += (measured[i] - avgX) * (reference[i] - avgY)
+	}
+
+	// With a single setpoint (or identical readings at every setpoint) the gain can't
+	// be estimated, so we only correct the bias
+	if sxx == 0 {
+		return avgY - avgX, 1, nil
+	}
+
+	gain := sxy / sxx
+	return avgY - gain*avgX, gain, nil
+}
+
+/**
+ * Fitting a correction for every sensor found in the setpoints. Both channels of
+ * combo sensors are fitted, under their "<name>:temperature" and "<name>:humidity"
+ * entries of the calibration tables
+ */
+func FitCalibrations(setpoints []Setpoint) []CalibrationResult {
+	channels := make([]Setpoint, len(setpoints))
+	for i, setpoint := range setpoints {
+		channels[i] = Setpoint{Ref: setpoint.Ref, Sensors: getCalibrationChannels(setpoint.Sensors)}
+	}
+	setpoints = channels
+
+	// Keep track of the order in which we discover sensors so the output is stable
+	var names []string
+	types := make(map[string]string)
+
+	for _, setpoint := range setpoints {
+		for _, sensor := range setpoint.Sensors {
+			if _, found := types[sensor.GetName()]; found {
+				continue
+			}
+			names = append(names, sensor.GetName())
+			types[sensor.GetName()] = sensor.GetType()
+		}
+	}
+
+	results := make([]CalibrationResult, 0, len(names))
+	for _, name := range names {
+		result, err := fitSensorCalibration(name, types[name], setpoints)
+		if err != nil {
+			logger.Info(err.Error())
+			continue
+		}
+		results = append(results, result)
+	}
+
+	return results
+}
+
+/**
+ * Exporting coefficients for the flashing station, one sensor per line: <name> <offset> <gain>
+ */
+func ExportCalibrations(w io.Writer, results []CalibrationResult) error {
+	if _, err := fmt.Fprintln(w, "# sensor offset gain"); err != nil {
+		return err
+	}
+
+	for _, result := range results {
+		_, err := fmt.Fprintf(w, "%s %.6f %.6f\n", QuoteToken(result.SensorName), result.Offset, result.Gain)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Sensors fitted apart: the channels of combo sensors are named after their calibration table entries
+func getCalibrationChannels(sensors []SensorInterface) []SensorInterface {
+	channels := make([]SensorInterface, 0, len(sensors))
+	for _, sensor := range sensors {
+		combo, isCombo := sensor.(*CombinedSensor)
+		if !isCombo {
+			channels = append(channels, sensor)
+			continue
+		}
+
+		channels = append(channels,
+			newChannelSensor(combo.temperature, combo.GetName()+CalibrationChannelSeparator+TemperatureChannel),
+			newChannelSensor(combo.humidity, combo.GetName()+CalibrationChannelSeparator+HumidityChannel))
+	}
+
+	return channels
+}
+
+func newChannelSensor(channel *Sensor, name string) SensorInterface {
+	return &Sensor{
+		sensorType:       channel.GetType(),
+		sensorName:       name,
+		sensorValues:     channel.GetValues(),
+		sensorResolution: channel.GetResolution(),
+		sensorDrift:      channel.GetDrift(),
+	}
+}
+
+func fitSensorCalibration(name string, sType string, setpoints []Setpoint) (CalibrationResult, error) {
+	result := CalibrationResult{
+		SensorName: name,
+		SensorType: sType,
+	}
+
+	var measured, reference []float64
+	var sensors []SensorInterface
+	var refs []ReferenceInterface
+
+	for index, setpoint := range setpoints {
+		for _, sensor := range setpoint.Sensors {
+			if sensor.GetName() != name || len(sensor.GetValues()) == 0 {
+				continue
+			}
+
+			refValue, valid := getReferenceValue(setpoint.Ref, sType)
+			if !valid {
+				return result, errors.New(Translate("Can't calibrate sensor %s: no reference for sensor type %s", name, sType))
+			}
+
+			measured = append(measured, sensor.GetAverageValue())
+			reference = append(reference, refValue)
+			sensors = append(sensors, sensor)
+			refs = append(refs, setpoint.Ref)
+			result.Setpoints = append(result.Setpoints, index)
+		}
+	}
+
+	if len(measured) == 0 {
+		return result, errors.New(Translate("Can't calibrate sensor %s: no readings found", name))
+	}
+
+	offset, gain, err := FitLinearCalibration(measured, reference)
+	if err != nil {
+		return result, err
+	}
+	result.Offset = offset
+	result.Gain = gain
+
+	for i, sensor := range sensors {
+		corrected := newCorrectedSensor(sensor, offset, gain)
+
+		result.Residuals = append(result.Residuals, reference[i]-corrected.GetAverageValue())
+		// Sensor types were checked against the reference above, so we can't get a rating error here
+		ratingBefore, _ := sensor.CalculateRating(refs[i])
+		ratingAfter, _ := corrected.CalculateRating(refs[i])
+
+		result.RatingsBefore = append(result.RatingsBefore, ratingBefore)
+		result.RatingsAfter = append(result.RatingsAfter, ratingAfter)
+	}
+
+	return result, nil
+}
+
+func newCorrectedSensor(sensor SensorInterface, offset float64, gain float64) SensorInterface {
+	correction := NewLinearCorrection(offset, gain)
+
+	values := sensor.GetValues()
+	corrected := make([]float64, len(values))
+	for i, value := range values {
+		corrected[i] = correction.Apply(value)
+	}
+
+	return &Sensor{
+		sensorType:       sensor.GetType(),
+		sensorName:       sensor.GetName(),
+		sensorValues:     corrected,
+		sensorResolution: sensor.GetResolution(),
+		sensorDrift:      sensor.GetDrift(),
+	}
+}
+
+func getReferenceValue(ref ReferenceInterface, sType string) (float64, bool) {
+	switch sType {
+	case Thermometer:
+		return ref.GetRefTemperature(), true
+	case HumiditySensor:
+		return ref.GetRefHumidity(), true
+	case PressureSensor:
+		return ref.GetRefPressure(), ref.HasRefPressure()
+	case CO2Sensor:
+		return ref.GetRefCO2(), ref.HasRefCO2()
+	}
+
+	return 0, false
+}
+
+/**
+ * Applying calibration coefficients
+ *   Shipped units carry stored corrections. A calibration table maps sensor names to
+ *   their correction, which is applied to every reading as it is appended to the sensor,
+ *   so statistics and ratings are computed on the corrected values.
+ */
+type CorrectionInterface interface {
+	Apply(value float64) float64
+}
+
+type LinearCorrection struct {
+	offset float64
+	gain   float64
+}
+
+func NewLinearCorrection(offset float64, gain float64) CorrectionInterface {
+	return &LinearCorrection{
+		offset: offset,
+		gain:   gain,
+	}
+}
+
+func (lc *LinearCorrection) Apply(value float64) float64 {
+	return lc.offset + lc.gain*value
+}
+
+type PolynomialCorrection struct {
+	// Coefficients in increasing order: c0 + c1*x + c2*x^2 + ...
+	coefficients []float64
+}
+
+func NewPolynomialCorrection(coefficients []float64) CorrectionInterface {
+	return &PolynomialCorrection{
+		coefficients: coefficients,
+	}
+}
+
+func (pc *PolynomialCorrection) Apply(value float64) float64 {
+	// Horner's method
+	var res float64
+	for i := len(pc.coefficients) - 1; i >= 0; i-- {
+		res = res*value + pc.coefficients[i]
+	}
+
+	return res
+}
+
+type CalibrationTable map[string]CorrectionInterface
+
+// Separator of the name of a combo sensor and of its channel in a calibration table
+const CalibrationChannelSeparator = ":"
+
+// Channels of combo sensors, the temperature channel also being corrected by the entry of the sensor name
+const TemperatureChannel = "temperature"
+const HumidityChannel = "humidity"
+
+/**
+ * Setting the correction the table holds for a sensor, if any. The channels of combo
+ * sensors are corrected by the "<name>:temperature" and "<name>:humidity" entries
+ */
+func (t CalibrationTable) Calibrate(sensor SensorInterface) {
+	name := sensor.GetName()
+	if correction, found := t[name]; found {
+		sensor.SetCorrection(correction)
+	}
+
+	combo, isCombo := sensor.(*CombinedSensor)
+	if !isCombo {
+		return
+	}
+	if correction, found := t[name+CalibrationChannelSeparator+TemperatureChannel]; found {
+		combo.SetCorrection(correction)
+	}
+	if correction, found := t[name+CalibrationChannelSeparator+HumidityChannel]; found {
+		combo.SetHumidityCorrection(correction)
+	}
+}
+
+/**
+ * Reading a calibration table, one sensor per line, either
+ *   <name> <offset> <gain>            (as exported by ExportCalibrations)
+ *   <name> poly <c0> <c1> ... <cn>
+ * where the name of a combo sensor may be followed by :temperature or :humidity.
+ * Names are quoted like in the logs. Blank lines and comments are ignored
+ */
+func ReadCalibrationTable(r io.Reader) (CalibrationTable, error) {
+	table := make(CalibrationTable)
+
+	scan := bufio.NewScanner(r)
+	lineNumber := 0
+	for scan.Scan() {
+		lineNumber++
+
+		correction, name, err := parseCalibrationText(scan.Text())
+		if err != nil {
+			return nil, errors.New(Translate("Error while parsing the calibration table at line %d: %s", lineNumber, err.Error()))
+		}
+		if correction != nil {
+			table[name] = correction
+		}
+	}
+
+	if err := scan.Err(); err != nil {
+		return nil, err
+	}
+
+	return table, nil
+}
+
+func LoadCalibrationTable(path string) (CalibrationTable, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ReadCalibrationTable(file)
+}
+
+// Names are quoted like in the logs, blank lines and comments give no correction
+func parseCalibrationText(line string) (CorrectionInterface, string, error) {
+	fields, err := Tokenize(line)
+	if err != nil || len(fields) == 0 {
+		return nil, "", err
+	}
+
+	return parseCalibrationLine(fields)
+}
+
+func parseCalibrationLine(fields []string) (CorrectionInterface, string, error) {
+	if len(fields) < 3 {
+		return nil, "", errors.New(Translate("not enough elements"))
+	}
+
+	name := fields[0]
+
+	if fields[1] == "poly" {
+		coefficients, err := parseFloats(fields[2:])
+		if err != nil {
+			return nil, name, err
+		}
+		return NewPolynomialCorrection(coefficients), name, nil
+	}
+
+	if len(fields) != 3 {
+		return nil, name, errors.New(Translate("too many elements for an offset/gain correction"))
+	}
+
+	coefficients, err := parseFloats(fields[1:])
+	if err != nil {
+		return nil, name, err
+	}
+
+	return NewLinearCorrection(coefficients[0], coefficients[1]), name, nil
+}
+
+func parseFloats(fields []string) ([]float64, error) {
+	values := make([]float64, len(fields))
+	for i, field := range fields {
+		value, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+
+	return values, nil
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFitLinearCalibration_HappyPath(t *testing.T) {
+	measured := []float64{10, 20, 30}
+	reference := []float64{21, 41, 61}
+
+	offset, gain, err := FitLinearCalibration(measured, reference)
+
+	assert.Nil(t, err)
+	assert.InDelta(t, 1.0, offset, 1e-9)
+	assert.InDelta(t, 2.0, gain, 1e-9)
+}
+
+func TestFitLinearCalibration_SinglePointOnlyCorrectsBias(t *testing.T) {
+	offset, gain, err := FitLinearCalibration([]float64{71.5}, []float64{70.0})
+
+	assert.Nil(t, err)
+	assert.InDelta(t, -1.5, offset, 1e-9)
+	assert.Equal(t, 1.0, gain)
+}
+
+func TestFitLinearCalibration_NoPoints(t *testing.T) {
+	_, _, err := FitLinearCalibration(nil, nil)
+
+	assert.NotNil(t, err)
+	assert.Equal(t, "Error while fitting the calibration: measured and reference points don't match", err.Error())
+}
+
+func TestFitCalibrations_BiasedThermometer(t *testing.T) {
+	// temp-1 reads consistently 1 degree too high: precise before correction, ultra precise after
+	setpoints := []Setpoint{
+		{
+			Ref: NewRefTemperatureHumidity(60.0, 45.0),
+			Sensors: []SensorInterface{
+				&Sensor{sensorType: Thermometer, sensorName: "temp-1", sensorValues: []float64{60.9, 61.1}},
+			},
+		},
+		{
+			Ref: NewRefTemperatureHumidity(80.0, 45.0),
+			Sensors: []SensorInterface{
+				&Sensor{sensorType: Thermometer, sensorName: "temp-1", sensorValues: []float64{80.9, 81.1}},
+			},
+		},
+	}
+
+	res := FitCalibrations(setpoints)
+
+	assert.Equal(t, 1, len(res))
+	assert.Equal(t, "temp-1", res[0].SensorName)
+	assert.InDelta(t, -1.0, res[0].Offset, 1e-9)
+	assert.InDelta(t, 1.0, res[0].Gain, 1e-9)
+	assert.InDelta(t, 0, res[0].Residuals[0], 1e-9)
+	assert.InDelta(t, 0, res[0].Residuals[1], 1e-9)
+	assert.Equal(t, []Rating{ThermometerPrecise, ThermometerPrecise}, res[0].RatingsBefore)
+	assert.Equal(t, []Rating{ThermometerUltraPrecise, ThermometerUltraPrecise}, res[0].RatingsAfter)
+}
+
+func TestFitCalibrations_SetpointsOfTheSensor(t *testing.T) {
+	setpoints := []Setpoint{
+		{Ref: NewRefTemperatureHumidity(60.0, 45.0), Sensors: []SensorInterface{&Sensor{sensorType: Thermometer, sensorName: "temp-2", sensorValues: []float64{60.0}}}},
+		{Ref: NewRefTemperatureHumidity(70.0, 45.0), Sensors: []SensorInterface{&Sensor{sensorType: Thermometer, sensorName: "temp-1", sensorValues: []float64{70.9, 71.1}}}},
+		{Ref: NewRefTemperatureHumidity(80.0, 45.0), Sensors: []SensorInterface{&Sensor{sensorType: Thermometer, sensorName: "temp-2", sensorValues: []float64{80.0}}}},
+	}
+
+	res := FitCalibrations(setpoints)
+
+	// Residuals and ratings are given for the setpoints each sensor was logged at
+	assert.Equal(t, "temp-2", res[0].SensorName)
+	assert.Equal(t, []int{0, 2}, res[0].Setpoints)
+	assert.Equal(t, "temp-1", res[1].SensorName)
+	assert.Equal(t, []int{1}, res[1].Setpoints)
+
+	var out bytes.Buffer
+	assert.Nil(t, RunCalibration(&out, []string{
+		"reference 60.0 45.0", "thermometer temp-2", "2007-04-05T22:00 temp-2 60.0",
+		"reference 70.0 45.0", "thermometer temp-1", "2007-04-05T22:00 temp-1 70.9", "2007-04-05T22:01 temp-1 71.1",
+		"reference 80.0 45.0", "thermometer temp-2", "2007-04-05T22:00 temp-2 80.0",
+	}, filepath.Join(t.TempDir(), "calibration.txt"), outputProfiles[InternalProfile]))
+	assert.Contains(t, out.String(), "temp-1: offset -1.0000, gain 1.0000\n  setpoint 2: ")
+	assert.Contains(t, out.String(), "  setpoint 3: ")
+}
+
+func TestFitCalibrations_SkipsInvalidSensorType(t *testing.T) {
+	setpoints := []Setpoint{
+		{
+			Ref: NewRefTemperatureHumidity(70.0, 45.0),
+			Sensors: []SensorInterface{
+				&Sensor{sensorType: "Potato", sensorName: "Potato-sensor", sensorValues: []float64{1}},
+				&Sensor{sensorType: HumiditySensor, sensorName: "hum-1", sensorValues: []float64{46}},
+			},
+		},
+	}
+
+	res := FitCalibrations(setpoints)
+
+	assert.Equal(t, 1, len(res))
+	assert.Equal(t, "hum-1", res[0].SensorName)
+	assert.InDelta(t, -1.0, res[0].Offset, 1e-9)
+}
+
+func TestFitCalibrations_ComboChannels(t *testing.T) {
+	// combo-1 reads 0.9 degrees and 1% too high
+	low := NewCombinedSensor("combo-1")
+	assert.Nil(t, low.AppendData([]string{"2007-04-05T22:00", "combo-1", "60.9", "46.0"}))
+	high := NewCombinedSensor("combo-1")
+	assert.Nil(t, high.AppendData([]string{"2007-04-05T23:00", "combo-1", "80.9", "46.0"}))
+	setpoints := []Setpoint{
+		{Ref: NewRefTemperatureHumidity(60.0, 45.0), Sensors: []SensorInterface{low}},
+		{Ref: NewRefTemperatureHumidity(80.0, 45.0), Sensors: []SensorInterface{high}},
+	}
+
+	res := FitCalibrations(setpoints)
+
+	// Each channel gets the correction of its entry in the calibration tables
+	assert.Equal(t, 2, len(res))
+	assert.Equal(t, "combo-1:temperature", res[0].SensorName)
+	assert.Equal(t, Thermometer, res[0].SensorType)
+	assert.InDelta(t, -0.9, res[0].Offset, 1e-9)
+	assert.InDelta(t, 1.0, res[0].Gain, 1e-9)
+	assert.Equal(t, "combo-1:humidity", res[1].SensorName)
+	assert.Equal(t, HumiditySensor, res[1].SensorType)
+	assert.InDelta(t, -1.0, res[1].Offset, 1e-9)
+	assert.Equal(t, []Rating{RatingAccepted, RatingAccepted}, res[1].RatingsAfter)
+}
+
+func TestExportCalibrations_HappyPath(t *testing.T) {
+	var out bytes.Buffer
+
+	results := []CalibrationResult{
+		{SensorName: "temp-1", Offset: -1, Gain: 1.0025},
+		{SensorName: "hum-1", Offset: 0.5, Gain: 1},
+	}
+
+	err := ExportCalibrations(&out, results)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "# sensor offset gain\ntemp-1 -1.000000 1.002500\nhum-1 0.500000 1.000000\n", out.String())
+}
+
+func TestLinearCorrection_Apply(t *testing.T) {
+	correction := NewLinearCorrection(-1.5, 2)
+
+	assert.Equal(t, 18.5, correction.Apply(10))
+}
+
+func TestPolynomialCorrection_Apply(t *testing.T) {
+	// 1 + 2x + 3x^2
+	correction := NewPolynomialCorrection([]float64{1, 2, 3})
+
+	assert.Equal(t, 1.0, correction.Apply(0))
+	assert.Equal(t, 17.0, correction.Apply(2))
+}
+
+func TestReadCalibrationTable_HappyPath(t *testing.T) {
+	var in bytes.Buffer
+	in.WriteString("# sensor offset gain\n\ntemp-1 -1.000000 1.002500\nhum-1 poly 0.5 1 0.01\n")
+
+	res, err := ReadCalibrationTable(&in)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(res))
+	assert.Equal(t, NewLinearCorrection(-1, 1.0025), res["temp-1"])
+	assert.Equal(t, NewPolynomialCorrection([]float64{0.5, 1, 0.01}), res["hum-1"])
+}
+
+func TestReadCalibrationTable_ExportRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+
+	ExportCalibrations(&buf, []CalibrationResult{{SensorName: "temp-1", Offset: -0.25, Gain: 1.5}})
+	res, err := ReadCalibrationTable(&buf)
+
+	assert.Nil(t, err)
+	assert.Equal(t, NewLinearCorrection(-0.25, 1.5), res["temp-1"])
+}
+
+func TestReadCalibrationTable_ExportRoundTripQuotedNames(t *testing.T) {
+	var buf bytes.Buffer
+	names := []string{"temp 1", `probe "B"`, `rack\3`, "#7"}
+
+	var results []CalibrationResult
+	for i, name := range names {
+		results = append(results, CalibrationResult{SensorName: name, Offset: float64(i), Gain: 1})
+	}
+	assert.Nil(t, ExportCalibrations(&buf, results))
+	assert.Contains(t, buf.String(), "\n\"temp 1\" 0.000000 1.000000\n")
+
+	res, err := ReadCalibrationTable(&buf)
+
+	assert.Nil(t, err)
+	assert.Equal(t, len(names), len(res))
+	for i, name := range names {
+		assert.Equal(t, NewLinearCorrection(float64(i), 1), res[name], name)
+	}
+}
+
+func TestReadCalibrationTable_NotEnoughElements(t *testing.T) {
+	var in bytes.Buffer
+	in.WriteString("temp-1 -1\n")
+
+	_, err := ReadCalibrationTable(&in)
+
+	assert.NotNil(t, err)
+	assert.Equal(t, "Error while parsing the calibration table at line 1: not enough elements", err.Error())
+}
+
+func TestReadCalibrationTable_NotANumber(t *testing.T) {
+	var in bytes.Buffer
+	in.WriteString("temp-1 -1 1\nhum-1 poly 1 potato\n")
+
+	_, err := ReadCalibrationTable(&in)
+
+	assert.NotNil(t, err)
+	assert.Equal(t, "Error while parsing the calibration table at line 2: strconv.ParseFloat: parsing \"potato\": invalid syntax", err.Error())
+}
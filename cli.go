@@ -0,0 +1,1023 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+/**
+ * Command line interface
+ *   sensor <command> [flags] [arguments]
+ *   Commands and their flags are declared once in Commands, the help texts are
+ *   generated from these declarations.
+ */
+
+// Version of the tool, set at build time with -ldflags "-X main.Version=1.2.0"
+var Version = "dev"
+
+// Command run when none is given, so "sensor < log" keeps working
+const DefaultCommand = "analyze"
+
+// Command run when none is given and the log would be typed in a terminal
+const InteractiveCommand = "interactive"
+
+// Reading the log from stdin, or writing the results to stdout
+const StdStream = "-"
+
+type Command struct {
+	Name      string
+	Arguments string
+	Summary   string
+	Flags     func(fs *flag.FlagSet, o *CLIOptions)
+	Run       func(ctx *CommandContext, args []string) int
+}
+
+// Values of the flags, commands only declare the ones they use
+type CLIOptions struct {
+	Input              string
+	Output             string
+	Format             string
+	CSVColumns         string
+	DecimalSeparator   string
+	ThousandsSeparator string
+	Profile            string
+	Labels             string
+	Lang               string
+	Calibration        string
+	ExportCalibration  string
+	Verbosity          string
+	JSON               bool
+
+	// Line sources
+	SourceTimeout time.Duration
+	SourceRetries int
+
+	// Watch mode
+	Interval         time.Duration
+	Target           string
+	ExpectedReadings int
+
+	// Spool directory
+	ReportFormats string
+	Settle        time.Duration
+	Archive       string
+	Failed        string
+
+	// HTTP API
+	Listen        string
+	JobsDir       string
+	JobWorkers    int
+	JobsRetention time.Duration
+
+	// MQTT mode
+	SensorTopic    string
+	ReferenceTopic string
+	EndTopic       string
+	Topics         string
+	ClientID       string
+	Username       string
+	PasswordFile   string
+	KeepAlive      time.Duration
+}
+
+type CommandContext struct {
+	Options *CLIOptions
+	Stdin   io.Reader
+	Stdout  io.Writer
+	Stderr  io.Writer
+}
+
+var Commands []Command
+
+func init() {
+	// Declared here as the help command refers to the list of commands
+	Commands = []Command{
+		{
+			Name:    "analyze",
+			Summary: "Grade the sensors of a log",
+			Flags: func(fs *flag.FlagSet, o *CLIOptions) {
+				addInputFlags(fs, o, StdStream)
+				addOutputFlags(fs, o)
+				fs.StringVar(&o.Calibration, "calibration", "", "apply the corrections of this calibration table to the readings")
+				fs.StringVar(&o.ExportCalibration, "export-calibration", "", "fit offset/gain corrections on a multi-setpoint log and export them to this file")
+				fs.BoolVar(&o.JSON, "json", false, "write the results as a JSON report, along with the run metadata")
+				addSourceFlags(fs, o)
+			},
+			Run: runAnalyze,
+		},
+		{
+			Name:      "validate",
+			Arguments: "[file]",
+			Summary:   "Check a log against the log format without grading it",
+			Flags: func(fs *flag.FlagSet, o *CLIOptions) {
+				addInputFlags(fs, o, "")
+				addSourceFlags(fs, o)
+				fs.StringVar(&o.Output, "output", StdStream, "write the results to this file")
+				fs.StringVar(&o.Profile, "profile", DefaultProfile, "output profile used to word the ratings (spec, internal, customer)")
+				fs.StringVar(&o.Lang, "lang", "", "language of the report (en, es, de), defaults to the locale environment")
+				fs.StringVar(&o.Verbosity, "verbosity", DefaultLogLevel.String(), "what is logged to stderr: quiet, info (discarded records) or debug (also the run metadata)")
+			},
+			Run: runValidate,
+		},
+		{
+			Name:    "report",
+			Summary: "Grade the sensors of a log and detail the statistics of each of them",
+			Flags: func(fs *flag.FlagSet, o *CLIOptions) {
+				addInputFlags(fs, o, StdStream)
+				addSourceFlags(fs, o)
+				addOutputFlags(fs, o)
+				fs.StringVar(&o.Calibration, "calibration", "", "apply the corrections of this calibration table to the readings")
+			},
+			Run: runReport,
+		},
+		{
+			Name:    InteractiveCommand,
+			Summary: "Paste or open logs and review their results on an interactive screen",
+			Flags: func(fs *flag.FlagSet, o *CLIOptions) {
+				addInputFlags(fs, o, "")
+				addSourceFlags(fs, o)
+				fs.StringVar(&o.Profile, "profile", DefaultProfile, "output profile used to word the ratings (spec, internal, customer)")
+				fs.StringVar(&o.Labels, "labels", "", "word the ratings with the labels of this file instead of the output profile")
+				fs.StringVar(&o.Lang, "lang", "", "language of the report (en, es, de), defaults to the locale environment")
+				fs.StringVar(&o.Verbosity, "verbosity", DefaultLogLevel.String(), "what is logged under the status line: quiet, info (discarded lines) or debug")
+				fs.StringVar(&o.Calibration, "calibration", "", "apply the corrections of this calibration table to the readings")
+			},
+			Run: runInteractive,
+		},
+		{
+			Name:      "watch",
+			Arguments: "<file>",
+			Summary:   "Follow a log as it's written and print provisional ratings",
+			Flags: func(fs *flag.FlagSet, o *CLIOptions) {
+				fs.DurationVar(&o.Interval, "interval", time.Minute, "how often the provisional ratings are printed")
+				fs.StringVar(&o.Target, "target", DefaultWatchTarget.String(), "rating thermometers are expected to reach")
+				fs.IntVar(&o.ExpectedReadings, "expected-readings", 0, "number of readings per sensor at the end of the run, to flag the thermometers which can't reach their target")
+				fs.StringVar(&o.DecimalSeparator, "decimal-separator", DefaultNumberFormat.DecimalSeparator, "decimal separator of the numbers in the log")
+				fs.StringVar(&o.ThousandsSeparator, "thousands-separator", DefaultNumberFormat.ThousandsSeparator, "thousands separator of the numbers in the log, if any")
+				fs.StringVar(&o.Profile, "profile", DefaultProfile, "output profile used to word the ratings (spec, internal, customer)")
+				fs.StringVar(&o.Labels, "labels", "", "word the ratings with the labels of this file instead of the output profile")
+				fs.StringVar(&o.Lang, "lang", "", "language of the report (en, es, de), defaults to the locale environment")
+				fs.StringVar(&o.Verbosity, "verbosity", DefaultLogLevel.String(), "what is logged to stderr: quiet, info (discarded lines) or debug")
+				fs.StringVar(&o.Calibration, "calibration", "", "apply the corrections of this calibration table to the readings")
+			},
+			Run: runWatch,
+		},
+		{
+			Name:      "spool",
+			Arguments: "<inbox>",
+			Summary:   "Grade the logs dropped in a directory and archive them with their reports",
+			Flags: func(fs *flag.FlagSet, o *CLIOptions) {
+				fs.StringVar(&o.Format, "format", AutoFormat, "format of the logs (auto, log, csv, jsonl), detected from their first lines by default")
+				fs.StringVar(&o.CSVColumns, "csv-columns", "", "header names of the CSV columns, e.g. timestamp=time,sensor=name")
+				fs.StringVar(&o.DecimalSeparator, "decimal-separator", DefaultNumberFormat.DecimalSeparator, "decimal separator of the numbers in the logs")
+				fs.StringVar(&o.ThousandsSeparator, "thousands-separator", DefaultNumberFormat.ThousandsSeparator, "thousands separator of the numbers in the logs, if any")
+				fs.StringVar(&o.ReportFormats, "formats", TextReport+","+JSONReport, "reports written next to each log (text, report, json), separated by commas")
+				fs.DurationVar(&o.Interval, "interval", 5*time.Second, "how often the inbox is checked for new logs")
+				fs.DurationVar(&o.Settle, "settle", DefaultSpoolSettle, "how long the size of a log must stay the same before it's graded, unless a <log>.done marker is dropped")
+				fs.StringVar(&o.Archive, "archive", "", "move the graded logs and their reports to this directory, defaults to <inbox>/archive")
+				fs.StringVar(&o.Failed, "failed", "", "move the logs which can't be graded to this directory, defaults to <inbox>/failed")
+				fs.StringVar(&o.Profile, "profile", DefaultProfile, "output profile used to word the ratings (spec, internal, customer)")
+				fs.StringVar(&o.Labels, "labels", "", "word the ratings with the labels of this file instead of the output profile")
+				fs.StringVar(&o.Lang, "lang", "", "language of the reports (en, es, de), defaults to the locale environment")
+				fs.StringVar(&o.Verbosity, "verbosity", DefaultLogLevel.String(), "what is logged to stderr: quiet, info (logs which can't be graded, discarded lines) or debug (also the logs graded)")
+				fs.StringVar(&o.Calibration, "calibration", "", "apply the corrections of this calibration table to the readings")
+			},
+			Run: runSpool,
+		},
+		{
+			Name:    "serve",
+			Summary: "Grade the logs posted to an HTTP API",
+			Flags: func(fs *flag.FlagSet, o *CLIOptions) {
+				fs.StringVar(&o.Listen, "listen", DefaultListenAddress, "address the HTTP server listens on")
+				fs.StringVar(&o.JobsDir, "jobs-dir", "", "enable the job queue, saving the logs posted to /jobs and their results in this directory")
+				fs.IntVar(&o.JobWorkers, "workers", DefaultJobWorkers, "number of jobs graded at the same time")
+				fs.DurationVar(&o.JobsRetention, "jobs-retention", DefaultJobRetention, "how long finished jobs are kept, 0 keeps them forever")
+				fs.StringVar(&o.CSVColumns, "csv-columns", "", "header names of the CSV columns, e.g. timestamp=time,sensor=name")
+				fs.StringVar(&o.DecimalSeparator, "decimal-separator", DefaultNumberFormat.DecimalSeparator, "decimal separator of the numbers in the logs")
+				fs.StringVar(&o.ThousandsSeparator, "thousands-separator", DefaultNumberFormat.ThousandsSeparator, "thousands separator of the numbers in the logs, if any")
+				fs.StringVar(&o.Profile, "profile", DefaultProfile, "output profile used to word the ratings (spec, internal, customer)")
+				fs.StringVar(&o.Labels, "labels", "", "word the ratings with the labels of this file instead of the output profile")
+				fs.StringVar(&o.Lang, "lang", "", "language of the error messages (en, es, de), defaults to the locale environment")
+				fs.StringVar(&o.Verbosity, "verbosity", DefaultLogLevel.String(), "what is logged to stderr: quiet, info (discarded lines) or debug (also the address listened on)")
+				fs.StringVar(&o.Calibration, "calibration", "", "apply the corrections of this calibration table to the readings")
+			},
+			Run: runServe,
+		},
+		{
+			Name:      "mqtt",
+			Arguments: "<broker>",
+			Summary:   "Grade the test sessions published to an MQTT broker (host:port)",
+			Flags: func(fs *flag.FlagSet, o *CLIOptions) {
+				fs.StringVar(&o.SensorTopic, "sensor-topic", DefaultMQTTSensorTopic, "topic of the readings, {type} and {sensor} standing for the type and name of the sensor")
+				fs.StringVar(&o.ReferenceTopic, "reference-topic", DefaultMQTTReferenceTopic, "topic of the reference")
+				fs.StringVar(&o.EndTopic, "end-topic", DefaultMQTTEndTopic, "topic ending the test session, which is then graded")
+				fs.StringVar(&o.Topics, "topics", "", "topic filters to subscribe to, separated by commas, derived from the topics by default")
+				fs.StringVar(&o.ClientID, "client-id", "sensor", "client identifier given to the broker")
+				fs.StringVar(&o.Username, "username", "", "user name given to the broker, if any")
+				fs.StringVar(&o.PasswordFile, "password-file", "", "read the password given to the broker from this file, else from the SENSOR_MQTT_PASSWORD environment variable")
+				fs.DurationVar(&o.KeepAlive, "keep-alive", DefaultMQTTKeepAlive, "how long the broker may stay silent before the connection is considered lost")
+				fs.StringVar(&o.ReportFormats, "formats", TextReport, "reports written for each session (text, report, json), separated by commas")
+				fs.StringVar(&o.DecimalSeparator, "decimal-separator", DefaultNumberFormat.DecimalSeparator, "decimal separator of the numbers in plain text payloads")
+				fs.StringVar(&o.ThousandsSeparator, "thousands-separator", DefaultNumberFormat.ThousandsSeparator, "thousands separator of the numbers in plain text payloads, if any")
+				addOutputFlags(fs, o)
+				fs.StringVar(&o.Calibration, "calibration", "", "apply the corrections of this calibration table to the readings")
+			},
+			Run: runMQTT,
+		},
+		{
+			Name:    "version",
+			Summary: "Print the version of the tool",
+			Flags:   func(fs *flag.FlagSet, o *CLIOptions) {},
+			Run:     runVersion,
+		},
+		{
+			Name:      "help",
+			Arguments: "[command]",
+			Summary:   "Print the help of the tool or of a command",
+			Flags:     func(fs *flag.FlagSet, o *CLIOptions) {},
+			Run:       runHelp,
+		},
+	}
+}
+
+func addInputFlags(fs *flag.FlagSet, o *CLIOptions, defaultInput string) {
+	fs.StringVar(&o.Input, "input", defaultInput, "read the log from this file (- for stdin), it can be compressed with gzip or bzip2")
+	fs.StringVar(&o.Format, "format", AutoFormat, "format of the log (auto, log, csv, jsonl), detected from its first lines by default")
+	fs.StringVar(&o.CSVColumns, "csv-columns", "", "header names of the CSV columns, e.g. timestamp=time,sensor=name")
+	fs.StringVar(&o.DecimalSeparator, "decimal-separator", DefaultNumberFormat.DecimalSeparator, "decimal separator of the numbers in the log")
+	fs.StringVar(&o.ThousandsSeparator, "thousands-separator", DefaultNumberFormat.ThousandsSeparator, "thousands separator of the numbers in the log, if any")
+}
+
+func addSourceFlags(fs *flag.FlagSet, o *CLIOptions) {
+	fs.DurationVar(&o.SourceTimeout, "timeout", DefaultSourceTimeout, "when the input is a rig (tcp://host:port or a serial port), how long it may stay silent before the log is considered complete")
+	fs.IntVar(&o.SourceRetries, "retries", DefaultSourceRetries, "when the input is a rig, how many times in all a lost connection is opened again")
+}
+
+func addOutputFlags(fs *flag.FlagSet, o *CLIOptions) {
+	fs.StringVar(&o.Output, "output", StdStream, "write the results to this file")
+	fs.StringVar(&o.Profile, "profile", DefaultProfile, "output profile used to word the ratings (spec, internal, customer)")
+	fs.StringVar(&o.Labels, "labels", "", "word the ratings with the labels of this file instead of the output profile")
+	fs.StringVar(&o.Lang, "lang", "", "language of the report (en, es, de), defaults to the locale environment")
+	fs.StringVar(&o.Verbosity, "verbosity", DefaultLogLevel.String(), "what is logged to stderr: quiet, info (discarded lines) or debug (also the run metadata and what was read from the log)")
+}
+
+/**
+ * Running the command line, returns the exit code: 0 on success, 1 when the
+ * command failed, 2 on usage errors
+ */
+func RunCLI(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	name := DefaultCommand
+	switch {
+	case len(args) > 0 && !strings.HasPrefix(args[0], "-"):
+		name, args = args[0], args[1:]
+	case len(args) == 0 && IsTerminal(stdin):
+		// Rather than prompting for the log, let the user paste it in the interactive screen
+		name = InteractiveCommand
+	}
+
+	if len(args) > 0 && name == DefaultCommand && isHelpFlag(args[0]) {
+		// "sensor -h" asks for the help of the tool, not the one of analyze
+		PrintHelp(stdout)
+		return 0
+	}
+
+	command, found := FindCommand(name)
+	if !found {
+		fmt.Fprintln(stderr, Translate("Unknown command %s", name))
+		PrintHelp(stderr)
+		return 2
+	}
+
+	options := &CLIOptions{}
+	fs := flag.NewFlagSet(command.Name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	command.Flags(fs, options)
+	fs.Usage = func() {
+		PrintCommandHelp(fs.Output(), command, fs)
+	}
+
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+
+	ctx := &CommandContext{Options: options, Stdin: stdin, Stdout: stdout, Stderr: stderr}
+
+	// Commands log to this run's stderr, restore the previous logger once done
+	defer SetLogger(GetLogger())
+
+	return command.Run(ctx, fs.Args())
+}
+
+func FindCommand(name string) (Command, bool) {
+	for _, command := range Commands {
+		if command.Name == name {
+			return command, true
+		}
+	}
+
+	return Command{}, false
+}
+
+func PrintHelp(w io.Writer) {
+	fmt.Fprintln(w, Translate("Usage: sensor <command> [flags]"))
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, Translate("Commands:"))
+	for _, command := range Commands {
+		fmt.Fprintf(w, "  %-10s %s\n", command.Name, Translate(command.Summary))
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, Translate("Without a command, the log is analyzed. Run \"sensor help <command>\" for the flags of a command."))
+}
+
+func PrintCommandHelp(w io.Writer, command Command, fs *flag.FlagSet) {
+	usage := "sensor " + command.Name
+	if hasFlags(fs) {
+		usage += " [flags]"
+	}
+	if command.Arguments != "" {
+		usage += " " + command.Arguments
+	}
+
+	fmt.Fprintln(w, Translate("Usage: %s", usage))
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, Translate(command.Summary))
+
+	if hasFlags(fs) {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, Translate("Flags:"))
+		// The help of the flags is declared in English, like the summaries
+		fs.VisitAll(func(f *flag.Flag) {
+			f.Usage = Translate(f.Usage)
+		})
+		output := fs.Output()
+		fs.SetOutput(w)
+		fs.PrintDefaults()
+		fs.SetOutput(output)
+	}
+}
+
+func hasFlags(fs *flag.FlagSet) bool {
+	found := false
+	fs.VisitAll(func(*flag.Flag) {
+		found = true
+	})
+
+	return found
+}
+
+func isHelpFlag(arg string) bool {
+	return arg == "-h" || arg == "-help" || arg == "--help"
+}
+
+/**
+ * Applying the settings shared by the commands reading a log: verbosity, number
+ * format, language and the CSV columns
+ */
+func (ctx *CommandContext) setup() (CSVColumnMapping, error) {
+	o := ctx.Options
+
+	level, err := ParseLogLevel(o.Verbosity)
+	if err != nil {
+		return DefaultCSVColumnMapping, err
+	}
+	SetLogger(NewLogger(ctx.Stderr, level))
+
+	mapping, err := ParseCSVColumnMapping(o.CSVColumns)
+	if err != nil {
+		return mapping, err
+	}
+
+	numberFormat, err := NewNumberFormat(o.DecimalSeparator, o.ThousandsSeparator)
+	if err != nil {
+		return mapping, err
+	}
+	SetInputNumberFormat(numberFormat)
+
+	if o.Lang != "" {
+		if err := SetLocale(o.Lang); err != nil {
+			return mapping, err
+		}
+	}
+
+	return mapping, nil
+}
+
+func (o *CLIOptions) getLabels() (RatingLabels, error) {
+	if o.Labels != "" {
+		return LoadRatingLabels(o.Labels)
+	}
+
+	return GetRatingLabels(o.Profile)
+}
+
+func (o *CLIOptions) getCalibrationTable() (CalibrationTable, error) {
+	if o.Calibration == "" {
+		return nil, nil
+	}
+
+	return LoadCalibrationTable(o.Calibration)
+}
+
+/**
+ * Password of the MQTT broker, read from the password file or the environment so
+ * it doesn't show in the list of processes
+ */
+func (o *CLIOptions) getMQTTPassword() (string, error) {
+	if o.PasswordFile == "" {
+		return os.Getenv(MQTTPasswordVariable), nil
+	}
+
+	content, err := ioutil.ReadFile(o.PasswordFile)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimRight(string(content), "\r\n"), nil
+}
+
+/**
+ * Reading the log from the input file, a rig or stdin and resolving its format
+ */
+func (ctx *CommandContext) readLog(mapping CSVColumnMapping) ([]string, string, error) {
+	if ctx.Options.Input == StdStream {
+		return ReadLogInput(ctx.Stdin, ctx.Options.Format, mapping)
+	}
+
+	lines, err := ctx.readInputLines()
+	if err != nil {
+		return nil, "", err
+	}
+
+	format, err := ResolveFormat(lines, ctx.Options.Format, mapping)
+	return lines, format, err
+}
+
+// Lines of the input file or rig
+func (ctx *CommandContext) readInputLines() ([]string, error) {
+	o := ctx.Options
+	if source := OpenLineSource(o.Input, o.SourceTimeout, o.SourceRetries); source != nil {
+		defer source.Close()
+		return ReadSourceLog(source)
+	}
+
+	return ReadLogFile(o.Input)
+}
+
+/**
+ * Opening the output file, the returned function closes it
+ */
+func (ctx *CommandContext) openOutput() (io.Writer, func() error, error) {
+	if ctx.Options.Output == StdStream {
+		return ctx.Stdout, func() error { return nil }, nil
+	}
+
+	file, err := os.Create(ctx.Options.Output)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return file, file.Close, nil
+}
+
+func (ctx *CommandContext) fail(err error) int {
+	fmt.Fprintln(ctx.Stderr, err)
+	return 1
+}
+
+func (ctx *CommandContext) usageError(command string) int {
+	cmd, _ := FindCommand(command)
+	fs := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	cmd.Flags(fs, &CLIOptions{})
+	PrintCommandHelp(ctx.Stderr, cmd, fs)
+
+	return 2
+}
+
+/**
+ * Reading and grading the log, shared by analyze and report
+ */
+func (ctx *CommandContext) gradeLog(mapping CSVColumnMapping) (ReferenceInterface, []SensorInterface, []Diagnostic, RunMetadata, error) {
+	o := ctx.Options
+
+	table, err := o.getCalibrationTable()
+	if err != nil {
+		return nil, nil, nil, RunMetadata{}, err
+	}
+
+	lines, format, err := ctx.readLog(mapping)
+	if err != nil {
+		return nil, nil, nil, RunMetadata{}, err
+	}
+
+	metadata := NewRunMetadata(o.Profile)
+	metadata.InputFormat = format
+
+	ref, sensors, diagnostics, _, err := GradeLog(lines, format, mapping, table)
+	if err != nil {
+		// They may tell why the log can't be graded
+		for _, diagnostic := range diagnostics {
+			fmt.Fprintln(ctx.Stderr, diagnostic)
+		}
+		return nil, nil, nil, metadata, err
+	}
+
+	return ref, sensors, diagnostics, metadata, nil
+}
+
+func logRunDetails(metadata RunMetadata, ref ReferenceInterface, sensors []SensorInterface) {
+	metadata.Print(logger.Writer(LogDebug))
+	logger.Debug(Translate("Ref. Temperature is %s | Ref. Humidity is %s", FormatNumber(ref.GetRefTemperature(), 6), FormatNumber(ref.GetRefHumidity(), 6)))
+	logger.Debug(Translate("Found %d sensors", len(sensors)))
+}
+
+func runAnalyze(ctx *CommandContext, args []string) int {
+	if len(args) > 0 {
+		return ctx.usageError("analyze")
+	}
+
+	o := ctx.Options
+
+	mapping, err := ctx.setup()
+	if err != nil {
+		return ctx.fail(err)
+	}
+
+	labels, err := o.getLabels()
+	if err != nil {
+		return ctx.fail(err)
+	}
+
+	if o.ExportCalibration != "" {
+		return runExportCalibration(ctx, mapping, labels)
+	}
+
+	ref, sensors, diagnostics, metadata, err := ctx.gradeLog(mapping)
+	if err != nil {
+		return ctx.fail(err)
+	}
+
+	out, closeOutput, err := ctx.openOutput()
+	if err != nil {
+		return ctx.fail(err)
+	}
+
+	logRunDetails(metadata, ref, sensors)
+	if o.JSON {
+		if err := WriteReport(out, JSONReport, sensors, ref, diagnostics, metadata, labels); err != nil {
+			closeOutput()
+			return ctx.fail(err)
+		}
+	} else {
+		PrintResults(out, sensors, labels)
+		PrintDiagnostics(out, diagnostics)
+	}
+
+	if err := closeOutput(); err != nil {
+		return ctx.fail(err)
+	}
+
+	return 0
+}
+
+func runExportCalibration(ctx *CommandContext, mapping CSVColumnMapping, labels RatingLabels) int {
+	// The flashing station applies the corrections to the raw readings
+	if ctx.Options.Calibration != "" {
+		return ctx.fail(errors.New(Translate("-calibration can't be used with -export-calibration, corrections are fitted on the raw readings")))
+	}
+
+	lines, format, err := ctx.readLog(mapping)
+	if err != nil {
+		return ctx.fail(err)
+	}
+
+	// Multi-setpoint logs are only written in the log format
+	if format != LegacyFormat {
+		return ctx.fail(errors.New(Translate("Calibrations can only be fitted on logs in the %s format", LegacyFormat)))
+	}
+
+	out, closeOutput, err := ctx.openOutput()
+	if err != nil {
+		return ctx.fail(err)
+	}
+
+	if err := RunCalibration(out, lines, ctx.Options.ExportCalibration, labels); err != nil {
+		closeOutput()
+		return ctx.fail(err)
+	}
+
+	if err := closeOutput(); err != nil {
+		return ctx.fail(err)
+	}
+
+	return 0
+}
+
+func runReport(ctx *CommandContext, args []string) int {
+	if len(args) > 0 {
+		return ctx.usageError("report")
+	}
+
+	mapping, err := ctx.setup()
+	if err != nil {
+		return ctx.fail(err)
+	}
+
+	labels, err := ctx.Options.getLabels()
+	if err != nil {
+		return ctx.fail(err)
+	}
+
+	ref, sensors, diagnostics, metadata, err := ctx.gradeLog(mapping)
+	if err != nil {
+		return ctx.fail(err)
+	}
+
+	out, closeOutput, err := ctx.openOutput()
+	if err != nil {
+		return ctx.fail(err)
+	}
+
+	logRunDetails(metadata, ref, sensors)
+	PrintReport(out, sensors, ref, labels)
+	PrintDiagnostics(out, diagnostics)
+
+	if err := closeOutput(); err != nil {
+		return ctx.fail(err)
+	}
+
+	return 0
+}
+
+/**
+ * Checking a log without grading it.
+ * Returns the exit code: 0 when the log is valid, 1 when it isn't, 2 on usage errors
+ */
+func runValidate(ctx *CommandContext, args []string) int {
+	o := ctx.Options
+
+	// The log to check is given as an argument or with -input, reading it from stdin must be asked for with "-input -"
+	switch {
+	case len(args) == 1 && o.Input == "":
+		o.Input = args[0]
+	case len(args) > 0 || o.Input == "":
+		return ctx.usageError("validate")
+	}
+
+	mapping, err := ctx.setup()
+	if err != nil {
+		return ctx.fail(err)
+	}
+	// The profile isn't used to check the log, but it's recorded in the run metadata
+	if _, err := GetRatingLabels(o.Profile); err != nil {
+		return ctx.fail(err)
+	}
+
+	lines, format, err := ctx.readLog(mapping)
+	if err != nil {
+		return ctx.fail(err)
+	}
+
+	out, closeOutput, err := ctx.openOutput()
+	if err != nil {
+		return ctx.fail(err)
+	}
+	defer closeOutput()
+
+	metadata := NewRunMetadata(o.Profile)
+	metadata.InputFormat = format
+	metadata.Print(logger.Writer(LogDebug))
+
+	name := o.Input
+	if name == StdStream {
+		name = "stdin"
+	}
+
+	diagnostics := ValidateLogFormat(lines, format, mapping)
+	for _, diagnostic := range diagnostics {
+		fmt.Fprintf(out, "%s: %s\n", name, diagnostic)
+	}
+
+	if len(diagnostics) > 0 {
+		fmt.Fprintln(out, Translate("%s: %d violation(s) found", name, len(diagnostics)))
+		return 1
+	}
+
+	fmt.Fprintln(out, Translate("%s is valid", name))
+	return 0
+}
+
+func runInteractive(ctx *CommandContext, args []string) int {
+	if len(args) > 0 {
+		return ctx.usageError(InteractiveCommand)
+	}
+
+	o := ctx.Options
+
+	mapping, err := ctx.setup()
+	if err != nil {
+		return ctx.fail(err)
+	}
+
+	labels, err := o.getLabels()
+	if err != nil {
+		return ctx.fail(err)
+	}
+
+	table, err := o.getCalibrationTable()
+	if err != nil {
+		return ctx.fail(err)
+	}
+
+	tui := NewTUI(ctx.Stdin, ctx.Stdout, IsTerminal(ctx.Stdout))
+	tui.SetInputFormat(o.Format, mapping)
+	tui.SetLabels(labels)
+	tui.SetCalibrationTable(table)
+	// The logger is restored once the command is done
+	SetLogger(NewTUILogger(tui, logger.GetLevel()))
+
+	// The log to review can be opened right away
+	if o.Input != "" {
+		lines, err := ctx.readInputLines()
+		if err != nil {
+			return ctx.fail(err)
+		}
+		tui.Load(lines)
+	}
+
+	if err := tui.Run(); err != nil {
+		return ctx.fail(err)
+	}
+
+	return 0
+}
+
+func runWatch(ctx *CommandContext, args []string) int {
+	if len(args) != 1 || ctx.Options.Interval <= 0 {
+		return ctx.usageError("watch")
+	}
+
+	o := ctx.Options
+
+	if _, err := ctx.setup(); err != nil {
+		return ctx.fail(err)
+	}
+
+	target, err := ParseRating(o.Target)
+	if err != nil {
+		return ctx.fail(err)
+	}
+	if !target.IsTier() {
+		return ctx.fail(errors.New(Translate("The target of the thermometers must be a tier (%s, %s or %s)", RatingPrecise, RatingVeryPrecise, RatingUltraPrecise)))
+	}
+
+	labels, err := o.getLabels()
+	if err != nil {
+		return ctx.fail(err)
+	}
+
+	table, err := o.getCalibrationTable()
+	if err != nil {
+		return ctx.fail(err)
+	}
+
+	watcher := NewWatcher(ctx.Stdout, labels)
+	watcher.SetTarget(target)
+	watcher.SetExpectedReadings(o.ExpectedReadings)
+	watcher.SetCalibrationTable(table)
+
+	// The run is followed until the user stops it
+	runCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := watcher.Watch(runCtx, args[0], o.Interval); err != nil {
+		return ctx.fail(err)
+	}
+
+	return 0
+}
+
+func runSpool(ctx *CommandContext, args []string) int {
+	if len(args) != 1 || ctx.Options.Interval <= 0 {
+		return ctx.usageError("spool")
+	}
+
+	o := ctx.Options
+
+	mapping, err := ctx.setup()
+	if err != nil {
+		return ctx.fail(err)
+	}
+
+	formats, err := ParseReportFormats(o.ReportFormats)
+	if err != nil {
+		return ctx.fail(err)
+	}
+
+	labels, err := o.getLabels()
+	if err != nil {
+		return ctx.fail(err)
+	}
+
+	table, err := o.getCalibrationTable()
+	if err != nil {
+		return ctx.fail(err)
+	}
+
+	spooler := NewSpooler(args[0])
+	spooler.SetInputFormat(o.Format, mapping)
+	spooler.SetReportFormats(formats)
+	spooler.SetSettle(o.Settle)
+	spooler.SetLabels(labels)
+	spooler.SetProfile(o.Profile)
+	spooler.SetCalibrationTable(table)
+
+	archive, failed := o.Archive, o.Failed
+	if archive == "" {
+		archive = filepath.Join(args[0], SpoolArchiveFolder)
+	}
+	if failed == "" {
+		failed = filepath.Join(args[0], SpoolFailedFolder)
+	}
+	spooler.SetFolders(archive, failed)
+
+	// The inbox is watched until the user stops it
+	runCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := spooler.Run(runCtx, o.Interval); err != nil {
+		return ctx.fail(err)
+	}
+
+	return 0
+}
+
+func runServe(ctx *CommandContext, args []string) int {
+	if len(args) > 0 || ctx.Options.JobWorkers <= 0 {
+		return ctx.usageError("serve")
+	}
+
+	o := ctx.Options
+
+	mapping, err := ctx.setup()
+	if err != nil {
+		return ctx.fail(err)
+	}
+
+	labels, err := o.getLabels()
+	if err != nil {
+		return ctx.fail(err)
+	}
+
+	table, err := o.getCalibrationTable()
+	if err != nil {
+		return ctx.fail(err)
+	}
+
+	server := NewServer()
+	server.SetCSVColumnMapping(mapping)
+	server.SetLabels(labels)
+	server.SetProfile(o.Profile)
+	server.SetCalibrationTable(table)
+
+	if o.JobsDir != "" {
+		jobs, err := OpenJobQueue(o.JobsDir, o.JobWorkers, server.GradeFile)
+		if err != nil {
+			return ctx.fail(err)
+		}
+		jobs.SetRetention(o.JobsRetention)
+		server.SetJobQueue(jobs)
+
+		jobs.Start()
+		defer jobs.Stop()
+	}
+
+	// The server runs until the user stops it
+	runCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := server.ListenAndServe(runCtx, o.Listen); err != nil {
+		return ctx.fail(err)
+	}
+
+	return 0
+}
+
+func runMQTT(ctx *CommandContext, args []string) int {
+	if len(args) != 1 {
+		return ctx.usageError("mqtt")
+	}
+
+	o := ctx.Options
+
+	if _, err := ctx.setup(); err != nil {
+		return ctx.fail(err)
+	}
+
+	formats, err := ParseReportFormats(o.ReportFormats)
+	if err != nil {
+		return ctx.fail(err)
+	}
+
+	labels, err := o.getLabels()
+	if err != nil {
+		return ctx.fail(err)
+	}
+
+	table, err := o.getCalibrationTable()
+	if err != nil {
+		return ctx.fail(err)
+	}
+
+	out, closeOutput, err := ctx.openOutput()
+	if err != nil {
+		return ctx.fail(err)
+	}
+	defer closeOutput()
+
+	password, err := o.getMQTTPassword()
+	if err != nil {
+		return ctx.fail(err)
+	}
+	options := MQTTOptions{ClientID: o.ClientID, Username: o.Username, Password: password, KeepAlive: o.KeepAlive}
+	if options.Password != "" && options.Username == "" {
+		return ctx.fail(errors.New(Translate("An MQTT password can't be given without a user name")))
+	}
+
+	client, err := DialMQTT(args[0], options)
+	if err != nil {
+		return ctx.fail(err)
+	}
+	defer client.Close()
+
+	topics := MQTTTopics{Sensor: o.SensorTopic, Reference: o.ReferenceTopic, End: o.EndTopic}
+	if o.Topics != "" {
+		topics.Subscriptions = strings.Split(o.Topics, ",")
+	}
+
+	subscriber := NewMQTTSubscriber(client, out)
+	subscriber.SetDialer(func() (MQTTClientInterface, error) {
+		client, err := DialMQTT(args[0], options)
+		if err != nil {
+			return nil, err
+		}
+		return client, nil
+	})
+	subscriber.SetTopics(topics)
+	subscriber.SetReportFormats(formats)
+	subscriber.SetLabels(labels)
+	subscriber.SetProfile(o.Profile)
+	subscriber.SetCalibrationTable(table)
+
+	// Sessions are graded until the user stops it
+	runCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := subscriber.Run(runCtx); err != nil {
+		return ctx.fail(err)
+	}
+
+	return 0
+}
+
+func runVersion(ctx *CommandContext, args []string) int {
+	if len(args) > 0 {
+		return ctx.usageError("version")
+	}
+
+	fmt.Fprintf(ctx.Stdout, "sensor %s (%s %s/%s)\n", Version, runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	return 0
+}
+
+func runHelp(ctx *CommandContext, args []string) int {
+	switch len(args) {
+	case 0:
+		PrintHelp(ctx.Stdout)
+		return 0
+	case 1:
+		command, found := FindCommand(args[0])
+		if !found {
+			fmt.Fprintln(ctx.Stderr, Translate("Unknown command %s", args[0]))
+			return 2
+		}
+
+		fs := flag.NewFlagSet(command.Name, flag.ContinueOnError)
+		command.Flags(fs, &CLIOptions{})
+		PrintCommandHelp(ctx.Stdout, command, fs)
+		return 0
+	}
+
+	return ctx.usageError("help")
+}
@@ -0,0 +1,341 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const cliLog = "reference 70.0 45.0\nthermometer temp-1\n2007-04-05T22:00 temp-1 70.1\n2007-04-05T22:01 temp-1 69.9\nhumidity hum-1\n2007-04-05T22:00 hum-1 45.2\n2007-04-05T22:01 hum-1 45.4\n"
+
+func writeLog(t *testing.T, name string, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	assert.Nil(t, ioutil.WriteFile(path, []byte(content), 0644))
+
+	return path
+}
+
+func TestRunCLI_AnalyzeIsTheDefaultCommand(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+
+	code := RunCLI(nil, strings.NewReader(cliLog), &stdout, &stderr)
+
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "temp-1: ultra precise\nhum-1: OK\n", stdout.String())
+	assert.Equal(t, "", stderr.String())
+}
+
+func TestRunCLI_AnalyzeInputAndOutputFiles(t *testing.T) {
+	input := writeLog(t, "run.log", cliLog)
+	output := filepath.Join(t.TempDir(), "results.txt")
+	var stdout, stderr bytes.Buffer
+
+	code := RunCLI([]string{"analyze", "-input", input, "-output", output, "-profile", InternalProfile}, nil, &stdout, &stderr)
+
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "", stdout.String())
+	res, _ := ioutil.ReadFile(output)
+	assert.True(t, strings.HasPrefix(string(res), "temp-1: "))
+	assert.Equal(t, 2, strings.Count(string(res), "\n"))
+}
+
+func TestRunCLI_AnalyzeVerbosity(t *testing.T) {
+	input := writeLog(t, "run.log", cliLog+"2007-04-05T22:02 hum-1 wet\n")
+	var stdout, stderr bytes.Buffer
+
+	// Debug details and discarded lines are logged to stderr, the results alone go to stdout
+	code := RunCLI([]string{"analyze", "-input", input, "-verbosity", "debug"}, nil, &stdout, &stderr)
+
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "temp-1: ultra precise\nhum-1: OK\n", stdout.String())
+	assert.Contains(t, stderr.String(), "Input format: log\n")
+	assert.Contains(t, stderr.String(), "Found 2 sensors\n")
+	assert.Contains(t, stderr.String(), "parsing \"wet\"")
+
+	stderr.Reset()
+	RunCLI([]string{"analyze", "-input", input}, nil, &stdout, &stderr)
+	assert.NotContains(t, stderr.String(), "Found 2 sensors")
+	assert.Contains(t, stderr.String(), "parsing \"wet\"")
+
+	stderr.Reset()
+	RunCLI([]string{"analyze", "-input", input, "-verbosity", "quiet"}, nil, &stdout, &stderr)
+	assert.Equal(t, "", stderr.String())
+
+	stderr.Reset()
+	code = RunCLI([]string{"analyze", "-input", input, "-verbosity", "loud"}, nil, &stdout, &stderr)
+	assert.Equal(t, 1, code)
+	assert.Equal(t, "Unknown verbosity loud, expected quiet, info or debug\n", stderr.String())
+}
+
+func TestRunCLI_RestoresLogger(t *testing.T) {
+	previous := GetLogger()
+	var stdout, stderr bytes.Buffer
+
+	RunCLI([]string{"analyze", "-input", writeLog(t, "run.log", cliLog)}, nil, &stdout, &stderr)
+
+	assert.Equal(t, previous, GetLogger())
+}
+
+func TestRunCLI_AnalyzeErrors(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+
+	code := RunCLI([]string{"analyze", "-input", filepath.Join(t.TempDir(), "missing.log")}, nil, &stdout, &stderr)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr.String(), "missing.log")
+
+	stderr.Reset()
+	code = RunCLI([]string{"analyze", "-profile", "potato"}, nil, &stdout, &stderr)
+	assert.Equal(t, 1, code)
+
+	stderr.Reset()
+	code = RunCLI([]string{"analyze", "-potato"}, nil, &stdout, &stderr)
+	assert.Equal(t, 2, code)
+	assert.Contains(t, stderr.String(), "Usage: sensor analyze [flags]")
+
+	stderr.Reset()
+	code = RunCLI([]string{"analyze", "run.log"}, nil, &stdout, &stderr)
+	assert.Equal(t, 2, code)
+}
+
+func TestRunCLI_ExportCalibrationNeedsLogFormat(t *testing.T) {
+	input := writeLog(t, "run.jsonl", `{"kind": "reference", "temperature": 70.0, "humidity": 45.0}`)
+	var stdout, stderr bytes.Buffer
+
+	code := RunCLI([]string{"analyze", "-input", input, "-export-calibration", filepath.Join(t.TempDir(), "table.txt")}, nil, &stdout, &stderr)
+
+	assert.Equal(t, 1, code)
+	assert.Equal(t, "Calibrations can only be fitted on logs in the log format\n", stderr.String())
+}
+
+func TestRunCLI_Report(t *testing.T) {
+	input := writeLog(t, "run.log", cliLog)
+	var stdout, stderr bytes.Buffer
+
+	code := RunCLI([]string{"report", "-input", input}, nil, &stdout, &stderr)
+
+	assert.Equal(t, 0, code)
+	assert.Equal(t, `temp-1 (thermometer): ultra precise
+  readings: 2 | average: 70.00 | standard deviation: 0.14
+  expanded uncertainty: 0.31 (k=2)
+
+hum-1 (humidity): OK
+  readings: 2 | average: 45.30 | standard deviation: 0.14
+  expanded uncertainty: 0.31 (k=2)
+`, stdout.String())
+}
+
+func TestRunCLI_Validate(t *testing.T) {
+	valid := writeLog(t, "valid.log", "reference 70.0 45.0\nthermometer temp-1\n2007-04-05T22:00 temp-1 72.4\n")
+	invalid := writeLog(t, "invalid.log", "reference 70.0 45.0\nthermometer temp-1\n2007-04-05T22:00 temp-1 hot\n")
+
+	var out bytes.Buffer
+	assert.Equal(t, 0, RunCLI([]string{"validate", valid}, nil, &out, &out))
+	assert.Equal(t, valid+" is valid\n", out.String())
+
+	out.Reset()
+	assert.Equal(t, 1, RunCLI([]string{"validate", "-input", invalid}, nil, &out, &out))
+	assert.Equal(t, invalid+": line 3: Invalid value hot for sensor temp-1\n"+invalid+": 1 violation(s) found\n", out.String())
+
+	out.Reset()
+	assert.Equal(t, 2, RunCLI([]string{"validate"}, nil, &out, &out))
+	assert.Contains(t, out.String(), "Usage: sensor validate [flags] [file]")
+}
+
+func TestRunCLI_ExportCalibrationOfCalibratedReadings(t *testing.T) {
+	input := writeLog(t, "run.log", cliLog)
+	table := writeLog(t, "calibration.txt", "temp-1 -1.0 1.0\n")
+	var stdout, stderr bytes.Buffer
+
+	code := RunCLI([]string{"analyze", "-input", input, "-calibration", table, "-export-calibration", filepath.Join(t.TempDir(), "export.txt")}, nil, &stdout, &stderr)
+
+	assert.Equal(t, 1, code)
+	assert.Equal(t, "-calibration can't be used with -export-calibration, corrections are fitted on the raw readings\n", stderr.String())
+}
+
+func TestRunCLI_ValidateRig(t *testing.T) {
+	address := startTestRig(t, "reference 70.0 45.0\nthermometer temp-1\n2007-04-05T22:00 temp-1 72.4\n")
+	var out bytes.Buffer
+
+	// The log is complete once the rig is silent
+	assert.Equal(t, 0, RunCLI([]string{"validate", "-timeout", "100ms", "-retries", "1", "tcp://" + address}, nil, &out, &out))
+	assert.Equal(t, "tcp://"+address+" is valid\n", out.String())
+}
+
+func TestRunCLI_ValidateProfile(t *testing.T) {
+	valid := writeLog(t, "valid.log", "reference 70.0 45.0\nthermometer temp-1\n2007-04-05T22:00 temp-1 72.4\n")
+	var stdout, stderr bytes.Buffer
+
+	assert.Equal(t, 0, RunCLI([]string{"validate", "-profile", "customer", "-verbosity", "debug", valid}, nil, &stdout, &stderr))
+	assert.Contains(t, stderr.String(), "Output profile: customer\n")
+
+	stderr.Reset()
+	assert.Equal(t, 1, RunCLI([]string{"validate", "-profile", "potato", valid}, nil, &stdout, &stderr))
+	assert.Equal(t, "Unknown output profile potato\n", stderr.String())
+}
+
+func TestRunCLI_ReportReferenceUncertainties(t *testing.T) {
+	// The uncertainties of the reference instruments add to the budgets
+	input := writeLog(t, "run.log", strings.Replace(cliLog, "reference 70.0 45.0", "reference 70.0 45.0 temperature_uncertainty=0.5 humidity_uncertainty=1.5", 1))
+	var stdout, stderr bytes.Buffer
+
+	code := RunCLI([]string{"report", "-input", input}, nil, &stdout, &stderr)
+
+	assert.Equal(t, 0, code)
+	assert.Equal(t, `temp-1 (thermometer): ultra precise
+  readings: 2 | average: 70.00 | standard deviation: 0.14
+  expanded uncertainty: 1.05 (k=2)
+
+hum-1 (humidity): OK
+  readings: 2 | average: 45.30 | standard deviation: 0.14
+  expanded uncertainty: 3.02 (k=2)
+`, stdout.String())
+}
+
+func TestRunCLI_AnalyzeDiagnostics(t *testing.T) {
+	jsonLog := writeLog(t, "run.jsonl", `{"kind": "reference", "temperature": 70.0, "humidity": 45.0}
+{"kind": "declaration", "type": "thermometer", "sensor": "temp-1"}
+{"kind": "reading", "timestamp": "2007-04-05T22:00", "sensor": "temp-1", "value": 70.1}
+{"kind": "reading", "timestamp": "2007-04-05T22:01", "sensor": "temp-1", "value": 69.9}
+{"kind": "reading", "sensor": "temp-1"}
+`)
+
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, 0, RunCLI([]string{"analyze", "-input", jsonLog}, nil, &stdout, &stderr))
+
+	// Reported with the results, not logged
+	assert.Equal(t, "temp-1: ultra precise\n\nDiagnostics:\n  line 5: Missing field timestamp\n", stdout.String())
+	assert.Empty(t, stderr.String())
+}
+
+func TestRunCLI_AnalyzeJSON(t *testing.T) {
+	input := writeLog(t, "run.log", strings.ReplaceAll(cliLog, ".", ","))
+	var stdout, stderr bytes.Buffer
+
+	code := RunCLI([]string{"analyze", "-json", "-profile", InternalProfile, "-decimal-separator", ",", "-input", input}, nil, &stdout, &stderr)
+
+	assert.Equal(t, 0, code, stderr.String())
+	var document ReportDocument
+	assert.Nil(t, json.Unmarshal(stdout.Bytes(), &document))
+	assert.Len(t, document.Sensors, 2)
+	assert.Equal(t, RunMetadata{
+		StartedAt:    document.Metadata.StartedAt,
+		Locale:       document.Metadata.Locale,
+		Profile:      InternalProfile,
+		NumberFormat: NumberFormat{DecimalSeparator: ","},
+		InputFormat:  LegacyFormat,
+	}, document.Metadata)
+}
+
+func TestRunCLI_ValidateOtherFormats(t *testing.T) {
+	jsonLog := writeLog(t, "run.jsonl", "{\"kind\": \"reference\", \"temperature\": 70.0, \"humidity\": 45.0}\n{\"kind\": \"reading\"}\n")
+	csvLog := writeLog(t, "run.csv", "timestamp,sensor,type,value\n,temperature,reference,70.0\n")
+
+	var out bytes.Buffer
+	assert.Equal(t, 1, RunCLI([]string{"validate", jsonLog}, nil, &out, &out))
+	assert.Equal(t, jsonLog+": line 2: Missing field timestamp\n"+jsonLog+": 1 violation(s) found\n", out.String())
+
+	out.Reset()
+	assert.Equal(t, 1, RunCLI([]string{"validate", csvLog}, nil, &out, &out))
+	assert.Equal(t, csvLog+": The CSV log needs reference rows for the temperature and the humidity\n"+csvLog+": 1 violation(s) found\n", out.String())
+}
+
+func TestRunCLI_ValidateCSVRows(t *testing.T) {
+	csvLog := writeLog(t, "run.csv", `timestamp,sensor,type,value
+,temperature,reference,70.0
+,humidity,reference,45.0
+2007-04-05T22:00,temp-1,thermometer,abc
+2007-04-05T22:01,temp-2,potato,72.4
+not-a-time,temp-1,thermometer,72.4
+`)
+
+	var out bytes.Buffer
+	assert.Equal(t, 1, RunCLI([]string{"validate", csvLog}, nil, &out, &out))
+	assert.Equal(t, csvLog+": line 4: Error while parsing the recorded measure for devide temp-1 :strconv.ParseFloat: parsing \"abc\": invalid syntax\n"+
+		csvLog+": line 5: Unknown sensor type potato\n"+
+		csvLog+": line 6: Invalid timestamp not-a-time\n"+
+		csvLog+": 3 violation(s) found\n", out.String())
+}
+
+func TestRunCLI_Version(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+
+	assert.Equal(t, 0, RunCLI([]string{"version"}, nil, &stdout, &stderr))
+	assert.True(t, strings.HasPrefix(stdout.String(), "sensor "+Version+" (go"))
+}
+
+func TestRunCLI_Help(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+
+	for _, args := range [][]string{{"help"}, {"-h"}, {"--help"}} {
+		stdout.Reset()
+		assert.Equal(t, 0, RunCLI(args, nil, &stdout, &stderr))
+		assert.True(t, strings.HasPrefix(stdout.String(), "Usage: sensor <command> [flags]\n"))
+		for _, command := range Commands {
+			assert.Contains(t, stdout.String(), "  "+command.Name)
+		}
+	}
+
+	stdout.Reset()
+	assert.Equal(t, 0, RunCLI([]string{"help", "report"}, nil, &stdout, &stderr))
+	assert.True(t, strings.HasPrefix(stdout.String(), "Usage: sensor report [flags]\n\nGrade the sensors of a log and detail the statistics of each of them\n\nFlags:\n"))
+	assert.Contains(t, stdout.String(), "-output string")
+
+	// Commands print their help when asked for it
+	stderr.Reset()
+	assert.Equal(t, 0, RunCLI([]string{"validate", "-h"}, nil, &stdout, &stderr))
+	assert.Contains(t, stderr.String(), "Usage: sensor validate [flags] [file]")
+
+	// The help of the flags is translated like the summary
+	SetLocale("es")
+	stdout.Reset()
+	assert.Equal(t, 0, RunCLI([]string{"help", "serve"}, nil, &stdout, &stderr))
+	SetLocale(DefaultLocale)
+	assert.Contains(t, stdout.String(), "Calificar los registros enviados a una API HTTP\n")
+	assert.Contains(t, stdout.String(), "número de trabajos calificados al mismo tiempo")
+
+	stdout.Reset()
+	assert.Equal(t, 0, RunCLI([]string{"help", "version"}, nil, &stdout, &stderr))
+	assert.Equal(t, "Usage: sensor version\n\nPrint the version of the tool\n", stdout.String())
+}
+
+func TestRunCLI_UnknownCommand(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+
+	assert.Equal(t, 2, RunCLI([]string{"potato"}, nil, &stdout, &stderr))
+	assert.True(t, strings.HasPrefix(stderr.String(), "Unknown command potato\nUsage: sensor <command> [flags]\n"))
+
+	stderr.Reset()
+	assert.Equal(t, 2, RunCLI([]string{"help", "potato"}, nil, &stdout, &stderr))
+	assert.Equal(t, "Unknown command potato\n", stderr.String())
+}
+
+func TestRunCLI_WatchTargetMustBeATier(t *testing.T) {
+	input := writeLog(t, "run.log", cliLog)
+	var stdout, stderr bytes.Buffer
+
+	code := RunCLI([]string{"watch", "-target", "accepted", input}, nil, &stdout, &stderr)
+
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr.String(), "The target of the thermometers must be a tier (precise, very precise or ultra precise)")
+}
+
+func TestRunCLI_MQTTPassword(t *testing.T) {
+	passwordFile := writeLog(t, "password", "secret\n")
+	var stdout, stderr bytes.Buffer
+
+	// MQTT doesn't allow a password without a user name
+	code := RunCLI([]string{"mqtt", "-password-file", passwordFile, "localhost:1883"}, nil, &stdout, &stderr)
+
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr.String(), "An MQTT password can't be given without a user name")
+
+	options := &CLIOptions{PasswordFile: passwordFile}
+	password, err := options.getMQTTPassword()
+	assert.Nil(t, err)
+	assert.Equal(t, "secret", password)
+}
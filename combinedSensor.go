@@ -0,0 +1,187 @@
+package main
+
+import (
+	"errors"
+)
+
+/**
+ * Combined temperature + humidity devices
+ *   Combo units report both channels under one name, each reading carrying 2 values:
+ *   <time> <name> <temperature> <humidity>
+ *   Each channel is stored in its own Sensor so it's graded with the same rules as
+ *   standalone thermometers and humidity sensors.
+ */
+type CombinedSensor struct {
+	sensorName   string
+	temperature  *Sensor
+	humidity     *Sensor
+	sensorRating Rating
+	sensorError  error
+}
+
+func NewCombinedSensor(sName string) SensorInterface {
+	return &CombinedSensor{
+		sensorName:   sName,
+		temperature:  newSingleSensor(Thermometer, sName),
+		humidity:     newSingleSensor(HumiditySensor, sName),
+		sensorRating: RatingUnknown,
+	}
+}
+
+func (cs *CombinedSensor) AppendData(data []string) error {
+	// input param is composed of 4 elements: date, sensor name, temperature and humidity recorded
+	if len(data) != 4 {
+		return errors.New(Translate("Data doesn't have the expected number of elements for device %s", cs.sensorName))
+	}
+
+	if data[1] != cs.sensorName {
+		return errors.New(Translate("Data is not for the right sensor"))
+	}
+
+	// Parse both values before appending anything, so both channels always have the same readings
+	temperature, err := cs.temperature.parseValue(data[2])
+	if err != nil {
+		return err
+	}
+
+	humidity, err := cs.humidity.parseValue(data[3])
+	if err != nil {
+		return err
+	}
+
+	cs.temperature.appendValue(temperature)
+	cs.humidity.appendValue(humidity)
+
+	return nil
+}
+
+func (cs *CombinedSensor) GetType() string {
+	return ComboSensor
+}
+
+func (cs *CombinedSensor) GetName() string {
+	return cs.sensorName
+}
+
+func (cs *CombinedSensor) GetTemperatureChannel() SensorInterface {
+	return cs.temperature
+}
+
+func (cs *CombinedSensor) GetHumidityChannel() SensorInterface {
+	return cs.humidity
+}
+
+/**
+ * Single-channel statistics report the temperature channel, except for the max deviation
+ * percentage which is only meaningful for the humidity channel.
+ * Use GetTemperatureChannel and GetHumidityChannel to access each channel, as the
+ * reports do to show the statistics and budgets of both.
+ */
+func (cs *CombinedSensor) GetValues() []float64 {
+	return cs.temperature.GetValues()
+}
+
+func (cs *CombinedSensor) GetAverageValue() float64 {
+	return cs.temperature.GetAverageValue()
+}
+
+func (cs *CombinedSensor) GetStandardDeviation() float64 {
+	return cs.temperature.GetStandardDeviation()
+}
+
+func (cs *CombinedSensor) GetMaxDeviationPercentage(refValue float64) float64 {
+	return cs.humidity.GetMaxDeviationPercentage(refValue)
+}
+
+/**
+ * Dew point derived from both channels, for each reading
+ */
+func (cs *CombinedSensor) GetDewPointValues() []float64 {
+	temperatures := cs.temperature.GetValues()
+	humidities := cs.humidity.GetValues()
+
+	var dewPoints []float64
+	for i := range temperatures {
+		dewPoints = append(dewPoints, GetDewPoint(temperatures[i], humidities[i]))
+	}
+
+	return dewPoints
+}
+
+func (cs *CombinedSensor) GetAverageDewPoint() float64 {
+	dewPoints := cs.GetDewPointValues()
+	if len(dewPoints) == 0 {
+		return float64(0)
+	}
+
+	var sum float64
+	for _, dewPoint := range dewPoints {
+		sum += dewPoint
+	}
+
+	return sum / float64(len(dewPoints))
+}
+
+func (cs *CombinedSensor) GetDewPointDeviation(ref ReferenceInterface) float64 {
+	return getDeviation(ref.GetRefDewPoint(), cs.GetAverageDewPoint())
+}
+
+func (cs *CombinedSensor) CalculateRating(ref ReferenceInterface) (Rating, error) {
+	temperatureRating, err := cs.temperature.CalculateRating(ref)
+	if err != nil {
+		return RatingError, err
+	}
+
+	humidityRating, err := cs.humidity.CalculateRating(ref)
+	if err != nil {
+		return RatingError, err
+	}
+
+	// A combo unit is discarded as soon as its humidity channel is, otherwise it's
+	// sold with the tier of its temperature channel
+	return WorstRating(temperatureRating, humidityRating), nil
+}
+
+func (cs *CombinedSensor) SetRating(ref ReferenceInterface) {
+	cs.sensorRating, cs.sensorError = cs.CalculateRating(ref)
+}
+
+func (cs *CombinedSensor) GetRating() Rating {
+	return cs.sensorRating
+}
+
+func (cs *CombinedSensor) GetRatingError() error {
+	return cs.sensorError
+}
+
+func (cs *CombinedSensor) GetResolution() float64 {
+	return cs.temperature.GetResolution()
+}
+
+func (cs *CombinedSensor) SetResolution(resolution float64) {
+	cs.temperature.SetResolution(resolution)
+	cs.humidity.SetResolution(resolution)
+}
+
+func (cs *CombinedSensor) GetDrift() float64 {
+	return cs.temperature.GetDrift()
+}
+
+func (cs *CombinedSensor) SetDrift(drift float64) {
+	cs.temperature.SetDrift(drift)
+	cs.humidity.SetDrift(drift)
+}
+
+// Budget of the temperature channel, see GetHumidityChannel for the other one
+func (cs *CombinedSensor) GetUncertaintyBudget(ref ReferenceInterface) UncertaintyBudget {
+	return cs.temperature.GetUncertaintyBudget(ref)
+}
+
+// Correction of the temperature channel, the humidity channel has its own
+func (cs *CombinedSensor) SetCorrection(correction CorrectionInterface) {
+	cs.temperature.SetCorrection(correction)
+}
+
+func (cs *CombinedSensor) SetHumidityCorrection(correction CorrectionInterface) {
+	cs.humidity.SetCorrection(correction)
+}
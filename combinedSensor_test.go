@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSensor_Combo(t *testing.T) {
+	res := NewSensor(ComboSensor, "combo-1")
+
+	assert.IsType(t, &CombinedSensor{}, res)
+	assert.Equal(t, ComboSensor, res.GetType())
+	assert.Equal(t, "combo-1", res.GetName())
+	assert.Nil(t, res.GetValues())
+}
+
+func TestCombinedSensorAppendData_HappyPath(t *testing.T) {
+	sensor := NewCombinedSensor("combo-1").(*CombinedSensor)
+
+	err := sensor.AppendData([]string{"2000-01-01T00:00:00", "combo-1", "70.1", "45.2"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []float64{70.1}, sensor.GetTemperatureChannel().GetValues())
+	assert.Equal(t, []float64{45.2}, sensor.GetHumidityChannel().GetValues())
+}
+
+func TestCombinedSensorAppendData_MissingChannel(t *testing.T) {
+	sensor := NewCombinedSensor("combo-1")
+
+	err := sensor.AppendData([]string{"2000-01-01T00:00:00", "combo-1", "70.1"})
+
+	assert.NotNil(t, err)
+	assert.Equal(t, "Data doesn't have the expected number of elements for device combo-1", err.Error())
+}
+
+func TestCombinedSensorAppendData_NotTheRightSensor(t *testing.T) {
+	sensor := NewCombinedSensor("combo-1")
+
+	err := sensor.AppendData([]string{"2000-01-01T00:00:00", "combo-2", "70.1", "45.2"})
+
+	assert.NotNil(t, err)
+	assert.Equal(t, "Data is not for the right sensor", err.Error())
+}
+
+func TestCombinedSensorAppendData_BadHumidityKeepsChannelsAligned(t *testing.T) {
+	sensor := NewCombinedSensor("combo-1").(*CombinedSensor)
+
+	err := sensor.AppendData([]string{"2000-01-01T00:00:00", "combo-1", "70.1", "potato"})
+
+	assert.NotNil(t, err)
+	assert.Nil(t, sensor.GetTemperatureChannel().GetValues())
+	assert.Nil(t, sensor.GetHumidityChannel().GetValues())
+}
+
+func TestCombinedSensorCalculateRating(t *testing.T) {
+	ref := NewRefTemperatureHumidity(70.0, 45.0)
+
+	cases := []struct {
+		name        string
+		temperature []float64
+		humidity    []float64
+		expected    Rating
+	}{
+		{"both channels pass", []float64{69.5, 70.1, 71.3, 71.5, 69.8}, []float64{45.2, 45.3, 45.1}, ThermometerUltraPrecise},
+		{"temperature downgraded", []float64{67.5, 72.5}, []float64{45.2, 45.3}, ThermometerVeryPrecise},
+		{"humidity rejected", []float64{69.5, 70.1}, []float64{44.4, 42.1}, HumidityRejected},
+	}
+
+	for _, c := range cases {
+		sensor := &CombinedSensor{
+			sensorName:  "combo-1",
+			temperature: &Sensor{sensorType: Thermometer, sensorName: "combo-1", sensorValues: c.temperature},
+			humidity:    &Sensor{sensorType: HumiditySensor, sensorName: "combo-1", sensorValues: c.humidity},
+		}
+
+		sensor.SetRating(ref)
+
+		assert.Equal(t, c.expected, sensor.GetRating(), c.name)
+	}
+}
+
+func TestExtractSensorData_ComboSensor(t *testing.T) {
+	lines := []string{
+		"thermometer temp-1",
+		"2007-04-05T22:00 temp-1 72.4",
+		"combo combo-1",
+		"2007-04-05T22:00 combo-1 70.2 45.1",
+		"2007-04-05T22:01 combo-1 69.9 44.9",
+	}
+
+	res := ExtractSensorData(lines)
+
+	assert.Equal(t, 2, len(res))
+	combo := res[1].(*CombinedSensor)
+	assert.Equal(t, []float64{70.2, 69.9}, combo.GetTemperatureChannel().GetValues())
+	assert.Equal(t, []float64{45.1, 44.9}, combo.GetHumidityChannel().GetValues())
+}
+
+func TestExtractCalibratedSensorData_ComboChannels(t *testing.T) {
+	lines := []string{
+		"combo combo-1",
+		"2007-04-05T22:00 combo-1 70.2 45.1",
+	}
+	table, err := ReadCalibrationTable(strings.NewReader("combo-1 -0.2 1\ncombo-1:humidity 0.4 1\n"))
+	assert.Nil(t, err)
+
+	combo := ExtractCalibratedSensorData(lines, table)[0].(*CombinedSensor)
+
+	assert.InDelta(t, 70.0, combo.GetTemperatureChannel().GetValues()[0], 1e-9)
+	assert.InDelta(t, 45.5, combo.GetHumidityChannel().GetValues()[0], 1e-9)
+}
+
+func TestPrintReport_ComboChannels(t *testing.T) {
+	ref, sensors, _, _, err := GradeLog([]string{"reference 70.0 45.0", "combo combo-1", "2007-04-05T22:00 combo-1 70.1 45.2", "2007-04-05T22:01 combo-1 69.9 45.4"}, LegacyFormat, DefaultCSVColumnMapping, nil)
+	assert.Nil(t, err)
+
+	var out bytes.Buffer
+	PrintReport(&out, sensors, ref, outputProfiles[DefaultProfile])
+
+	assert.Equal(t, `combo-1 (combo): ultra precise
+  temperature channel:
+    readings: 2 | average: 70.00 | standard deviation: 0.14
+    expanded uncertainty: 0.31 (k=2)
+  humidity channel:
+    readings: 2 | average: 45.30 | standard deviation: 0.14
+    expanded uncertainty: 0.31 (k=2)
+  dew point: 47.85 | reference: 47.67 | deviation: 0.18
+`, out.String())
+
+	document := NewReportDocument(sensors, ref, nil, RunMetadata{}, outputProfiles[DefaultProfile])
+	assert.Len(t, document.Sensors[0].Channels, 2)
+	assert.Equal(t, HumidityChannel, document.Sensors[0].Channels[1].Channel)
+	assert.InDelta(t, 45.3, *document.Sensors[0].Channels[1].Average, 1e-9)
+	assert.InDelta(t, 47.67, *document.Reference.DewPoint, 0.01)
+	assert.InDelta(t, 0.18, *document.Sensors[0].DewPointDeviation, 0.01)
+}
+
+func TestCombinedSensorDewPoint(t *testing.T) {
+	ref := NewRefTemperatureHumidity(70.0, 45.0)
+
+	sensor := &CombinedSensor{
+		sensorName:  "combo-1",
+		temperature: &Sensor{sensorType: Thermometer, sensorName: "combo-1", sensorValues: []float64{70.0, 70.0}},
+		humidity:    &Sensor{sensorType: HumiditySensor, sensorName: "combo-1", sensorValues: []float64{45.0, 100.0}},
+	}
+
+	res := sensor.GetDewPointValues()
+
+	assert.Equal(t, 2, len(res))
+	assert.InDelta(t, ref.GetRefDewPoint(), res[0], 1e-9)
+	assert.InDelta(t, 70.0, res[1], 1e-9)
+	assert.InDelta(t, (res[0]+res[1])/2, sensor.GetAverageDewPoint(), 1e-9)
+	assert.InDelta(t, (70.0-res[0])/2, sensor.GetDewPointDeviation(ref), 1e-9)
+}
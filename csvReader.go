@@ -0,0 +1,327 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"strings"
+)
+
+/**
+ * CSV logs
+ *   Newer data loggers export one reading per row: timestamp,sensor,type,value
+ *   Columns are found by header name, so their order doesn't matter and they can be
+ *   renamed with a column mapping. Reference values are given by rows of type
+ *   "reference", the sensor column naming the quantity (temperature, humidity,
+ *   pressure or co2). Combo sensors carry their humidity in a second value column.
+ */
+const CSVReferenceType = "reference"
+const CSVTemperatureQuantity = "temperature"
+const CSVHumidityQuantity = "humidity"
+
+type CSVColumnMapping struct {
+	Timestamp   string
+	Sensor      string
+	Type        string
+	Value       string
+	SecondValue string
+}
+
+var DefaultCSVColumnMapping = CSVColumnMapping{
+	Timestamp:   "timestamp",
+	Sensor:      "sensor",
+	Type:        "type",
+	Value:       "value",
+	SecondValue: "value2",
+}
+
+/**
+ * Parsing a column mapping given as a list of field=header pairs, e.g.
+ * "timestamp=time,sensor=name". Fields missing from the list keep their default header
+ */
+func ParseCSVColumnMapping(spec string) (CSVColumnMapping, error) {
+	mapping := DefaultCSVColumnMapping
+	if strings.TrimSpace(spec) == "" {
+		return mapping, nil
+	}
+
+	for _, pair := range strings.Split(spec, ",") {
+		fieldHeader := strings.SplitN(pair, "=", 2)
+		if len(fieldHeader) != 2 || strings.TrimSpace(fieldHeader[1]) == "" {
+			return mapping, errors.New(Translate("Invalid CSV column mapping %s, expected field=header", pair))
+		}
+
+		header := strings.TrimSpace(fieldHeader[1])
+		switch strings.TrimSpace(fieldHeader[0]) {
+		case "timestamp":
+			mapping.Timestamp = header
+		case "sensor":
+			mapping.Sensor = header
+		case "type":
+			mapping.Type = header
+		case "value":
+			mapping.Value = header
+		case "value2":
+			mapping.SecondValue = header
+		default:
+			return mapping, errors.New(Translate("Unknown CSV column %s", fieldHeader[0]))
+		}
+	}
+
+	return mapping, nil
+}
+
+/**
+ * Reading a CSV log into the same model as ExtractRef and ExtractCalibratedSensorData.
+ * Like for the JSON Lines format, rows which can't be used are discarded and
+ * returned as diagnostics
+ */
+func ReadCSVLog(r io.Reader, mapping CSVColumnMapping, table CalibrationTable) (ReferenceInterface, []SensorInterface, []Diagnostic, error) {
+	reader := newCSVRecordReader(r)
+	logReader := newCSVLogReader(mapping, table)
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return logReader.results()
+	}
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if err := logReader.readHeader(header); err != nil {
+		return nil, nil, nil, err
+	}
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) && parseErr.Err == csv.ErrFieldCount {
+				logReader.reportFieldCount(parseErr.Line, record)
+				continue
+			}
+			return nil, nil, logReader.diagnostics, err
+		}
+
+		lineNumber, _ := reader.FieldPos(0)
+		logReader.readRecord(lineNumber, record)
+	}
+
+	return logReader.results()
+}
+
+func newCSVRecordReader(r io.Reader) *csv.Reader {
+	reader := csv.NewReader(r)
+	reader.Comment = CommentChar
+	reader.TrimLeadingSpace = true
+
+	return reader
+}
+
+type csvLogReader struct {
+	mapping CSVColumnMapping
+	table   CalibrationTable
+
+	header      []string
+	columns     csvColumns
+	diagnostics []Diagnostic
+	// Error stopping the reading of the log, when it's read line by line
+	err error
+
+	ref                         *RefTemperatureHumidity
+	hasTemperature, hasHumidity bool
+
+	// Rows of different sensors can be interleaved, keep the order in which sensors are found
+	sensors       []SensorInterface
+	sensorsByName map[string]SensorInterface
+
+	lineNumber int
+}
+
+func newCSVLogReader(mapping CSVColumnMapping, table CalibrationTable) *csvLogReader {
+	return &csvLogReader{
+		mapping:       mapping,
+		table:         table,
+		ref:           &RefTemperatureHumidity{},
+		sensors:       make([]SensorInterface, 0),
+		sensorsByName: make(map[string]SensorInterface),
+	}
+}
+
+func (cr *csvLogReader) readHeader(header []string) error {
+	columns, err := findCSVColumns(header, cr.mapping)
+	if err != nil {
+		return err
+	}
+
+	cr.header, cr.columns = header, columns
+	return nil
+}
+
+/**
+ * Reading the next line of the log, for logs received line by line. Rows can't
+ * span several lines, unlike in a CSV file
+ */
+func (cr *csvLogReader) readLine(line string) {
+	cr.lineNumber++
+	if cr.err != nil {
+		return
+	}
+
+	reader := newCSVRecordReader(strings.NewReader(line))
+	reader.FieldsPerRecord = len(cr.header)
+
+	record, err := reader.Read()
+	switch {
+	case err == io.EOF:
+		// Blank lines and comments
+	case cr.header == nil && err == nil:
+		cr.err = cr.readHeader(record)
+	case errors.Is(err, csv.ErrFieldCount) && cr.header != nil:
+		cr.reportFieldCount(cr.lineNumber, record)
+	case err != nil:
+		cr.err = err
+	default:
+		cr.readRecord(cr.lineNumber, record)
+	}
+}
+
+func (cr *csvLogReader) readRecord(lineNumber int, record []string) {
+	timestamp, name, sType := record[cr.columns.timestamp], record[cr.columns.sensor], record[cr.columns.sType]
+	values := []string{timestamp, name, record[cr.columns.value]}
+
+	if sType == CSVReferenceType {
+		if err := setCSVReference(cr.ref, name, record[cr.columns.value]); err != nil {
+			cr.report(lineNumber, err.Error())
+			return
+		}
+		cr.hasTemperature = cr.hasTemperature || name == CSVTemperatureQuantity
+		cr.hasHumidity = cr.hasHumidity || name == CSVHumidityQuantity
+		return
+	}
+
+	if !IsSensorType(sType) {
+		cr.report(lineNumber, Translate("Unknown sensor type %s", sType))
+		return
+	}
+
+	if _, err := ParseTimestamp(timestamp); err != nil {
+		cr.report(lineNumber, Translate("Invalid timestamp %s", timestamp))
+		return
+	}
+
+	if sType == ComboSensor {
+		if cr.columns.secondValue < 0 {
+			cr.report(lineNumber, Translate("Missing %s column for combo sensor %s", cr.mapping.SecondValue, name))
+			return
+		}
+		values = append(values, record[cr.columns.secondValue])
+	}
+
+	sensor, found := cr.sensorsByName[name]
+	if !found {
+		sensor = NewSensor(sType, name)
+		cr.table.Calibrate(sensor)
+		cr.sensorsByName[name] = sensor
+		cr.sensors = append(cr.sensors, sensor)
+	}
+
+	if sensor.GetType() != sType {
+		cr.report(lineNumber, Translate("Sensor %s is logged as %s and %s", name, sensor.GetType(), sType))
+		return
+	}
+
+	if err := sensor.AppendData(values); err != nil {
+		cr.report(lineNumber, err.Error())
+	}
+}
+
+func (cr *csvLogReader) reportFieldCount(lineNumber int, record []string) {
+	cr.report(lineNumber, Translate("Expected %d columns, got %d", len(cr.header), len(record)))
+}
+
+func (cr *csvLogReader) report(lineNumber int, message string) {
+	cr.diagnostics = append(cr.diagnostics, Diagnostic{Line: lineNumber, Message: message})
+}
+
+// Reference, sensors and diagnostics of the rows read so far
+func (cr *csvLogReader) results() (ReferenceInterface, []SensorInterface, []Diagnostic, error) {
+	if cr.err != nil {
+		return nil, nil, cr.diagnostics, cr.err
+	}
+
+	if cr.header == nil {
+		return nil, nil, nil, errors.New(Translate("The CSV log is empty"))
+	}
+
+	if !cr.hasTemperature || !cr.hasHumidity {
+		return nil, nil, cr.diagnostics, errors.New(Translate("The CSV log needs reference rows for the temperature and the humidity"))
+	}
+
+	return cr.ref, cr.sensors, cr.diagnostics, nil
+}
+
+type csvColumns struct {
+	timestamp   int
+	sensor      int
+	sType       int
+	value       int
+	secondValue int
+}
+
+func findCSVColumns(header []string, mapping CSVColumnMapping) (csvColumns, error) {
+	indexes := make(map[string]int)
+	for i, name := range header {
+		indexes[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	find := func(name string) (int, error) {
+		index, found := indexes[strings.ToLower(name)]
+		if !found {
+			return -1, errors.New(Translate("Missing column %s in the CSV header", name))
+		}
+		return index, nil
+	}
+
+	var columns csvColumns
+	var err error
+
+	if columns.timestamp, err = find(mapping.Timestamp); err != nil {
+		return columns, err
+	}
+	if columns.sensor, err = find(mapping.Sensor); err != nil {
+		return columns, err
+	}
+	if columns.sType, err = find(mapping.Type); err != nil {
+		return columns, err
+	}
+	if columns.value, err = find(mapping.Value); err != nil {
+		return columns, err
+	}
+
+	// The second value is only needed by combo sensors
+	columns.secondValue, _ = find(mapping.SecondValue)
+
+	return columns, nil
+}
+
+func setCSVReference(ref *RefTemperatureHumidity, quantity string, rawValue string) error {
+	value, err := ParseNumber(rawValue)
+	if err != nil {
+		return err
+	}
+
+	switch quantity {
+	case CSVTemperatureQuantity:
+		ref.SetRefTemperature(value)
+	case CSVHumidityQuantity:
+		ref.SetRefHumidity(value)
+	default:
+		return SetRefQuantity(ref, quantity, value)
+	}
+
+	return nil
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCSVColumnMapping_HappyPath(t *testing.T) {
+	res, err := ParseCSVColumnMapping("timestamp=time, sensor=name")
+
+	assert.Nil(t, err)
+	assert.Equal(t, "time", res.Timestamp)
+	assert.Equal(t, "name", res.Sensor)
+	assert.Equal(t, DefaultCSVColumnMapping.Type, res.Type)
+	assert.Equal(t, DefaultCSVColumnMapping.Value, res.Value)
+}
+
+func TestParseCSVColumnMapping_Empty(t *testing.T) {
+	res, err := ParseCSVColumnMapping("")
+
+	assert.Nil(t, err)
+	assert.Equal(t, DefaultCSVColumnMapping, res)
+}
+
+func TestParseCSVColumnMapping_Errors(t *testing.T) {
+	_, err := ParseCSVColumnMapping("timestamp")
+	assert.Equal(t, "Invalid CSV column mapping timestamp, expected field=header", err.Error())
+
+	_, err = ParseCSVColumnMapping("potato=spud")
+	assert.Equal(t, "Unknown CSV column potato", err.Error())
+}
+
+const csvReference = "timestamp,sensor,type,value\n,temperature,reference,70.0\n,humidity,reference,45.0\n"
+
+func TestReadCSVLog_HappyPath(t *testing.T) {
+	in := strings.NewReader(`timestamp,sensor,type,value
+,temperature,reference,70.0
+,humidity,reference,45.0
+2007-04-05T22:00,temp-1,thermometer,72.4
+2007-04-05T22:00,hum-1,humidity,45.2
+# interleaved sensors
+2007-04-05T22:01,temp-1,thermometer,76.0
+2007-04-05T22:01,hum-1,humidity,45.3
+`)
+
+	ref, sensors, _, err := ReadCSVLog(in, DefaultCSVColumnMapping, nil)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 70.0, ref.GetRefTemperature())
+	assert.Equal(t, 45.0, ref.GetRefHumidity())
+	assert.Equal(t, 2, len(sensors))
+	assert.Equal(t, Thermometer, sensors[0].GetType())
+	assert.Equal(t, "temp-1", sensors[0].GetName())
+	assert.Equal(t, []float64{72.4, 76.0}, sensors[0].GetValues())
+	assert.Equal(t, HumiditySensor, sensors[1].GetType())
+	assert.Equal(t, []float64{45.2, 45.3}, sensors[1].GetValues())
+}
+
+func TestReadCSVLog_ColumnMappingAndOrder(t *testing.T) {
+	in := strings.NewReader(`Kind,Reading,Name,Time
+reference,70.0,temperature,
+reference,45.0,humidity,
+reference,1013.25,pressure,
+pressure,1013.5,press-1,2007-04-05T22:00
+`)
+	mapping, _ := ParseCSVColumnMapping("timestamp=time,sensor=name,type=kind,value=reading")
+
+	ref, sensors, _, err := ReadCSVLog(in, mapping, nil)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1013.25, ref.GetRefPressure())
+	assert.Equal(t, 1, len(sensors))
+	assert.Equal(t, []float64{1013.5}, sensors[0].GetValues())
+}
+
+func TestReadCSVLog_ComboAndCalibration(t *testing.T) {
+	in := strings.NewReader(`timestamp,sensor,type,value,value2
+,temperature,reference,70.0,
+,humidity,reference,45.0,
+2007-04-05T22:00,combo-1,combo,71.0,45.2
+`)
+	table := CalibrationTable{"combo-1": NewLinearCorrection(-1, 1)}
+
+	_, sensors, _, err := ReadCSVLog(in, DefaultCSVColumnMapping, table)
+
+	assert.Nil(t, err)
+	combo := sensors[0].(*CombinedSensor)
+	assert.Equal(t, []float64{70.0}, combo.GetTemperatureChannel().GetValues())
+	assert.Equal(t, []float64{45.2}, combo.GetHumidityChannel().GetValues())
+}
+
+func TestReadCSVLog_DiscardsBadRows(t *testing.T) {
+	in := strings.NewReader(`timestamp,sensor,type,value
+,temperature,reference,70.0
+,humidity,reference,45.0
+2007-04-05T22:00,temp-1,thermometer,hot
+2007-04-05T22:01,temp-1,humidity,45.0
+2007-04-05T22:02,temp-1,thermometer
+2007-04-05T22:03,temp-1,thermometer,72.4
+`)
+
+	_, sensors, diagnostics, err := ReadCSVLog(in, DefaultCSVColumnMapping, nil)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(sensors))
+	assert.Equal(t, []float64{72.4}, sensors[0].GetValues())
+	assert.Equal(t, []Diagnostic{
+		{Line: 4, Message: "Error while parsing the recorded measure for devide temp-1 :strconv.ParseFloat: parsing \"hot\": invalid syntax"},
+		{Line: 5, Message: "Sensor temp-1 is logged as thermometer and humidity"},
+		{Line: 6, Message: "Expected 4 columns, got 3"},
+	}, diagnostics)
+}
+
+func TestReadCSVLog_InvalidValue(t *testing.T) {
+	in := strings.NewReader(csvReference + "2007-04-05T22:00,temp-1,thermometer,abc\n")
+
+	_, _, diagnostics, err := ReadCSVLog(in, DefaultCSVColumnMapping, nil)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []Diagnostic{{Line: 4, Message: "Error while parsing the recorded measure for devide temp-1 :strconv.ParseFloat: parsing \"abc\": invalid syntax"}}, diagnostics)
+}
+
+func TestReadCSVLog_UnknownSensorType(t *testing.T) {
+	in := strings.NewReader(csvReference + "2007-04-05T22:00,temp-1,potato,72.4\n")
+
+	_, sensors, diagnostics, err := ReadCSVLog(in, DefaultCSVColumnMapping, nil)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(sensors))
+	assert.Equal(t, []Diagnostic{{Line: 4, Message: "Unknown sensor type potato"}}, diagnostics)
+}
+
+func TestReadCSVLog_InvalidTimestamp(t *testing.T) {
+	in := strings.NewReader(csvReference + "not-a-time,temp-1,thermometer,72.4\n")
+
+	_, sensors, diagnostics, err := ReadCSVLog(in, DefaultCSVColumnMapping, nil)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(sensors))
+	assert.Equal(t, []Diagnostic{{Line: 4, Message: "Invalid timestamp not-a-time"}}, diagnostics)
+}
+
+func TestReadCSVLog_MissingColumn(t *testing.T) {
+	in := strings.NewReader("timestamp,sensor,value\n")
+
+	_, _, _, err := ReadCSVLog(in, DefaultCSVColumnMapping, nil)
+
+	assert.NotNil(t, err)
+	assert.Equal(t, "Missing column type in the CSV header", err.Error())
+}
+
+func TestReadCSVLog_MissingReference(t *testing.T) {
+	in := strings.NewReader("timestamp,sensor,type,value\n,temperature,reference,70.0\n")
+
+	_, _, _, err := ReadCSVLog(in, DefaultCSVColumnMapping, nil)
+
+	assert.NotNil(t, err)
+	assert.Equal(t, "The CSV log needs reference rows for the temperature and the humidity", err.Error())
+}
+
+func TestReadCSVLog_Empty(t *testing.T) {
+	_, _, _, err := ReadCSVLog(strings.NewReader(""), DefaultCSVColumnMapping, nil)
+
+	assert.NotNil(t, err)
+	assert.Equal(t, "The CSV log is empty", err.Error())
+}
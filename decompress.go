@@ -0,0 +1,34 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"io"
+)
+
+/**
+ * Compressed logs
+ *   Archived burn-in logs are stored compressed. Compression is detected by the
+ *   magic bytes at the start of the stream, whatever the file is named, and the
+ *   log is decompressed on the fly.
+ */
+var gzipMagic = []byte{0x1f, 0x8b}
+var bzip2Magic = []byte("BZh")
+
+func Decompress(r io.Reader) (io.Reader, error) {
+	buffered := bufio.NewReader(r)
+
+	// A short stream can't be compressed, Peek then returns what's there with an error
+	magic, _ := buffered.Peek(len(bzip2Magic))
+
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		return gzip.NewReader(buffered)
+	case bytes.HasPrefix(magic, bzip2Magic):
+		return bzip2.NewReader(buffered), nil
+	}
+
+	return buffered, nil
+}
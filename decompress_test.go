@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const compressedLog = "reference 70.0 45.0\nthermometer temp-1\n2007-04-05T22:00 temp-1 72.4\n"
+
+// compressedLog, compressed with bzip2 -9 (the standard library can't write bzip2)
+var bzip2Log = []byte{
+	0x42, 0x5a, 0x68, 0x39, 0x31, 0x41, 0x59, 0x26, 0x53, 0x59, 0xda, 0x11,
+	0x01, 0xfc, 0x00, 0x00, 0x1e, 0xdb, 0x80, 0x00, 0x10, 0x40, 0x03, 0x76,
+	0x90, 0x04, 0x00, 0x0b, 0x43, 0xd4, 0x00, 0x20, 0x00, 0x48, 0x6a, 0x9e,
+	0x4c, 0x48, 0x6c, 0x50, 0xcd, 0x21, 0x0d, 0x46, 0x41, 0xa0, 0x00, 0x5b,
+	0xb0, 0x8b, 0x55, 0xb0, 0xb4, 0x1d, 0x1d, 0xc1, 0xb4, 0x24, 0x04, 0x20,
+	0x8f, 0x2b, 0x76, 0x9c, 0x18, 0x30, 0x9d, 0xeb, 0xd9, 0xd8, 0x22, 0xce,
+	0xab, 0xa9, 0xe8, 0x3c, 0xd0, 0xc6, 0x41, 0x54, 0x83, 0xbe, 0x2e, 0xe4,
+	0x8a, 0x70, 0xa1, 0x21, 0xb4, 0x22, 0x03, 0xf8,
+}
+
+func gzipLog(t *testing.T) []byte {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	_, err := writer.Write([]byte(compressedLog))
+	assert.Nil(t, err)
+	assert.Nil(t, writer.Close())
+
+	return buf.Bytes()
+}
+
+func TestDecompress_HappyPath(t *testing.T) {
+	for name, compressed := range map[string][]byte{"gzip": gzipLog(t), "bzip2": bzip2Log, "plain": []byte(compressedLog)} {
+		r, err := Decompress(bytes.NewReader(compressed))
+		assert.Nil(t, err, name)
+
+		res, err := ioutil.ReadAll(r)
+		assert.Nil(t, err, name)
+		assert.Equal(t, compressedLog, string(res), name)
+	}
+}
+
+func TestDecompress_ShortInput(t *testing.T) {
+	for _, in := range []string{"", "\x1f", "BZ"} {
+		r, err := Decompress(bytes.NewReader([]byte(in)))
+		assert.Nil(t, err)
+
+		res, _ := ioutil.ReadAll(r)
+		assert.Equal(t, in, string(res))
+	}
+}
+
+func TestDecompress_CorruptedGzip(t *testing.T) {
+	_, err := Decompress(bytes.NewReader([]byte{0x1f, 0x8b, 0x00}))
+
+	assert.NotNil(t, err)
+}
+
+func TestReadLogFile_Compressed(t *testing.T) {
+	// Compression is detected from the content, not from the file name
+	path := filepath.Join(t.TempDir(), "burn-in.log")
+	assert.Nil(t, ioutil.WriteFile(path, bzip2Log, 0644))
+
+	lines, err := ReadLogFile(path)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"reference 70.0 45.0", "thermometer temp-1", "2007-04-05T22:00 temp-1 72.4"}, lines)
+}
+
+func TestReadLogInput_Compressed(t *testing.T) {
+	lines, format, err := ReadLogInput(bytes.NewReader(gzipLog(t)), AutoFormat, DefaultCSVColumnMapping)
+
+	assert.Nil(t, err)
+	assert.Equal(t, LegacyFormat, format)
+	assert.Equal(t, 3, len(lines))
+}
+
+func TestReadLogInput_TruncatedGzip(t *testing.T) {
+	compressed := gzipLog(t)
+
+	_, _, err := ReadLogInput(bytes.NewReader(compressed[:len(compressed)-10]), AutoFormat, DefaultCSVColumnMapping)
+
+	assert.NotNil(t, err)
+}
+
+func TestReadLogFile_Missing(t *testing.T) {
+	_, err := ReadLogFile(filepath.Join(t.TempDir(), "missing.log.gz"))
+
+	assert.True(t, os.IsNotExist(err))
+}
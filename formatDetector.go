@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+/**
+ * Input format detection
+ *   Operators don't always know which logger wrote a log. The first lines of the
+ *   input are sniffed and each format checks whether they look like its own; the
+ *   input is only routed to a reader when exactly one format recognizes it.
+ */
+const AutoFormat = "auto"
+
+// Number of significant lines (not blank, not comments) looked at to detect the format
+const DetectionSampleSize = 10
+
+func DetectFormat(lines []string, mapping CSVColumnMapping) (string, error) {
+	sample := getDetectionSample(lines)
+	if len(sample) == 0 {
+		return "", errors.New(Translate("The log is empty, can't detect its format"))
+	}
+
+	var candidates []string
+	if looksLikeLegacyLog(sample) {
+		candidates = append(candidates, LegacyFormat)
+	}
+	if looksLikeCSVLog(sample, mapping) {
+		candidates = append(candidates, CSVFormat)
+	}
+	if looksLikeJSONLog(sample) {
+		candidates = append(candidates, JSONLinesFormat)
+	}
+
+	switch len(candidates) {
+	case 0:
+		return "", errors.New(Translate("Can't detect the format of the log, set it with -format (%s)", strings.Join(InputFormats, ", ")))
+	case 1:
+		return candidates[0], nil
+	}
+
+	return "", errors.New(Translate("The log could be in several formats (%s), set it with -format", strings.Join(candidates, ", ")))
+}
+
+func getDetectionSample(lines []string) []string {
+	var sample []string
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == CommentChar {
+			continue
+		}
+
+		sample = append(sample, line)
+		if len(sample) == DetectionSampleSize {
+			break
+		}
+	}
+
+	return sample
+}
+
+// Legacy logs start with a reference line, possibly after some metadata
+func looksLikeLegacyLog(sample []string) bool {
+	for _, line := range sample {
+		tokens, err := LexLine(line)
+		if err != nil || len(tokens) == 0 {
+			return false
+		}
+
+		switch tokens[0].Text {
+		case MetadataKeyword:
+			continue
+		case ReferenceKeyword:
+			return len(tokens) >= 3 && tokens[1].Kind == TokenNumber && tokens[2].Kind == TokenNumber
+		}
+
+		return false
+	}
+
+	return false
+}
+
+// CSV logs start with a header naming the expected columns, followed by rows of the same width
+func looksLikeCSVLog(sample []string, mapping CSVColumnMapping) bool {
+	reader := csv.NewReader(strings.NewReader(strings.Join(sample, "\n")))
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+	if err != nil || len(records) == 0 || len(records[0]) < 2 {
+		return false
+	}
+
+	_, err = findCSVColumns(records[0], mapping)
+	return err == nil
+}
+
+// JSON Lines logs only have JSON objects
+func looksLikeJSONLog(sample []string) bool {
+	for _, line := range sample {
+		if line[0] != '{' || !json.Valid([]byte(line)) {
+			return false
+		}
+	}
+
+	return true
+}
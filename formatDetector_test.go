@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectFormat_HappyPath(t *testing.T) {
+	tests := map[string][]string{
+		LegacyFormat: {"", "# rig 3", "meta rig=3", "reference 70.0 45.0", "thermometer temp-1", "2007-04-05T22:00 temp-1 72.4"},
+		CSVFormat:    {"# exported by logger", "timestamp,sensor,type,value", ",temperature,reference,70.0", "2007-04-05T22:00,temp-1,thermometer,72.4"},
+		JSONLinesFormat: {
+			`{"kind": "reference", "temperature": 70.0, "humidity": 45.0}`,
+			"",
+			`{"kind": "declaration", "type": "thermometer", "sensor": "temp-1"}`,
+		},
+	}
+
+	for expected, lines := range tests {
+		res, err := DetectFormat(lines, DefaultCSVColumnMapping)
+
+		assert.Nil(t, err, expected)
+		assert.Equal(t, expected, res)
+	}
+}
+
+func TestDetectFormat_LocaleFormattedLegacyLog(t *testing.T) {
+	nf, _ := NewNumberFormat(",", ".")
+	SetInputNumberFormat(nf)
+	defer SetInputNumberFormat(DefaultNumberFormat)
+
+	res, err := DetectFormat([]string{"reference 70,0 45,0", "thermometer temp-1"}, DefaultCSVColumnMapping)
+
+	assert.Nil(t, err)
+	assert.Equal(t, LegacyFormat, res)
+}
+
+func TestDetectFormat_CustomCSVColumns(t *testing.T) {
+	lines := []string{"time,name,kind,reading", ",temperature,reference,70.0"}
+
+	_, err := DetectFormat(lines, DefaultCSVColumnMapping)
+	assert.NotNil(t, err)
+
+	mapping, _ := ParseCSVColumnMapping("timestamp=time,sensor=name,type=kind,value=reading")
+	res, err := DetectFormat(lines, mapping)
+
+	assert.Nil(t, err)
+	assert.Equal(t, CSVFormat, res)
+}
+
+func TestDetectFormat_Unknown(t *testing.T) {
+	_, err := DetectFormat([]string{"thermometer temp-1", "2007-04-05T22:00 temp-1 72.4"}, DefaultCSVColumnMapping)
+
+	assert.NotNil(t, err)
+	assert.Equal(t, "Can't detect the format of the log, set it with -format (log, csv, jsonl)", err.Error())
+
+	_, err = DetectFormat([]string{"", "# nothing"}, DefaultCSVColumnMapping)
+
+	assert.NotNil(t, err)
+	assert.Equal(t, "The log is empty, can't detect its format", err.Error())
+}
+
+func TestDetectFormat_Ambiguous(t *testing.T) {
+	// A CSV header which happens to be a valid reference line
+	mapping, _ := ParseCSVColumnMapping("timestamp=reference 70.0 45.0 time,sensor=name,type=kind,value=reading")
+
+	_, err := DetectFormat([]string{"reference 70.0 45.0 time,name,kind,reading"}, mapping)
+
+	assert.NotNil(t, err)
+	assert.Equal(t, "The log could be in several formats (log, csv), set it with -format", err.Error())
+}
+
+func TestReadLogInput_DetectsFormat(t *testing.T) {
+	in := strings.NewReader("timestamp,sensor,type,value\n,temperature,reference,70.0\n\x1D")
+
+	lines, format, err := ReadLogInput(in, AutoFormat, DefaultCSVColumnMapping)
+
+	assert.Nil(t, err)
+	assert.Equal(t, CSVFormat, format)
+	assert.Equal(t, 2, len(lines))
+
+	_, format, err = ReadLogInput(strings.NewReader("potato\n\x1D"), LegacyFormat, DefaultCSVColumnMapping)
+
+	assert.Nil(t, err)
+	assert.Equal(t, LegacyFormat, format)
+}
+
+func TestRunMetadata_PrintsInputFormat(t *testing.T) {
+	metadata := NewRunMetadata(SpecProfile)
+	metadata.InputFormat = JSONLinesFormat
+
+	var out bytes.Buffer
+	metadata.Print(&out)
+
+	assert.Contains(t, out.String(), "Input format: jsonl\n")
+}
@@ -0,0 +1,341 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+/**
+ * Job queue
+ *   Large logs are uploaded to the jobs directory and graded in the background by a
+ *   bounded pool of workers, so the HTTP request doesn't stay open while they're
+ *   graded. Each job is saved as "<id>.json" next to its upload "<id>.log": jobs
+ *   which were queued or running when the server stopped are queued again when
+ *   it's restarted. The upload is removed once the job is finished, and the job
+ *   itself once it's been finished for longer than the retention.
+ */
+const JobQueued = "queued"
+const JobRunning = "running"
+const JobDone = "done"
+const JobFailed = "failed"
+
+const DefaultJobWorkers = 2
+
+// How long finished jobs are kept
+const DefaultJobRetention = 7 * 24 * time.Hour
+
+// How often the jobs past the retention are removed
+const JobPruneInterval = time.Hour
+
+type Job struct {
+	ID        string          `json:"id"`
+	Status    string          `json:"status"`
+	Format    string          `json:"format"`
+	Submitted time.Time       `json:"submitted"`
+	Started   *time.Time      `json:"started,omitempty"`
+	Finished  *time.Time      `json:"finished,omitempty"`
+	Error     string          `json:"error,omitempty"`
+	Result    *ReportDocument `json:"result,omitempty"`
+}
+
+// Grading the log uploaded for a job, in the given format
+type JobGrader func(path string, format string) (ReportDocument, error)
+
+type JobQueue struct {
+	dir       string
+	workers   int
+	grade     JobGrader
+	retention time.Duration
+
+	lock    sync.Mutex
+	ready   *sync.Cond
+	jobs    map[string]*Job
+	pending []string
+	stopped bool
+	done    chan struct{}
+	running sync.WaitGroup
+}
+
+/**
+ * Opening the jobs directory, the jobs it holds are loaded and the unfinished ones
+ * are queued again
+ */
+func OpenJobQueue(dir string, workers int, grade JobGrader) (*JobQueue, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	queue := &JobQueue{
+		dir:       dir,
+		workers:   workers,
+		grade:     grade,
+		retention: DefaultJobRetention,
+		jobs:      make(map[string]*Job),
+		done:      make(chan struct{}),
+	}
+	queue.ready = sync.NewCond(&queue.lock)
+
+	if err := queue.load(); err != nil {
+		return nil, err
+	}
+
+	return queue, nil
+}
+
+func (q *JobQueue) load() error {
+	paths, err := filepath.Glob(filepath.Join(q.dir, "*.json"))
+	if err != nil {
+		return err
+	}
+
+	var unfinished []*Job
+	for _, path := range paths {
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		job := &Job{}
+		if err := json.Unmarshal(content, job); err != nil {
+			logger.Info(Translate("Can't load the job %s: %s", path, err))
+			continue
+		}
+		q.jobs[job.ID] = job
+
+		if job.Status == JobQueued || job.Status == JobRunning {
+			unfinished = append(unfinished, job)
+		}
+	}
+
+	// Jobs are graded in the order they were submitted
+	sort.Slice(unfinished, func(i, j int) bool {
+		return unfinished[i].Submitted.Before(unfinished[j].Submitted)
+	})
+	for _, job := range unfinished {
+		job.Status, job.Started = JobQueued, nil
+		q.pending = append(q.pending, job.ID)
+	}
+
+	return nil
+}
+
+// Finished jobs are kept this long, forever when it's not positive
+func (q *JobQueue) SetRetention(retention time.Duration) {
+	q.retention = retention
+}
+
+/**
+ * Starting the workers grading the queued jobs, and the removal of the jobs past
+ * the retention
+ */
+func (q *JobQueue) Start() {
+	for i := 0; i < q.workers; i++ {
+		q.running.Add(1)
+		go q.work()
+	}
+
+	q.running.Add(1)
+	go q.pruneEvery(JobPruneInterval)
+}
+
+/**
+ * Stopping the workers once they're done with the job they're grading. Jobs still
+ * queued are graded when the queue is opened again
+ */
+func (q *JobQueue) Stop() {
+	q.lock.Lock()
+	if !q.stopped {
+		q.stopped = true
+		close(q.done)
+	}
+	q.ready.Broadcast()
+	q.lock.Unlock()
+
+	q.running.Wait()
+}
+
+/**
+ * Saving an upload as a new job, which is queued
+ */
+func (q *JobQueue) Submit(upload io.Reader, format string) (Job, error) {
+	id, err := newJobID()
+	if err != nil {
+		return Job{}, err
+	}
+
+	file, err := os.Create(q.uploadPath(id))
+	if err != nil {
+		return Job{}, err
+	}
+	_, err = io.Copy(file, upload)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(q.uploadPath(id))
+		return Job{}, err
+	}
+
+	job := &Job{ID: id, Status: JobQueued, Format: format, Submitted: time.Now().UTC()}
+
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if err := q.save(job); err != nil {
+		os.Remove(q.uploadPath(id))
+		return Job{}, err
+	}
+	q.jobs[id] = job
+	q.pending = append(q.pending, id)
+	q.ready.Signal()
+
+	return *job, nil
+}
+
+func (q *JobQueue) GetJob(id string) (Job, bool) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	job, found := q.jobs[id]
+	if !found {
+		return Job{}, false
+	}
+
+	return *job, true
+}
+
+/**
+ * Removing the jobs finished for longer than the retention, from memory and disk
+ */
+func (q *JobQueue) Prune(now time.Time) {
+	if q.retention <= 0 {
+		return
+	}
+
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	for id, job := range q.jobs {
+		if job.Finished == nil || now.Sub(*job.Finished) < q.retention {
+			continue
+		}
+
+		if err := os.Remove(q.jobPath(id)); err != nil && !os.IsNotExist(err) {
+			logger.Info(err.Error())
+			continue
+		}
+		// Left behind if the server stopped right after the job was finished
+		os.Remove(q.uploadPath(id))
+		delete(q.jobs, id)
+	}
+}
+
+func (q *JobQueue) pruneEvery(interval time.Duration) {
+	defer q.running.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		q.Prune(time.Now())
+
+		select {
+		case <-q.done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (q *JobQueue) work() {
+	defer q.running.Done()
+
+	for {
+		job := q.next()
+		if job == nil {
+			return
+		}
+
+		result, err := q.grade(q.uploadPath(job.ID), job.Format)
+
+		q.lock.Lock()
+		finished := time.Now().UTC()
+		job.Finished = &finished
+		if err != nil {
+			job.Status, job.Error = JobFailed, err.Error()
+		} else {
+			job.Status, job.Result = JobDone, &result
+		}
+		if err := q.save(job); err != nil {
+			logger.Info(err.Error())
+		}
+		q.lock.Unlock()
+
+		os.Remove(q.uploadPath(job.ID))
+		logger.Debug(Translate("Job %s %s", job.ID, job.Status))
+	}
+}
+
+// Waiting for a queued job and marking it as running, nil once the queue is stopped
+func (q *JobQueue) next() *Job {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	for len(q.pending) == 0 && !q.stopped {
+		q.ready.Wait()
+	}
+	if q.stopped {
+		return nil
+	}
+
+	job := q.jobs[q.pending[0]]
+	q.pending = q.pending[1:]
+
+	started := time.Now().UTC()
+	job.Status, job.Started = JobRunning, &started
+	if err := q.save(job); err != nil {
+		logger.Info(err.Error())
+	}
+
+	return job
+}
+
+// Writing the job to a temporary file first, so a crash doesn't leave half of it
+func (q *JobQueue) save(job *Job) error {
+	content, err := json.MarshalIndent(job, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path := q.jobPath(job.ID)
+	if err := ioutil.WriteFile(path+".tmp", content, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(path+".tmp", path)
+}
+
+func (q *JobQueue) jobPath(id string) string {
+	return filepath.Join(q.dir, id+".json")
+}
+
+func (q *JobQueue) uploadPath(id string) string {
+	return filepath.Join(q.dir, id+".log")
+}
+
+func newJobID() (string, error) {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(id), nil
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func waitForJob(t *testing.T, queue *JobQueue, id string) Job {
+	var job Job
+	waitFor(t, func() bool {
+		job, _ = queue.GetJob(id)
+		return job.Status == JobDone || job.Status == JobFailed
+	})
+
+	return job
+}
+
+func TestJobQueue_Submit(t *testing.T) {
+	dir := t.TempDir()
+	queue, err := OpenJobQueue(dir, 2, NewServer().GradeFile)
+	assert.Nil(t, err)
+	queue.Start()
+	defer queue.Stop()
+
+	job, err := queue.Submit(strings.NewReader(cliLog), AutoFormat)
+	assert.Nil(t, err)
+	assert.Equal(t, JobQueued, job.Status)
+	assert.Len(t, job.ID, 16)
+
+	job = waitForJob(t, queue, job.ID)
+	assert.Equal(t, JobDone, job.Status)
+	assert.Equal(t, "temp-1", job.Result.Sensors[0].Name)
+	assert.NotNil(t, job.Finished)
+
+	// The upload is removed once graded, the job is kept
+	_, err = os.Stat(filepath.Join(dir, job.ID+".log"))
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(filepath.Join(dir, job.ID+".json"))
+	assert.Nil(t, err)
+
+	_, found := queue.GetJob("0123456789abcdef")
+	assert.False(t, found)
+}
+
+func TestJobQueue_Failed(t *testing.T) {
+	grade := func(path string, format string) (ReportDocument, error) {
+		return ReportDocument{}, errors.New("no reference")
+	}
+	queue, _ := OpenJobQueue(t.TempDir(), 1, grade)
+	queue.Start()
+	defer queue.Stop()
+
+	job, _ := queue.Submit(strings.NewReader(cliLog), LegacyFormat)
+	job = waitForJob(t, queue, job.ID)
+
+	assert.Equal(t, JobFailed, job.Status)
+	assert.Equal(t, "no reference", job.Error)
+	assert.Nil(t, job.Result)
+}
+
+func TestJobQueue_Restart(t *testing.T) {
+	dir := t.TempDir()
+	queue, _ := OpenJobQueue(dir, 1, NewServer().GradeFile)
+
+	// Submitted while the workers aren't running, e.g. right before the server stopped
+	job, err := queue.Submit(strings.NewReader(cliLog), LegacyFormat)
+	assert.Nil(t, err)
+
+	reopened, err := OpenJobQueue(dir, 1, NewServer().GradeFile)
+	assert.Nil(t, err)
+	loaded, found := reopened.GetJob(job.ID)
+	assert.True(t, found)
+	assert.Equal(t, JobQueued, loaded.Status)
+	assert.Equal(t, LegacyFormat, loaded.Format)
+
+	reopened.Start()
+	defer reopened.Stop()
+	assert.Equal(t, JobDone, waitForJob(t, reopened, job.ID).Status)
+
+	// Finished jobs aren't graded again
+	again, _ := OpenJobQueue(dir, 1, nil)
+	loaded, _ = again.GetJob(job.ID)
+	assert.Equal(t, JobDone, loaded.Status)
+	assert.Len(t, again.pending, 0)
+}
+
+func TestJobQueue_Prune(t *testing.T) {
+	dir := t.TempDir()
+	queue, _ := OpenJobQueue(dir, 1, NewServer().GradeFile)
+	queue.SetRetention(time.Hour)
+
+	queued, _ := queue.Submit(strings.NewReader(cliLog), LegacyFormat)
+	queue.Start()
+	finished := waitForJob(t, queue, queued.ID)
+	queue.Stop()
+	queued, _ = queue.Submit(strings.NewReader(cliLog), LegacyFormat)
+
+	// Finished jobs are kept for the retention
+	queue.Prune(finished.Finished.Add(59 * time.Minute))
+	_, found := queue.GetJob(finished.ID)
+	assert.True(t, found)
+
+	queue.Prune(finished.Finished.Add(time.Hour))
+	_, found = queue.GetJob(finished.ID)
+	assert.False(t, found)
+	_, err := os.Stat(filepath.Join(dir, finished.ID+".json"))
+	assert.True(t, os.IsNotExist(err))
+
+	// Jobs which aren't finished stay whatever their age
+	_, found = queue.GetJob(queued.ID)
+	assert.True(t, found)
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+)
+
+/**
+ * JSON Lines logs
+ *   The rig controller emits one JSON object per line, the "kind" field telling
+ *   what the record is:
+ *     {"kind": "reference", "temperature": 70.0, "humidity": 45.0, "co2": 400, "temperature_uncertainty": 0.05}
+ *     {"kind": "declaration", "type": "thermometer", "sensor": "temp-1"}
+ *     {"kind": "reading", "timestamp": "2007-04-05T22:00", "sensor": "temp-1", "value": 72.4}
+ *   Combo sensors give their humidity in "value2". Values are JSON numbers, so the
+ *   input number format doesn't apply. Records breaking the schema are discarded
+ *   and reported as diagnostics.
+ */
+const JSONReferenceKind = "reference"
+const JSONDeclarationKind = "declaration"
+const JSONReadingKind = "reading"
+
+type jsonRecord struct {
+	Kind string `json:"kind"`
+
+	// Reference
+	Temperature *float64 `json:"temperature"`
+	Humidity    *float64 `json:"humidity"`
+	Pressure    *float64 `json:"pressure"`
+	CO2         *float64 `json:"co2"`
+	// Standard uncertainties of the reference instruments
+	TemperatureUncertainty *float64 `json:"temperature_uncertainty,omitempty"`
+	HumidityUncertainty    *float64 `json:"humidity_uncertainty,omitempty"`
+	PressureUncertainty    *float64 `json:"pressure_uncertainty,omitempty"`
+	CO2Uncertainty         *float64 `json:"co2_uncertainty,omitempty"`
+
+	// Declaration and reading
+	Type      string   `json:"type"`
+	Sensor    string   `json:"sensor"`
+	Timestamp string   `json:"timestamp"`
+	Value     *float64 `json:"value"`
+	Value2    *float64 `json:"value2"`
+}
+
+type jsonLogReader struct {
+	table       CalibrationTable
+	diagnostics []Diagnostic
+	// Error stopping the reading of the log
+	err error
+
+	ref           *RefTemperatureHumidity
+	sensors       []SensorInterface
+	sensorsByName map[string]SensorInterface
+
+	lineNumber int
+}
+
+func ReadJSONLog(r io.Reader, table CalibrationTable) (ReferenceInterface, []SensorInterface, []Diagnostic, error) {
+	reader := newJSONLogReader(table)
+
+	scan := bufio.NewScanner(r)
+	for scan.Scan() {
+		reader.readLine(scan.Text())
+	}
+	reader.err = scan.Err()
+
+	return reader.results()
+}
+
+func newJSONLogReader(table CalibrationTable) *jsonLogReader {
+	return &jsonLogReader{
+		table:         table,
+		sensors:       make([]SensorInterface, 0),
+		sensorsByName: make(map[string]SensorInterface),
+	}
+}
+
+func (jr *jsonLogReader) readLine(line string) {
+	jr.lineNumber++
+
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return
+	}
+
+	record, err := decodeJSONRecord(line)
+	if err != nil {
+		jr.report(jr.lineNumber, Translate("Invalid record: %s", err.Error()))
+		return
+	}
+
+	switch record.Kind {
+	case JSONReferenceKind:
+		jr.readReference(jr.lineNumber, record)
+	case JSONDeclarationKind:
+		jr.readDeclaration(jr.lineNumber, record)
+	case JSONReadingKind:
+		jr.readReading(jr.lineNumber, record)
+	case "":
+		jr.report(jr.lineNumber, Translate("Missing field %s", "kind"))
+	default:
+		jr.report(jr.lineNumber, Translate("Unknown record kind %s", record.Kind))
+	}
+}
+
+// Reference, sensors and diagnostics of the records read so far
+func (jr *jsonLogReader) results() (ReferenceInterface, []SensorInterface, []Diagnostic, error) {
+	if jr.err != nil {
+		return nil, nil, jr.diagnostics, jr.err
+	}
+
+	if jr.ref == nil {
+		return nil, nil, jr.diagnostics, errors.New(Translate("No reference record found in the log"))
+	}
+
+	return jr.ref, jr.sensors, jr.diagnostics, nil
+}
+
+func decodeJSONRecord(line string) (jsonRecord, error) {
+	var record jsonRecord
+
+	decoder := json.NewDecoder(bytes.NewReader([]byte(line)))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&record); err != nil {
+		return record, err
+	}
+
+	// Only one object per line
+	if decoder.More() {
+		return record, errors.New(Translate("unexpected data after the JSON object"))
+	}
+
+	return record, nil
+}
+
+func (jr *jsonLogReader) readReference(lineNumber int, record jsonRecord) {
+	if jr.ref != nil {
+		jr.report(lineNumber, Translate("Only one reference record is allowed, this one is ignored"))
+		return
+	}
+
+	if !jr.require(lineNumber, record.Temperature != nil, "temperature") || !jr.require(lineNumber, record.Humidity != nil, "humidity") {
+		return
+	}
+
+	ref := &RefTemperatureHumidity{}
+	ref.SetRefTemperature(*record.Temperature)
+	ref.SetRefHumidity(*record.Humidity)
+	if record.Pressure != nil {
+		ref.SetRefPressure(*record.Pressure)
+	}
+	if record.CO2 != nil {
+		ref.SetRefCO2(*record.CO2)
+	}
+	uncertainties := []struct {
+		quantity string
+		value    *float64
+	}{
+		{RefTemperatureUncertaintyKey, record.TemperatureUncertainty},
+		{RefHumidityUncertaintyKey, record.HumidityUncertainty},
+		{RefPressureUncertaintyKey, record.PressureUncertainty},
+		{RefCO2UncertaintyKey, record.CO2Uncertainty},
+	}
+	for _, u := range uncertainties {
+		if u.value == nil {
+			continue
+		}
+		if err := SetRefQuantity(ref, u.quantity, *u.value); err != nil {
+			jr.report(lineNumber, err.Error())
+			return
+		}
+	}
+
+	jr.ref = ref
+}
+
+func (jr *jsonLogReader) readDeclaration(lineNumber int, record jsonRecord) {
+	if !jr.require(lineNumber, record.Type != "", "type") || !jr.require(lineNumber, record.Sensor != "", "sensor") {
+		return
+	}
+
+	if !IsSensorType(record.Type) {
+		jr.report(lineNumber, Translate("Unknown sensor type %s", record.Type))
+		return
+	}
+
+	if _, found := jr.sensorsByName[record.Sensor]; found {
+		jr.report(lineNumber, Translate("Sensor %s is declared twice", record.Sensor))
+		return
+	}
+
+	sensor := NewSensor(record.Type, record.Sensor)
+	jr.table.Calibrate(sensor)
+
+	jr.sensorsByName[record.Sensor] = sensor
+	jr.sensors = append(jr.sensors, sensor)
+}
+
+func (jr *jsonLogReader) readReading(lineNumber int, record jsonRecord) {
+	if !jr.require(lineNumber, record.Timestamp != "", "timestamp") ||
+		!jr.require(lineNumber, record.Sensor != "", "sensor") ||
+		!jr.require(lineNumber, record.Value != nil, "value") {
+		return
+	}
+
+	if _, err := ParseTimestamp(record.Timestamp); err != nil {
+		jr.report(lineNumber, Translate("Invalid timestamp %s", record.Timestamp))
+		return
+	}
+
+	sensor, found := jr.sensorsByName[record.Sensor]
+	if !found {
+		jr.report(lineNumber, Translate("Reading for sensor %s before any sensor declaration", record.Sensor))
+		return
+	}
+
+	switch s := sensor.(type) {
+	case *CombinedSensor:
+		if !jr.require(lineNumber, record.Value2 != nil, "value2") {
+			return
+		}
+		s.temperature.appendValue(*record.Value)
+		s.humidity.appendValue(*record.Value2)
+	case *Sensor:
+		if record.Value2 != nil {
+			jr.report(lineNumber, Translate("Sensor %s expects %d value(s), got %d", record.Sensor, 1, 2))
+			return
+		}
+		s.appendValue(*record.Value)
+	}
+}
+
+func (jr *jsonLogReader) require(lineNumber int, present bool, field string) bool {
+	if !present {
+		jr.report(lineNumber, Translate("Missing field %s", field))
+	}
+
+	return present
+}
+
+func (jr *jsonLogReader) report(lineNumber int, message string) {
+	jr.diagnostics = append(jr.diagnostics, Diagnostic{Line: lineNumber, Message: message})
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadJSONLog_HappyPath(t *testing.T) {
+	in := strings.NewReader(`{"kind": "reference", "temperature": 70.0, "humidity": 45.0, "co2": 400}
+{"kind": "declaration", "type": "thermometer", "sensor": "temp-1"}
+{"kind": "declaration", "type": "combo", "sensor": "combo-1"}
+
+{"kind": "reading", "timestamp": "2007-04-05T22:00", "sensor": "temp-1", "value": 72.4}
+{"kind": "reading", "timestamp": "2007-04-05T22:00", "sensor": "combo-1", "value": 70.1, "value2": 45.2}
+{"kind": "reading", "timestamp": "2007-04-05T22:01", "sensor": "temp-1", "value": 76.0}
+`)
+
+	ref, sensors, diagnostics, err := ReadJSONLog(in, nil)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(diagnostics))
+	assert.Equal(t, 70.0, ref.GetRefTemperature())
+	assert.Equal(t, 45.0, ref.GetRefHumidity())
+	assert.Equal(t, 400.0, ref.GetRefCO2())
+	assert.False(t, ref.HasRefPressure())
+	assert.Equal(t, 2, len(sensors))
+	assert.Equal(t, []float64{72.4, 76.0}, sensors[0].GetValues())
+	combo := sensors[1].(*CombinedSensor)
+	assert.Equal(t, []float64{70.1}, combo.GetTemperatureChannel().GetValues())
+	assert.Equal(t, []float64{45.2}, combo.GetHumidityChannel().GetValues())
+}
+
+func TestReadJSONLog_ReferenceUncertainties(t *testing.T) {
+	in := strings.NewReader(`{"kind": "reference", "temperature": 70.0, "humidity": 45.0, "temperature_uncertainty": 0.05, "humidity_uncertainty": 0.8}
+`)
+
+	ref, _, diagnostics, err := ReadJSONLog(in, nil)
+
+	assert.Nil(t, err)
+	assert.Empty(t, diagnostics)
+	assert.Equal(t, 0.05, ref.GetRefTemperatureUncertainty())
+	assert.Equal(t, 0.8, ref.GetRefHumidityUncertainty())
+}
+
+func TestReadJSONLog_Calibration(t *testing.T) {
+	in := strings.NewReader(`{"kind": "reference", "temperature": 70.0, "humidity": 45.0}
+{"kind": "declaration", "type": "thermometer", "sensor": "temp-1"}
+{"kind": "reading", "timestamp": "2007-04-05T22:00", "sensor": "temp-1", "value": 72.0}
+`)
+	table := CalibrationTable{"temp-1": NewLinearCorrection(-2, 1)}
+
+	_, sensors, _, err := ReadJSONLog(in, table)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []float64{70.0}, sensors[0].GetValues())
+}
+
+func TestReadJSONLog_SchemaDiagnostics(t *testing.T) {
+	in := strings.NewReader(`{"kind": "reference", "temperature": 70.0}
+{"kind": "reference", "temperature": 70.0, "humidity": 45.0}
+{"kind": "reference", "temperature": 71.0, "humidity": 45.0}
+not json
+{"kind": "reading", "sensor": "temp-1", "value": 72.4, "unit": "F"}
+{"temperature": 70.0}
+{"kind": "calibration"}
+{"kind": "declaration", "type": "barometer", "sensor": "baro-1"}
+{"kind": "declaration", "type": "thermometer", "sensor": "temp-1"}
+{"kind": "declaration", "type": "thermometer", "sensor": "temp-1"}
+{"kind": "reading", "timestamp": "yesterday", "sensor": "temp-1", "value": 72.4}
+{"kind": "reading", "timestamp": "2007-04-05T22:00", "sensor": "temp-2", "value": 72.4}
+{"kind": "reading", "timestamp": "2007-04-05T22:00", "sensor": "temp-1", "value": "72,4"}
+{"kind": "reading", "timestamp": "2007-04-05T22:00", "sensor": "temp-1", "value": 72.4, "value2": 45.0}
+{"kind": "reading", "timestamp": "2007-04-05T22:00", "sensor": "temp-1"}
+{"kind": "reading", "timestamp": "2007-04-05T22:01", "sensor": "temp-1", "value": 72.5}
+`)
+
+	ref, sensors, diagnostics, err := ReadJSONLog(in, nil)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 70.0, ref.GetRefTemperature())
+	assert.Equal(t, 1, len(sensors))
+	assert.Equal(t, []float64{72.5}, sensors[0].GetValues())
+
+	lines := make([]int, len(diagnostics))
+	for i, diagnostic := range diagnostics {
+		lines[i] = diagnostic.Line
+	}
+	assert.Equal(t, []int{1, 3, 4, 5, 6, 7, 8, 10, 11, 12, 13, 14, 15}, lines)
+	assert.Equal(t, "Missing field humidity", diagnostics[0].Message)
+	assert.Equal(t, "Missing field kind", diagnostics[4].Message)
+	assert.Equal(t, "Unknown record kind calibration", diagnostics[5].Message)
+	assert.Equal(t, "Sensor temp-1 expects 1 value(s), got 2", diagnostics[11].Message)
+	assert.Equal(t, "Missing field value", diagnostics[12].Message)
+}
+
+func TestReadJSONLog_NoReference(t *testing.T) {
+	in := strings.NewReader(`{"kind": "declaration", "type": "thermometer", "sensor": "temp-1"}`)
+
+	_, _, _, err := ReadJSONLog(in, nil)
+
+	assert.NotNil(t, err)
+	assert.Equal(t, "No reference record found in the log", err.Error())
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"strings"
+)
+
+/**
+ * Rating vocabulary
+ *   Grading only computes a Rating, the wording used to render it depends on who
+ *   reads the output. Each output profile maps ratings to labels, ratings missing
+ *   from a profile are rendered with their internal name. Labels are translated
+ *   in the current locale when the message catalog knows them.
+ */
+type RatingLabels map[Rating]string
+
+// Output profiles
+const SpecProfile = "spec"
+const InternalProfile = "internal"
+const CustomerProfile = "customer"
+
+const DefaultProfile = SpecProfile
+
+var outputProfiles = map[string]RatingLabels{
+	// Wording of the expected output in the specification
+	SpecProfile: {
+		RatingAccepted: "OK",
+		RatingRejected: "discard",
+	},
+	// Internal rating names
+	InternalProfile: {},
+	// Wording printed on the packaging
+	CustomerProfile: {
+		RatingUltraPrecise: "Ultra Precise",
+		RatingVeryPrecise:  "Very Precise",
+		RatingPrecise:      "Precise",
+		RatingAccepted:     "Certified",
+		RatingRejected:     "Not certified",
+	},
+}
+
+func GetRatingLabels(profile string) (RatingLabels, error) {
+	labels, found := outputProfiles[profile]
+	if !found {
+		return nil, errors.New(Translate("Unknown output profile %s", profile))
+	}
+
+	return labels, nil
+}
+
+func (rl RatingLabels) Label(r Rating) string {
+	if label, found := rl[r]; found {
+		return Translate(label)
+	}
+
+	return Translate(r.String())
+}
+
+/**
+ * Reading a custom vocabulary, one rating per line: <rating> = <label>
+ * Blank lines and lines starting with # are ignored
+ */
+func ReadRatingLabels(r io.Reader) (RatingLabels, error) {
+	labels := make(RatingLabels)
+
+	scan := bufio.NewScanner(r)
+	lineNumber := 0
+	for scan.Scan() {
+		lineNumber++
+
+		line := strings.TrimSpace(scan.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		ratingLabel := strings.SplitN(line, "=", 2)
+		if len(ratingLabel) != 2 {
+			return nil, errors.New(Translate("Error while parsing the rating labels at line %d: missing =", lineNumber))
+		}
+
+		rating, err := ParseRating(strings.TrimSpace(ratingLabel[0]))
+		if err != nil {
+			return nil, errors.New(Translate("Error while parsing the rating labels at line %d: %s", lineNumber, err.Error()))
+		}
+		labels[rating] = strings.TrimSpace(ratingLabel[1])
+	}
+
+	if err := scan.Err(); err != nil {
+		return nil, err
+	}
+
+	return labels, nil
+}
+
+func LoadRatingLabels(path string) (RatingLabels, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ReadRatingLabels(file)
+}
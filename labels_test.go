@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetRatingLabels_SpecProfile(t *testing.T) {
+	labels, err := GetRatingLabels(SpecProfile)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "OK", labels.Label(RatingAccepted))
+	assert.Equal(t, "discard", labels.Label(RatingRejected))
+	// Ratings missing from the profile are rendered with their internal name
+	assert.Equal(t, "ultra precise", labels.Label(RatingUltraPrecise))
+}
+
+func TestGetRatingLabels_InternalProfile(t *testing.T) {
+	labels, err := GetRatingLabels(InternalProfile)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "accepted", labels.Label(RatingAccepted))
+	assert.Equal(t, "rejected", labels.Label(RatingRejected))
+}
+
+func TestGetRatingLabels_UnknownProfile(t *testing.T) {
+	_, err := GetRatingLabels("potato")
+
+	assert.NotNil(t, err)
+	assert.Equal(t, "Unknown output profile potato", err.Error())
+}
+
+func TestReadRatingLabels_HappyPath(t *testing.T) {
+	var in bytes.Buffer
+	in.WriteString("# customer wording\n\nultra precise = Premium\naccepted=Pass\n")
+
+	labels, err := ReadRatingLabels(&in)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "Premium", labels.Label(RatingUltraPrecise))
+	assert.Equal(t, "Pass", labels.Label(RatingAccepted))
+	assert.Equal(t, "rejected", labels.Label(RatingRejected))
+}
+
+func TestReadRatingLabels_UnknownRating(t *testing.T) {
+	var in bytes.Buffer
+	in.WriteString("potato = Spud\n")
+
+	_, err := ReadRatingLabels(&in)
+
+	assert.NotNil(t, err)
+	assert.Equal(t, "Error while parsing the rating labels at line 1: Unknown rating potato", err.Error())
+}
+
+func TestReadRatingLabels_MissingSeparator(t *testing.T) {
+	var in bytes.Buffer
+	in.WriteString("accepted OK\n")
+
+	_, err := ReadRatingLabels(&in)
+
+	assert.NotNil(t, err)
+	assert.Equal(t, "Error while parsing the rating labels at line 1: missing =", err.Error())
+}
+
+func TestPrintResults_SpecWording(t *testing.T) {
+	var out bytes.Buffer
+
+	sensors := []SensorInterface{
+		&Sensor{sensorName: "temp-1", sensorRating: RatingPrecise},
+		&Sensor{sensorName: "temp-2", sensorRating: RatingUltraPrecise},
+		&Sensor{sensorName: "hum-1", sensorRating: RatingAccepted},
+		&Sensor{sensorName: "hum-2", sensorRating: RatingRejected},
+	}
+	labels, _ := GetRatingLabels(SpecProfile)
+
+	PrintResults(&out, sensors, labels)
+
+	assert.Equal(t, "temp-1: precise\ntemp-2: ultra precise\nhum-1: OK\nhum-2: discard\n", out.String())
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+/**
+ * Line sources
+ *   Older rigs write their log as lines on a TCP socket or a serial port, so it can
+ *   be graded without an intermediate file. The log ends with a Ctrl+] line, like a
+ *   typed log, when the rig was silent for the idle timeout, or when a TCP rig closes
+ *   the connection after sending whole lines. A lost connection is opened again, the
+ *   retries being shared by the whole log, and the lines already read are kept.
+ *   Serial ports are read as device files: their speed is set beforehand, e.g. with
+ *   "stty -F /dev/ttyUSB0 9600 raw".
+ */
+const TCPSourcePrefix = "tcp://"
+
+const DefaultSourceTimeout = 30 * time.Second
+const DefaultSourceRetries = 5
+
+// Delay before opening a lost connection again, doubled after each failed attempt
+const SourceRetryDelay = time.Second
+
+var ErrSourceTimeout = errors.New("line source timeout")
+
+type LineSourceInterface interface {
+	// Next line of the source, without its line break. io.EOF when the source ended, ErrSourceTimeout when it was idle
+	ReadLine() (string, error)
+	Close() error
+}
+
+// Connections and device files both have read deadlines
+type sourceStream interface {
+	io.ReadCloser
+	SetReadDeadline(t time.Time) error
+}
+
+type LineSource struct {
+	name    string
+	open    func() (sourceStream, error)
+	timeout time.Duration
+	retries int
+	// A TCP rig closing the connection before sending a whole line is lost, a device file ending is done
+	reconnectOnEOF bool
+
+	stream  sourceStream
+	reader  *bufio.Reader
+	partial string
+	// Lines returned and connections attempted since the source was created
+	nbrLines    int
+	nbrAttempts int
+}
+
+/**
+ * Line source reading from a rig listening on a TCP port (host:port)
+ */
+func NewTCPLineSource(address string, timeout time.Duration, retries int) *LineSource {
+	return &LineSource{
+		name: address,
+		open: func() (sourceStream, error) {
+			return net.DialTimeout("tcp", address, timeout)
+		},
+		timeout:        timeout,
+		retries:        retries,
+		reconnectOnEOF: true,
+	}
+}
+
+/**
+ * Line source reading from a device file, e.g. a serial port
+ */
+func NewDeviceLineSource(path string, timeout time.Duration, retries int) *LineSource {
+	return &LineSource{
+		name: path,
+		open: func() (sourceStream, error) {
+			return os.Open(path)
+		},
+		timeout: timeout,
+		retries: retries,
+	}
+}
+
+/**
+ * Opening the line source an input refers to: tcp://host:port or a character
+ * device. Returns nil for anything else, read as a file
+ */
+func OpenLineSource(input string, timeout time.Duration, retries int) LineSourceInterface {
+	if strings.HasPrefix(input, TCPSourcePrefix) {
+		return NewTCPLineSource(strings.TrimPrefix(input, TCPSourcePrefix), timeout, retries)
+	}
+
+	if info, err := os.Stat(input); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+		return NewDeviceLineSource(input, timeout, retries)
+	}
+
+	return nil
+}
+
+func (s *LineSource) ReadLine() (string, error) {
+	for {
+		if s.stream == nil {
+			if err := s.connect(); err != nil {
+				return "", err
+			}
+		}
+
+		if s.timeout > 0 {
+			// Regular files have no deadline, they never wait for lines anyway
+			if err := s.stream.SetReadDeadline(time.Now().Add(s.timeout)); err != nil && !errors.Is(err, os.ErrNoDeadline) {
+				return "", err
+			}
+		}
+
+		line, err := s.reader.ReadString('\n')
+		s.partial += line
+		if err == nil {
+			line, s.partial = strings.TrimRight(s.partial, "\r\n"), ""
+			s.nbrLines++
+			return line, nil
+		}
+
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			return "", ErrSourceTimeout
+		}
+		// A rig closing the connection after a whole line sent its log, in the middle of one it's lost
+		if err == io.EOF && (!s.reconnectOnEOF || (s.nbrLines > 0 && s.partial == "")) {
+			return "", io.EOF
+		}
+
+		// The rig starts the line over once reconnected
+		logger.Info(Translate("Connection to %s lost (%s), reconnecting", s.name, err))
+		s.stream.Close()
+		s.stream, s.partial = nil, ""
+	}
+}
+
+func (s *LineSource) Close() error {
+	if s.stream == nil {
+		return nil
+	}
+
+	err := s.stream.Close()
+	s.stream = nil
+	return err
+}
+
+/**
+ * Opening the stream, retrying with an increasing delay. The retries are shared by
+ * all the connections of the source, so a rig which keeps dropping it is given up on
+ */
+func (s *LineSource) connect() error {
+	err := errors.New(Translate("Connection to %s lost too many times", s.name))
+	for s.nbrAttempts <= s.retries {
+		if s.nbrAttempts > 0 {
+			time.Sleep(SourceRetryDelay << uint(s.nbrAttempts-1))
+		}
+		s.nbrAttempts++
+
+		var stream sourceStream
+		if stream, err = s.open(); err == nil {
+			s.stream, s.reader = stream, bufio.NewReader(stream)
+			return nil
+		}
+		logger.Debug(Translate("Can't open %s: %s", s.name, err))
+	}
+
+	return err
+}
+
+/**
+ * Reading a log from a line source, until its end line, the end of the source, an
+ * idle timeout or an error once lines were read
+ */
+func ReadSourceLog(source LineSourceInterface) ([]string, error) {
+	var lines []string
+	for {
+		line, err := source.ReadLine()
+		if err == io.EOF || (err == ErrSourceTimeout && len(lines) > 0) {
+			return lines, nil
+		}
+		if err == ErrSourceTimeout {
+			return nil, errors.New(Translate("No line received before the timeout"))
+		}
+		if err != nil && len(lines) > 0 {
+			// What was read is graded rather than lost
+			logger.Info(Translate("Can't read more lines (%s), grading the %d lines read", err, len(lines)))
+			return lines, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if line == "\x1D" {
+			return lines, nil
+		}
+		lines = append(lines, line)
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Rig serving each connection with the next content, the connection being closed after
+// it unless it ends with a line break
+func startTestRig(t *testing.T, contents ...string) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+
+	done := make(chan struct{})
+	t.Cleanup(func() {
+		close(done)
+		listener.Close()
+	})
+
+	go func() {
+		for _, content := range contents {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Write([]byte(content))
+			if !strings.HasSuffix(content, "\n") {
+				conn.Close()
+				continue
+			}
+
+			// Staying connected, silent
+			defer conn.Close()
+		}
+		<-done
+	}()
+
+	return listener.Addr().String()
+}
+
+func TestLineSource_TCPEndLine(t *testing.T) {
+	address := startTestRig(t, cliLog+"\x1D\n")
+	source := NewTCPLineSource(address, time.Second, 0)
+	defer source.Close()
+
+	lines, err := ReadSourceLog(source)
+
+	assert.Nil(t, err)
+	assert.Equal(t, strings.Split(strings.TrimSuffix(cliLog, "\n"), "\n"), lines)
+}
+
+func TestLineSource_TCPReconnect(t *testing.T) {
+	// The connection is lost in the middle of a line, the rig starts it over
+	address := startTestRig(t, "reference 70.0 45.0\r\nthermometer temp-1\r\n2007-04-05T22:00 te", "2007-04-05T22:00 temp-1 70.1\r\n")
+	source := NewTCPLineSource(address, 100*time.Millisecond, 1)
+	defer source.Close()
+
+	// The log ends once the rig is silent
+	lines, err := ReadSourceLog(source)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"reference 70.0 45.0", "thermometer temp-1", "2007-04-05T22:00 temp-1 70.1"}, lines)
+}
+
+func TestLineSource_TCPClosed(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err == nil {
+			conn.Write([]byte(cliLog))
+			conn.Close()
+		}
+	}()
+
+	// The rig closing the connection after its last line ends the log, without waiting
+	source := NewTCPLineSource(listener.Addr().String(), 10*time.Second, 3)
+	defer source.Close()
+	start := time.Now()
+	lines, err := ReadSourceLog(source)
+
+	assert.Nil(t, err)
+	assert.Len(t, lines, 7)
+	assert.Less(t, int64(time.Since(start)), int64(time.Second))
+}
+
+func TestLineSource_TCPRetriesExhausted(t *testing.T) {
+	// The retries are shared by the whole log, the third connection isn't attempted
+	address := startTestRig(t, "reference 70.0 45.0\nthermometer te", "thermometer temp-1\n2007-04-05T22:00 te", "2007-04-05T22:00 temp-1 70.1\n")
+	source := NewTCPLineSource(address, time.Second, 1)
+	defer source.Close()
+
+	// The lines read before the rig was given up on are still graded
+	lines, err := ReadSourceLog(source)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"reference 70.0 45.0", "thermometer temp-1"}, lines)
+}
+
+func TestLineSource_TCPErrors(t *testing.T) {
+	address := startTestRig(t, "\n")
+	source := NewTCPLineSource(address, 50*time.Millisecond, 0)
+
+	// A blank line isn't a line of the log yet, the rig is still silent
+	line, err := source.ReadLine()
+	assert.Nil(t, err)
+	assert.Equal(t, "", line)
+	_, err = source.ReadLine()
+	assert.Equal(t, ErrSourceTimeout, err)
+	source.Close()
+
+	listener, _ := net.Listen("tcp", "127.0.0.1:0")
+	listener.Close()
+	_, err = ReadSourceLog(NewTCPLineSource(listener.Addr().String(), 50*time.Millisecond, 0))
+	assert.NotNil(t, err)
+}
+
+func TestLineSource_Device(t *testing.T) {
+	// Regular files stand in for serial ports, they end instead of timing out
+	path := writeLog(t, "ttyUSB0", cliLog)
+	source := NewDeviceLineSource(path, time.Second, 0)
+	defer source.Close()
+
+	lines, err := ReadSourceLog(source)
+	assert.Nil(t, err)
+	assert.Len(t, lines, 7)
+
+	_, err = source.ReadLine()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestOpenLineSource(t *testing.T) {
+	assert.IsType(t, &LineSource{}, OpenLineSource("tcp://rig-3:4000", time.Second, 0))
+	assert.IsType(t, &LineSource{}, OpenLineSource("/dev/null", time.Second, 0))
+	assert.Nil(t, OpenLineSource(writeLog(t, "run.log", cliLog), time.Second, 0))
+}
+
+func TestRunCLI_AnalyzeFromRig(t *testing.T) {
+	address := startTestRig(t, cliLog)
+	var stdout, stderr bytes.Buffer
+
+	code := RunCLI([]string{"analyze", "-input", TCPSourcePrefix + address, "-timeout", "200ms"}, nil, &stdout, &stderr)
+
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "temp-1: ultra precise\nhum-1: OK\n", stdout.String())
+}
@@ -0,0 +1,365 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+/**
+ * Live readings
+ *   Rigs stream the lines of their log to the server as readings are taken, each
+ *   rig to its own channel. The lines are read as they arrive by the reader of the
+ *   input format, which keeps the statistics of each sensor up to date, so live
+ *   statistics and ratings are the ones a batch grading of the log gives without
+ *   going through the lines again. Only the first lines are kept, until the format
+ *   of the log is detected.
+ *   Dashboards subscribe to the status of a rig, or of one of its sensors, and get
+ *   it pushed as the readings arrive.
+ */
+
+// Status pushed at most this often while a rig streams its readings
+const LivePublishInterval = time.Second
+
+// Longest line accepted from a rig, like the lines of a posted log
+const LiveMaxLineLength = bufio.MaxScanTokenSize
+
+// Reporting what was read from a rig, in the given input format
+type LiveReporter func(ref ReferenceInterface, sensors []SensorInterface, diagnostics []Diagnostic, format string) ReportDocument
+
+// Provisional results of a rig: the report of the lines received so far, or why they can't be graded yet
+type LiveStatus struct {
+	Lines    int    `json:"lines"`
+	Readings int    `json:"readings"`
+	Error    string `json:"error,omitempty"`
+	*ReportDocument
+}
+
+type LiveHub struct {
+	report  LiveReporter
+	mapping CSVColumnMapping
+	table   CalibrationTable
+
+	// Only guards the list of rigs, each rig has its own lock
+	lock sync.Mutex
+	rigs map[string]*liveRig
+}
+
+type liveRig struct {
+	lock sync.Mutex
+
+	// Format given when the rig was created, and the format the lines are read in once it's known
+	format     string
+	readFormat string
+	reader     logReader
+	// Lines kept until the format is detected, or why it can't be
+	head      []string
+	formatErr error
+
+	lines int
+	// Start of a line whose end comes with the next request
+	partial string
+
+	// Report of the lines, made again when lines were received since
+	document  *ReportDocument
+	gradeErr  error
+	graded    bool
+	published time.Time
+
+	subscribers map[*LiveSubscription]bool
+}
+
+// Statuses are pushed to the channel, a subscriber slower than the rig only gets the latest one
+type LiveSubscription struct {
+	Updates <-chan []byte
+
+	sensor  string
+	updates chan []byte
+}
+
+func NewLiveHub(report LiveReporter) *LiveHub {
+	return &LiveHub{
+		report:  report,
+		mapping: DefaultCSVColumnMapping,
+		rigs:    make(map[string]*liveRig),
+	}
+}
+
+func (h *LiveHub) SetCSVColumnMapping(mapping CSVColumnMapping) {
+	h.mapping = mapping
+}
+
+func (h *LiveHub) SetCalibrationTable(table CalibrationTable) {
+	h.table = table
+}
+
+/**
+ * Reading the lines streamed by a rig until the stream ends, the rig being created
+ * by its first stream. A line cut at the end of the stream is completed by the next
+ * one. Subscribers get the status every publish interval, and once the stream ended
+ */
+func (h *LiveHub) Ingest(name string, format string, stream io.Reader) error {
+	h.lock.Lock()
+	rig, found := h.rigs[name]
+	if !found {
+		rig = &liveRig{format: format, subscribers: make(map[*LiveSubscription]bool)}
+		if err := h.start(rig); err != nil {
+			h.lock.Unlock()
+			return err
+		}
+		h.rigs[name] = rig
+	}
+	h.lock.Unlock()
+
+	reader := bufio.NewReader(stream)
+	for {
+		// Long lines are read in several chunks, so they can be refused once they're too long
+		chunk, err := reader.ReadSlice('\n')
+		if err == bufio.ErrBufferFull {
+			err = nil
+		}
+
+		rig.lock.Lock()
+		lineErr := rig.read(string(chunk), h.mapping, h.table)
+		if err != nil || lineErr != nil || time.Since(rig.published) >= LivePublishInterval {
+			h.publish(rig)
+		}
+		rig.lock.Unlock()
+
+		if lineErr != nil {
+			return lineErr
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+/**
+ * Forgetting the readings of a rig, to start a new run.
+ * Returns false when there's no such rig
+ */
+func (h *LiveHub) Reset(name string) bool {
+	rig, found := h.getRig(name)
+	if !found {
+		return false
+	}
+
+	rig.lock.Lock()
+	defer rig.lock.Unlock()
+
+	rig.lines, rig.partial = 0, ""
+	// The format was checked when the rig was created
+	h.start(rig)
+	h.publish(rig)
+
+	return true
+}
+
+/**
+ * Status of a rig, or of one of its sensors (all of them when it's empty).
+ * Returns false when there's no such rig
+ */
+func (h *LiveHub) GetStatus(name string, sensor string) (LiveStatus, bool) {
+	rig, found := h.getRig(name)
+	if !found {
+		return LiveStatus{}, false
+	}
+
+	rig.lock.Lock()
+	defer rig.lock.Unlock()
+
+	return h.getStatus(rig, sensor), true
+}
+
+/**
+ * Subscribing to the status of a rig, or of one of its sensors. The current status
+ * is pushed right away. Returns false when there's no such rig
+ */
+func (h *LiveHub) Subscribe(name string, sensor string) (*LiveSubscription, bool) {
+	rig, found := h.getRig(name)
+	if !found {
+		return nil, false
+	}
+
+	rig.lock.Lock()
+	defer rig.lock.Unlock()
+
+	updates := make(chan []byte, 1)
+	subscription := &LiveSubscription{Updates: updates, sensor: sensor, updates: updates}
+
+	rig.subscribers[subscription] = true
+	h.push(rig, subscription)
+
+	return subscription, true
+}
+
+func (h *LiveHub) Unsubscribe(name string, subscription *LiveSubscription) {
+	rig, found := h.getRig(name)
+	if !found {
+		return
+	}
+
+	rig.lock.Lock()
+	defer rig.lock.Unlock()
+
+	delete(rig.subscribers, subscription)
+}
+
+func (h *LiveHub) getRig(name string) (*liveRig, bool) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	rig, found := h.rigs[name]
+	return rig, found
+}
+
+// Starting to read the log of a rig, its format is detected from the first lines unless it's given
+func (h *LiveHub) start(rig *liveRig) error {
+	rig.reader, rig.readFormat, rig.head, rig.formatErr, rig.graded = nil, "", nil, nil, false
+	if rig.format == AutoFormat {
+		return nil
+	}
+
+	reader, err := newLogReader(rig.format, h.mapping, h.table)
+	rig.reader, rig.readFormat = reader, rig.format
+
+	return err
+}
+
+func (r *liveRig) read(chunk string, mapping CSVColumnMapping, table CalibrationTable) error {
+	r.partial += chunk
+	if !strings.HasSuffix(r.partial, "\n") {
+		if len(r.partial) > LiveMaxLineLength {
+			r.partial = ""
+			return errors.New(Translate("Line %d is longer than %d bytes", r.lines+1, LiveMaxLineLength))
+		}
+		return nil
+	}
+
+	line := strings.TrimRight(r.partial, "\r\n")
+	r.lines++
+	r.partial, r.graded = "", false
+
+	if r.reader != nil {
+		r.reader.readLine(line)
+		return nil
+	}
+	if r.formatErr != nil {
+		return nil
+	}
+
+	r.head = append(r.head, line)
+	if len(getDetectionSample(r.head)) < DetectionSampleSize {
+		return nil
+	}
+
+	// Like for a complete log, the format is detected from its first lines: they're read again in this format
+	r.reader, r.readFormat, r.formatErr = r.readHead(mapping, table)
+	r.head = nil
+
+	return nil
+}
+
+func (r *liveRig) readHead(mapping CSVColumnMapping, table CalibrationTable) (logReader, string, error) {
+	format, err := DetectFormat(r.head, mapping)
+	if err != nil {
+		return nil, "", err
+	}
+
+	reader, err := newLogReader(format, mapping, table)
+	if err != nil {
+		return nil, "", err
+	}
+	for _, line := range r.head {
+		reader.readLine(line)
+	}
+
+	return reader, format, nil
+}
+
+// Reference, sensors and diagnostics of the lines received so far, with the format they were read in
+func (r *liveRig) results(mapping CSVColumnMapping, table CalibrationTable) (ReferenceInterface, []SensorInterface, []Diagnostic, string, error) {
+	if r.formatErr != nil {
+		return nil, nil, nil, "", r.formatErr
+	}
+
+	reader, format := r.reader, r.readFormat
+	if reader == nil {
+		// The format is detected from the lines received so far, until there are enough of them
+		var err error
+		if reader, format, err = r.readHead(mapping, table); err != nil {
+			return nil, nil, nil, "", err
+		}
+	}
+
+	ref, sensors, diagnostics, err := reader.results()
+	return ref, sensors, diagnostics, format, err
+}
+
+func (h *LiveHub) getStatus(rig *liveRig, sensor string) LiveStatus {
+	if !rig.graded {
+		rig.document, rig.gradeErr = nil, nil
+		if rig.lines > 0 {
+			ref, sensors, diagnostics, format, err := rig.results(h.mapping, h.table)
+			if err != nil {
+				rig.gradeErr = err
+			} else {
+				ComputeResults(sensors, ref)
+				document := h.report(ref, sensors, diagnostics, format)
+				rig.document = &document
+			}
+		}
+		rig.graded = true
+	}
+
+	status := LiveStatus{Lines: rig.lines}
+	if rig.gradeErr != nil {
+		status.Error = rig.gradeErr.Error()
+	}
+	if rig.document == nil {
+		return status
+	}
+
+	document := *rig.document
+	document.Sensors = make([]ReportSensor, 0, len(rig.document.Sensors))
+	for _, result := range rig.document.Sensors {
+		if sensor != "" && result.Name != sensor {
+			continue
+		}
+		document.Sensors = append(document.Sensors, result)
+		status.Readings += result.Readings
+	}
+	status.ReportDocument = &document
+
+	return status
+}
+
+func (h *LiveHub) publish(rig *liveRig) {
+	rig.published = time.Now()
+	for subscription := range rig.subscribers {
+		h.push(rig, subscription)
+	}
+}
+
+func (h *LiveHub) push(rig *liveRig, subscription *LiveSubscription) {
+	update, err := json.Marshal(h.getStatus(rig, subscription.sensor))
+	if err != nil {
+		logger.Info(err.Error())
+		return
+	}
+
+	// Replacing the status the subscriber didn't get yet
+	select {
+	case <-subscription.updates:
+	default:
+	}
+	subscription.updates <- update
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+/**
+ * Localization
+ *   Messages are written in English in the code and used as keys of the message
+ *   catalogs (gettext-like), so a message missing from a catalog is still readable.
+ *   The locale is selected once at startup (flag or locale environment) and used
+ *   for every message afterwards.
+ */
+type Locale struct {
+	name             string
+	decimalSeparator string
+	messages         map[string]string
+}
+
+const DefaultLocale = "en"
+
+var locales = map[string]*Locale{
+	"en": {
+		name:             "en",
+		decimalSeparator: ".",
+		messages:         map[string]string{},
+	},
+	"es": {
+		name:             "es",
+		decimalSeparator: ",",
+		messages:         spanishMessages,
+	},
+	"de": {
+		name:             "de",
+		decimalSeparator: ",",
+		messages:         germanMessages,
+	},
+}
+
+var currentLocale = locales[DefaultLocale]
+
+func SetLocale(name string) error {
+	locale, found := locales[name]
+	if !found {
+		return errors.New("Unsupported locale " + name)
+	}
+
+	currentLocale = locale
+	return nil
+}
+
+func GetLocale() string {
+	return currentLocale.name
+}
+
+/**
+ * Finding the locale from the environment, following the POSIX precedence
+ * (LC_ALL, LC_MESSAGES then LANG). Values look like de_DE.UTF-8, unsupported
+ * languages fall back to the default locale
+ */
+func DetectLocale() string {
+	for _, variable := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		value := os.Getenv(variable)
+		if value == "" {
+			continue
+		}
+
+		fields := strings.FieldsFunc(value, func(r rune) bool {
+			return r == '_' || r == '.' || r == '@' || r == '-'
+		})
+		if len(fields) == 0 {
+			continue
+		}
+
+		language := strings.ToLower(fields[0])
+		if _, found := locales[language]; found {
+			return language
+		}
+
+		return DefaultLocale
+	}
+
+	return DefaultLocale
+}
+
+/**
+ * Translating a message, args are formatted as with fmt.Sprintf
+ */
+func Translate(msg string, args ...interface{}) string {
+	if translated, found := currentLocale.messages[msg]; found {
+		msg = translated
+	}
+
+	if len(args) == 0 {
+		return msg
+	}
+
+	return fmt.Sprintf(msg, args...)
+}
+
+/**
+ * Formatting a number with the decimal separator of the current locale
+ */
+func FormatNumber(value float64, decimals int) string {
+	formatted := strconv.FormatFloat(value, 'f', decimals, 64)
+
+	return strings.Replace(formatted, ".", currentLocale.decimalSeparator, 1)
+}
+
+/**
+ * Formatting a standard deviation, which isn't known until there are 2 readings
+ */
+func FormatStandardDeviation(readings int, value float64, decimals int) string {
+	if readings < 2 {
+		return Translate("n/a")
+	}
+
+	return FormatNumber(value, decimals)
+}
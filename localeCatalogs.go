@@ -0,0 +1,561 @@
+package main
+
+/**
+ * Message catalogs, keyed by the English message
+ */
+
+var spanishMessages = map[string]string{
+	// Report
+	"Enter log content:":                           "Introduzca el contenido del registro:",
+	"Ref. Temperature is %s | Ref. Humidity is %s": "Temperatura de ref. %s | Humedad de ref. %s",
+	"No content found for sensors, exiting now":    "No se encontró contenido para los sensores, saliendo",
+	"Found %d sensors":                             "Se encontraron %d sensores",
+	"%s: offset %s, gain %s":                       "%s: desplazamiento %s, ganancia %s",
+	"  setpoint %d: %s -> %s (residual %s)":        "  punto de consigna %d: %s -> %s (residuo %s)",
+	"Run started at %s":                            "Ejecución iniciada el %s",
+	"Language: %s | Output profile: %s":            "Idioma: %s | Perfil de salida: %s",
+	"Input number format: %s":                      "Formato numérico de entrada: %s",
+
+	// Ratings
+	"ultra precise": "ultra preciso",
+	"very precise":  "muy preciso",
+	"precise":       "preciso",
+	"accepted":      "aceptado",
+	"rejected":      "rechazado",
+	"error":         "error",
+	"unknown":       "desconocido",
+	"OK":            "OK",
+	"discard":       "descartar",
+	"Ultra Precise": "Ultra Preciso",
+	"Very Precise":  "Muy Preciso",
+	"Precise":       "Preciso",
+	"Certified":     "Certificado",
+	"Not certified": "No certificado",
+
+	// Diagnostics
+	"No reference found in the log, can't extract setpoints":          "No se encontró ninguna referencia en el registro, no se pueden extraer los puntos de consigna",
+	"Error while parsing the header: not enough elements":             "Error al analizar el encabezado: faltan elementos",
+	"First line doesn't seem to contain the reference, stopping now":  "La primera línea no parece contener la referencia, deteniendo ahora",
+	"Error while parsing the header: %s is not a key=value pair":      "Error al analizar el encabezado: %s no es un par clave=valor",
+	"Error while parsing the header: unknown reference quantity %s":   "Error al analizar el encabezado: magnitud de referencia desconocida %s",
+	"Data doesn't have the expected number of elements for device %s": "Los datos no tienen el número esperado de elementos para el dispositivo %s",
+	"Data is not for the right sensor":                                "Los datos no corresponden al sensor correcto",
+	"Invalid sensor type for type %s. Checking next sensor":           "Tipo de sensor no válido: %s. Pasando al siguiente sensor",
+	"Missing pressure reference for device %s. Checking next sensor":  "Falta la referencia de presión para el dispositivo %s. Pasando al siguiente sensor",
+	"Missing CO2 reference for device %s. Checking next sensor":       "Falta la referencia de CO2 para el dispositivo %s. Pasando al siguiente sensor",
+	"Error while parsing the recorded measure for devide %s :%s":      "Error al analizar la medida registrada del dispositivo %s: %s",
+	"Unterminated quote in line: %s":                                  "Comilla sin cerrar en la línea: %s",
+
+	// Validation
+	"line %d: %s": "línea %d: %s",
+	"The log must start with a reference line":                                  "El registro debe comenzar con una línea de referencia",
+	"A reference line needs a temperature and a humidity":                       "Una línea de referencia necesita una temperatura y una humedad",
+	"Invalid reference value %s":                                                "Valor de referencia no válido %s",
+	"Invalid reference quantity %s, expected <key>=<value> with a key among %s": "Magnitud de referencia no válida %s, se esperaba <clave>=<valor> con una clave entre %s",
+	"The uncertainty of the reference can't be negative":                        "La incertidumbre de la referencia no puede ser negativa",
+	"A metadata line needs at least one key=value pair":                         "Una línea de metadatos necesita al menos un par clave=valor",
+	"Invalid metadata %s, expected key=value":                                   "Metadato no válido %s, se esperaba clave=valor",
+	"Unknown sensor type %s":                                                    "Tipo de sensor desconocido %s",
+	"Sensor %s is declared twice":                                               "El sensor %s está declarado dos veces",
+	"A reading needs a timestamp, a sensor name and a value":                    "Una lectura necesita una marca de tiempo, un nombre de sensor y un valor",
+	"Invalid timestamp %s":                                                      "Marca de tiempo no válida %s",
+	"Reading for sensor %s before any sensor declaration":                       "Lectura del sensor %s antes de cualquier declaración de sensor",
+	"Reading for sensor %s in the block of sensor %s":                           "Lectura del sensor %s en el bloque del sensor %s",
+	"Sensor %s expects %d value(s), got %d":                                     "El sensor %s espera %d valor(es), se recibieron %d",
+	"Invalid value %s for sensor %s":                                            "Valor no válido %s para el sensor %s",
+	"Unrecognized line":                                                         "Línea no reconocida",
+	"%s: %d violation(s) found":                                                 "%s: se encontraron %d infracción(es)",
+	"%s is valid":                                                               "%s es válido",
+
+	// Input formats
+	"Unknown input format %s":                                               "Formato de entrada desconocido %s",
+	"Invalid CSV column mapping %s, expected field=header":                  "Asignación de columnas CSV no válida %s, se esperaba campo=encabezado",
+	"Unknown CSV column %s":                                                 "Columna CSV desconocida %s",
+	"The CSV log is empty":                                                  "El registro CSV está vacío",
+	"Missing column %s in the CSV header":                                   "Falta la columna %s en el encabezado CSV",
+	"Expected %d columns, got %d":                                           "Se esperaban %d columnas, hay %d",
+	"Missing %s column for combo sensor %s":                                 "Falta la columna %s para el sensor combinado %s",
+	"Sensor %s is logged as %s and %s":                                      "El sensor %s está registrado como %s y %s",
+	"The CSV log needs reference rows for the temperature and the humidity": "El registro CSV necesita filas de referencia para la temperatura y la humedad",
+
+	// JSON Lines
+	"Invalid record: %s":                                        "Registro no válido: %s",
+	"Missing field %s":                                          "Falta el campo %s",
+	"Unknown record kind %s":                                    "Tipo de registro desconocido %s",
+	"No reference record found in the log":                      "No se encontró ningún registro de referencia en el registro",
+	"unexpected data after the JSON object":                     "datos inesperados después del objeto JSON",
+	"Only one reference record is allowed, this one is ignored": "Solo se permite un registro de referencia, este se ignora",
+
+	// Format detection
+	"The log is empty, can't detect its format":                     "El registro está vacío, no se puede detectar su formato",
+	"Can't detect the format of the log, set it with -format (%s)":  "No se puede detectar el formato del registro, indíquelo con -format (%s)",
+	"The log could be in several formats (%s), set it with -format": "El registro podría estar en varios formatos (%s), indíquelo con -format",
+	"Input format: %s": "Formato de entrada: %s",
+
+	// Command line
+	"Usage: sensor <command> [flags]": "Uso: sensor <comando> [opciones]",
+	"Usage: %s":                       "Uso: %s",
+	"Commands:":                       "Comandos:",
+	"Flags:":                          "Opciones:",
+	"Unknown command %s":              "Comando desconocido %s",
+	"Without a command, the log is analyzed. Run \"sensor help <command>\" for the flags of a command.": "Sin comando, se analiza el registro. Ejecute \"sensor help <comando>\" para ver las opciones de un comando.",
+	"Grade the sensors of a log":                                                                      "Calificar los sensores de un registro",
+	"Check a log against the log format without grading it":                                           "Comprobar el formato de un registro sin calificarlo",
+	"Grade the sensors of a log and detail the statistics of each of them":                            "Calificar los sensores de un registro y detallar las estadísticas de cada uno",
+	"Print the version of the tool":                                                                   "Mostrar la versión de la herramienta",
+	"Print the help of the tool or of a command":                                                      "Mostrar la ayuda de la herramienta o de un comando",
+	"Calibrations can only be fitted on logs in the %s format":                                        "Las calibraciones solo se pueden ajustar con registros en formato %s",
+	"-calibration can't be used with -export-calibration, corrections are fitted on the raw readings": "-calibration no se puede usar con -export-calibration, las correcciones se ajustan sobre las lecturas sin corregir",
+	"  readings: %d | average: %s | standard deviation: %s":                                           "  lecturas: %d | media: %s | desviación estándar: %s",
+	"n/a":                               "n/d",
+	"  expanded uncertainty: %s (k=%s)": "  incertidumbre expandida: %s (k=%s)",
+
+	// Logging
+	"Unknown verbosity %s, expected quiet, info or debug": "Nivel de detalle desconocido %s, se esperaba quiet, info o debug",
+
+	// Interactive mode
+	"Paste or open logs and review their results on an interactive screen":      "Pegar o abrir registros y revisar sus resultados en una pantalla interactiva",
+	"Loaded %d sensors (%s format)":                                             "%d sensores cargados (formato %s)",
+	"Paste the log, then end it with a line containing only \"%s\" (or Ctrl+])": "Pegue el registro y termínelo con una línea que contenga solo \"%s\" (o Ctrl+])",
+	"No sensor %s in the log":                                                   "No hay ningún sensor %s en el registro",
+	"Load a log before exporting its report":                                    "Cargue un registro antes de exportar su informe",
+	"Report exported to %s":                                                     "Informe exportado a %s",
+	"No log loaded":                                                             "Ningún registro cargado",
+	"Commands: paste | open <file> | quit":                                      "Comandos: paste | open <archivo> | quit",
+	"Commands: back | <number> or show <sensor> | export <file> | paste | open <file> | quit": "Comandos: back | <número> o show <sensor> | export <archivo> | paste | open <archivo> | quit",
+	"Commands: <number> or show <sensor> | export <file> | paste | open <file> | quit":        "Comandos: <número> o show <sensor> | export <archivo> | paste | open <archivo> | quit",
+	"#\tSensor\tType\tReadings\tAverage\tStd. deviation\tRating":                              "#\tSensor\tTipo\tLecturas\tMedia\tDesv. estándar\tCalificación",
+
+	// Watch mode
+	"Follow a log as it's written and print provisional ratings":  "Seguir un registro mientras se escribe y mostrar calificaciones provisionales",
+	"Provisional ratings at %s (%d readings)":                     "Calificaciones provisionales a las %s (%d lecturas)",
+	"  waiting for the reference line":                            "  esperando la línea de referencia",
+	"  %s: no readings yet":                                       "  %s: aún sin lecturas",
+	"  %s: %s | %d readings | average %s | standard deviation %s": "  %s: %s | %d lecturas | media %s | desviación estándar %s",
+	" | can't reach %s, pull the unit":                            " | no puede alcanzar %s, retire la unidad",
+	"%s was truncated, reading it again":                          "%s fue truncado, se vuelve a leer",
+
+	// Spool directory
+	"Unknown report format %s, expected %s": "Formato de informe desconocido %s, se esperaba %s",
+	"Can't grade %s: %s":                    "No se puede calificar %s: %s",
+	"%s graded and archived":                "%s calificado y archivado",
+
+	// HTTP API
+	"Listening on %s":                              "Escuchando en %s",
+	"The log is larger than %d bytes":              "El log ocupa más de %d bytes",
+	"The decompressed log is larger than %d bytes": "El registro descomprimido supera los %d bytes",
+	"Method not allowed, expected %s":              "Método no permitido, se esperaba %s",
+
+	// Job queue
+	"Can't load the job %s: %s":          "No se puede cargar el trabajo %s: %s",
+	"Job %s %s":                          "Trabajo %s %s",
+	"Jobs aren't enabled on this server": "Los trabajos no están habilitados en este servidor",
+	"No job %s":                          "No existe el trabajo %s",
+
+	// Live readings
+	"No such page %s":           "No existe la página %s",
+	"Streaming isn't supported": "La transmisión no está soportada",
+
+	// MQTT mode
+	"Unexpected MQTT packet %d, expected CONNACK":                            "Paquete MQTT inesperado %d, se esperaba CONNACK",
+	"The MQTT broker refused the connection (return code %d)":                "El broker MQTT rechazó la conexión (código de retorno %d)",
+	"The MQTT broker refused the subscription to %s":                         "El broker MQTT rechazó la suscripción a %s",
+	"Malformed MQTT packet":                                                  "Paquete MQTT mal formado",
+	"Invalid reference on %s: %s":                                            "Referencia no válida en %s: %s",
+	"Message on unexpected topic %s":                                         "Mensaje en un tema inesperado %s",
+	"Invalid reading on %s: %s":                                              "Lectura no válida en %s: %s",
+	"The topic or the payload must give the type and the name of the sensor": "El tema o el contenido deben indicar el tipo y el nombre del sensor",
+	"Can't grade the session %s: %s":                                         "No se puede calificar la sesión %s: %s",
+	"Session %s ended with %d sensors":                                       "Sesión %s terminada con %d sensores",
+	"Session %s":                                                             "Sesión %s",
+	"Expected [timestamp] <value> [<value2>]":                                "Se esperaba [marca de tiempo] <valor> [<valor2>]",
+
+	// Line sources
+	"Connection to %s lost (%s), reconnecting": "Conexión con %s perdida (%s), reconectando",
+	"Can't open %s: %s":                        "No se puede abrir %s: %s",
+	"No line received before the timeout":      "No se recibió ninguna línea antes del tiempo límite",
+
+	// Line sources
+	"Connection to %s lost too many times":                  "Conexión a %s perdida demasiadas veces",
+	"Can't read more lines (%s), grading the %d lines read": "No se pueden leer más líneas (%s), se evalúan las %d líneas leídas",
+
+	// Reported diagnostics
+	"Diagnostics:": "Diagnósticos:",
+	"Loaded %d sensors (%s format), %d records discarded": "%d sensores cargados (formato %s), %d registros descartados",
+
+	// Combo channels
+	"  temperature channel:": "  canal de temperatura:",
+	"  humidity channel:":    "  canal de humedad:",
+
+	// Dew point
+	"  dew point: %s | reference: %s | deviation: %s": "  punto de rocío: %s | referencia: %s | desviación: %s",
+
+	// Live rigs
+	"No rig %s, post its readings first": "No hay ningún banco %s, envíe primero sus lecturas",
+	"Line %d is longer than %d bytes":    "La línea %d supera los %d bytes",
+
+	// Run metadata
+	"write the results as a JSON report, along with the run metadata": "escribir los resultados como un informe JSON, junto con los metadatos de la ejecución",
+
+	// Watch targets
+	"The target of the thermometers must be a tier (%s, %s or %s)": "El objetivo de los termómetros debe ser un nivel (%s, %s o %s)",
+
+	// Job retention
+	"how long finished jobs are kept, 0 keeps them forever": "cuánto tiempo se conservan los trabajos terminados, 0 los conserva para siempre",
+
+	// MQTT connections
+	"An MQTT password can't be given without a user name":                                                           "No se puede dar una contraseña MQTT sin un nombre de usuario",
+	"Connection to the MQTT broker lost (%s), connecting again in %s":                                               "Conexión con el broker MQTT perdida (%s), reconectando en %s",
+	"read the password given to the broker from this file, else from the SENSOR_MQTT_PASSWORD environment variable": "leer la contraseña dada al broker de este archivo, si no de la variable de entorno SENSOR_MQTT_PASSWORD",
+
+	// Spool directory
+	"%s can't be moved out of the inbox, it won't be graded again until it changes": "%s no se puede sacar de la bandeja de entrada, no se calificará de nuevo hasta que cambie",
+
+	// Calibration
+	"Error while fitting the calibration: measured and reference points don't match": "Error al ajustar la calibración: los puntos medidos y de referencia no coinciden",
+	"Can't calibrate sensor %s: no reference for sensor type %s":                     "No se puede calibrar el sensor %s: no hay referencia para el tipo de sensor %s",
+	"Can't calibrate sensor %s: no readings found":                                   "No se puede calibrar el sensor %s: no se encontraron lecturas",
+	"Error while parsing the calibration table at line %d: %s":                       "Error al analizar la tabla de calibración en la línea %d: %s",
+	"not enough elements":                             "faltan elementos",
+	"too many elements for an offset/gain correction": "demasiados elementos para una corrección de desplazamiento/ganancia",
+
+	// Interactive mode
+	"%d more messages logged": "%d mensajes más registrados",
+
+	// Command summaries and flags
+	"Grade the logs dropped in a directory and archive them with their reports":                                    "Calificar los registros depositados en un directorio y archivarlos con sus informes",
+	"Grade the logs posted to an HTTP API":                                                                         "Calificar los registros enviados a una API HTTP",
+	"Grade the test sessions published to an MQTT broker (host:port)":                                              "Calificar las sesiones de prueba publicadas en un broker MQTT (host:puerto)",
+	"address the HTTP server listens on":                                                                           "dirección en la que escucha el servidor HTTP",
+	"apply the corrections of this calibration table to the readings":                                              "aplicar a las lecturas las correcciones de esta tabla de calibración",
+	"client identifier given to the broker":                                                                        "identificador de cliente dado al broker",
+	"decimal separator of the numbers in plain text payloads":                                                      "separador decimal de los números en los mensajes de texto plano",
+	"decimal separator of the numbers in the log":                                                                  "separador decimal de los números del registro",
+	"decimal separator of the numbers in the logs":                                                                 "separador decimal de los números de los registros",
+	"enable the job queue, saving the logs posted to /jobs and their results in this directory":                    "activar la cola de trabajos, guardando en este directorio los registros enviados a /jobs y sus resultados",
+	"fit offset/gain corrections on a multi-setpoint log and export them to this file":                             "ajustar correcciones de desplazamiento/ganancia en un registro de varios puntos de consigna y exportarlas a este archivo",
+	"format of the log (auto, log, csv, jsonl), detected from its first lines by default":                          "formato del registro (auto, log, csv, jsonl), detectado por defecto a partir de sus primeras líneas",
+	"format of the logs (auto, log, csv, jsonl), detected from their first lines by default":                       "formato de los registros (auto, log, csv, jsonl), detectado por defecto a partir de sus primeras líneas",
+	"header names of the CSV columns, e.g. timestamp=time,sensor=name":                                             "nombres de encabezado de las columnas CSV, p. ej. timestamp=time,sensor=name",
+	"how long the broker may stay silent before the connection is considered lost":                                 "cuánto tiempo puede permanecer en silencio el broker antes de considerar perdida la conexión",
+	"how long the size of a log must stay the same before it's graded, unless a <log>.done marker is dropped":      "cuánto tiempo debe mantenerse el tamaño de un registro antes de calificarlo, salvo que se deposite un marcador <registro>.done",
+	"how often the inbox is checked for new logs":                                                                  "cada cuánto se revisa la bandeja de entrada en busca de nuevos registros",
+	"how often the provisional ratings are printed":                                                                "cada cuánto se muestran las calificaciones provisionales",
+	"language of the error messages (en, es, de), defaults to the locale environment":                              "idioma de los mensajes de error (en, es, de), por defecto el del entorno",
+	"language of the report (en, es, de), defaults to the locale environment":                                      "idioma del informe (en, es, de), por defecto el del entorno",
+	"language of the reports (en, es, de), defaults to the locale environment":                                     "idioma de los informes (en, es, de), por defecto el del entorno",
+	"move the graded logs and their reports to this directory, defaults to <inbox>/archive":                        "mover los registros calificados y sus informes a este directorio, por defecto <bandeja>/archive",
+	"move the logs which can't be graded to this directory, defaults to <inbox>/failed":                            "mover los registros que no se pueden calificar a este directorio, por defecto <bandeja>/failed",
+	"number of jobs graded at the same time":                                                                       "número de trabajos calificados al mismo tiempo",
+	"number of readings per sensor at the end of the run, to flag the thermometers which can't reach their target": "número de lecturas por sensor al final de la ejecución, para señalar los termómetros que no pueden alcanzar su objetivo",
+	"output profile used to word the ratings (spec, internal, customer)":                                           "perfil de salida usado para redactar las calificaciones (spec, internal, customer)",
+	"rating thermometers are expected to reach":                                                                    "calificación que deben alcanzar los termómetros",
+	"read the log from this file (- for stdin), it can be compressed with gzip or bzip2":                           "leer el registro de este archivo (- para la entrada estándar), puede estar comprimido con gzip o bzip2",
+	"reports written for each session (text, report, json), separated by commas":                                   "informes escritos para cada sesión (text, report, json), separados por comas",
+	"reports written next to each log (text, report, json), separated by commas":                                   "informes escritos junto a cada registro (text, report, json), separados por comas",
+	"thousands separator of the numbers in plain text payloads, if any":                                            "separador de miles de los números en los mensajes de texto plano, si lo hay",
+	"thousands separator of the numbers in the log, if any":                                                        "separador de miles de los números del registro, si lo hay",
+	"thousands separator of the numbers in the logs, if any":                                                       "separador de miles de los números de los registros, si lo hay",
+	"topic ending the test session, which is then graded":                                                          "tema que termina la sesión de prueba, que entonces se califica",
+	"topic filters to subscribe to, separated by commas, derived from the topics by default":                       "filtros de temas a los que suscribirse, separados por comas, derivados por defecto de los temas",
+	"topic of the readings, {type} and {sensor} standing for the type and name of the sensor":                      "tema de las lecturas, donde {type} y {sensor} representan el tipo y el nombre del sensor",
+	"topic of the reference":                "tema de la referencia",
+	"user name given to the broker, if any": "nombre de usuario dado al broker, si lo hay",
+	"what is logged to stderr: quiet, info (discarded lines) or debug (also the address listened on)":                               "lo que se registra en stderr: quiet, info (líneas descartadas) o debug (también la dirección de escucha)",
+	"what is logged to stderr: quiet, info (discarded lines) or debug (also the run metadata and what was read from the log)":       "lo que se registra en stderr: quiet, info (líneas descartadas) o debug (también los metadatos de la ejecución y lo leído del registro)",
+	"what is logged to stderr: quiet, info (discarded lines) or debug":                                                              "lo que se registra en stderr: quiet, info (líneas descartadas) o debug",
+	"what is logged to stderr: quiet, info (discarded records) or debug (also the run metadata)":                                    "lo que se registra en stderr: quiet, info (registros descartados) o debug (también los metadatos de la ejecución)",
+	"what is logged to stderr: quiet, info (logs which can't be graded, discarded lines) or debug (also the logs graded)":           "lo que se registra en stderr: quiet, info (registros que no se pueden calificar, líneas descartadas) o debug (también los registros calificados)",
+	"what is logged under the status line: quiet, info (discarded lines) or debug":                                                  "lo que se registra bajo la línea de estado: quiet, info (líneas descartadas) o debug",
+	"when the input is a rig (tcp://host:port or a serial port), how long it may stay silent before the log is considered complete": "cuando la entrada es un banco (tcp://host:puerto o un puerto serie), cuánto tiempo puede permanecer en silencio antes de considerar completo el registro",
+	"when the input is a rig, how many times in all a lost connection is opened again":                                              "cuando la entrada es un banco, cuántas veces en total se vuelve a abrir una conexión perdida",
+	"word the ratings with the labels of this file instead of the output profile":                                                   "redactar las calificaciones con las etiquetas de este archivo en lugar del perfil de salida",
+	"write the results to this file": "escribir los resultados en este archivo",
+
+	// Ratings, labels and number formats
+	"Unknown rating %s":         "Calificación desconocida %s",
+	"Unknown output profile %s": "Perfil de salida desconocido %s",
+	"Error while parsing the rating labels at line %d: missing =": "Error al analizar las etiquetas de calificación en la línea %d: falta =",
+	"Error while parsing the rating labels at line %d: %s":        "Error al analizar las etiquetas de calificación en la línea %d: %s",
+	"Invalid decimal separator \"%s\"":                            "Separador decimal no válido \"%s\"",
+	"Invalid thousands separator \"%s\"":                          "Separador de miles no válido \"%s\"",
+	"Decimal and thousands separators must be different":          "Los separadores decimal y de miles deben ser distintos",
+}
+
+var germanMessages = map[string]string{
+	// Report
+	"Enter log content:":                           "Protokollinhalt eingeben:",
+	"Ref. Temperature is %s | Ref. Humidity is %s": "Ref.-Temperatur ist %s | Ref.-Feuchte ist %s",
+	"No content found for sensors, exiting now":    "Kein Inhalt für Sensoren gefunden, Programm wird beendet",
+	"Found %d sensors":                             "%d Sensoren gefunden",
+	"%s: offset %s, gain %s":                       "%s: Offset %s, Verstärkung %s",
+	"  setpoint %d: %s -> %s (residual %s)":        "  Sollwert %d: %s -> %s (Residuum %s)",
+	"Run started at %s":                            "Lauf gestartet am %s",
+	"Language: %s | Output profile: %s":            "Sprache: %s | Ausgabeprofil: %s",
+	"Input number format: %s":                      "Eingabe-Zahlenformat: %s",
+
+	// Ratings
+	"ultra precise": "ultrapräzise",
+	"very precise":  "sehr präzise",
+	"precise":       "präzise",
+	"accepted":      "akzeptiert",
+	"rejected":      "abgelehnt",
+	"error":         "Fehler",
+	"unknown":       "unbekannt",
+	"OK":            "OK",
+	"discard":       "aussortieren",
+	"Ultra Precise": "Ultrapräzise",
+	"Very Precise":  "Sehr präzise",
+	"Precise":       "Präzise",
+	"Certified":     "Zertifiziert",
+	"Not certified": "Nicht zertifiziert",
+
+	// Diagnostics
+	"No reference found in the log, can't extract setpoints":          "Keine Referenz im Protokoll gefunden, Sollwerte können nicht extrahiert werden",
+	"Error while parsing the header: not enough elements":             "Fehler beim Lesen der Kopfzeile: nicht genügend Elemente",
+	"First line doesn't seem to contain the reference, stopping now":  "Die erste Zeile scheint keine Referenz zu enthalten, Abbruch",
+	"Error while parsing the header: %s is not a key=value pair":      "Fehler beim Lesen der Kopfzeile: %s ist kein Schlüssel=Wert-Paar",
+	"Error while parsing the header: unknown reference quantity %s":   "Fehler beim Lesen der Kopfzeile: unbekannte Referenzgröße %s",
+	"Data doesn't have the expected number of elements for device %s": "Die Daten haben nicht die erwartete Anzahl von Elementen für Gerät %s",
+	"Data is not for the right sensor":                                "Die Daten gehören nicht zum richtigen Sensor",
+	"Invalid sensor type for type %s. Checking next sensor":           "Ungültiger Sensortyp %s. Weiter mit dem nächsten Sensor",
+	"Missing pressure reference for device %s. Checking next sensor":  "Fehlende Druckreferenz für Gerät %s. Weiter mit dem nächsten Sensor",
+	"Missing CO2 reference for device %s. Checking next sensor":       "Fehlende CO2-Referenz für Gerät %s. Weiter mit dem nächsten Sensor",
+	"Error while parsing the recorded measure for devide %s :%s":      "Fehler beim Lesen des Messwerts für Gerät %s: %s",
+	"Unterminated quote in line: %s":                                  "Nicht geschlossenes Anführungszeichen in Zeile: %s",
+
+	// Validation
+	"line %d: %s": "Zeile %d: %s",
+	"The log must start with a reference line":                                  "Das Protokoll muss mit einer Referenzzeile beginnen",
+	"A reference line needs a temperature and a humidity":                       "Eine Referenzzeile benötigt eine Temperatur und eine Feuchte",
+	"Invalid reference value %s":                                                "Ungültiger Referenzwert %s",
+	"Invalid reference quantity %s, expected <key>=<value> with a key among %s": "Ungültige Referenzgröße %s, erwartet <Schlüssel>=<Wert> mit einem Schlüssel aus %s",
+	"The uncertainty of the reference can't be negative":                        "Die Unsicherheit der Referenz darf nicht negativ sein",
+	"A metadata line needs at least one key=value pair":                         "Eine Metadatenzeile benötigt mindestens ein Schlüssel=Wert-Paar",
+	"Invalid metadata %s, expected key=value":                                   "Ungültige Metadaten %s, erwartet Schlüssel=Wert",
+	"Unknown sensor type %s":                                                    "Unbekannter Sensortyp %s",
+	"Sensor %s is declared twice":                                               "Sensor %s ist doppelt deklariert",
+	"A reading needs a timestamp, a sensor name and a value":                    "Ein Messwert benötigt einen Zeitstempel, einen Sensornamen und einen Wert",
+	"Invalid timestamp %s":                                                      "Ungültiger Zeitstempel %s",
+	"Reading for sensor %s before any sensor declaration":                       "Messwert für Sensor %s vor jeder Sensordeklaration",
+	"Reading for sensor %s in the block of sensor %s":                           "Messwert für Sensor %s im Block von Sensor %s",
+	"Sensor %s expects %d value(s), got %d":                                     "Sensor %s erwartet %d Wert(e), erhalten %d",
+	"Invalid value %s for sensor %s":                                            "Ungültiger Wert %s für Sensor %s",
+	"Unrecognized line":                                                         "Unbekannte Zeile",
+	"%s: %d violation(s) found":                                                 "%s: %d Verstöße gefunden",
+	"%s is valid":                                                               "%s ist gültig",
+
+	// Input formats
+	"Unknown input format %s":                                               "Unbekanntes Eingabeformat %s",
+	"Invalid CSV column mapping %s, expected field=header":                  "Ungültige CSV-Spaltenzuordnung %s, erwartet Feld=Spaltenname",
+	"Unknown CSV column %s":                                                 "Unbekannte CSV-Spalte %s",
+	"The CSV log is empty":                                                  "Das CSV-Protokoll ist leer",
+	"Missing column %s in the CSV header":                                   "Spalte %s fehlt in der CSV-Kopfzeile",
+	"Expected %d columns, got %d":                                           "%d Spalten erwartet, %d erhalten",
+	"Missing %s column for combo sensor %s":                                 "Spalte %s fehlt für Kombisensor %s",
+	"Sensor %s is logged as %s and %s":                                      "Sensor %s ist als %s und %s protokolliert",
+	"The CSV log needs reference rows for the temperature and the humidity": "Das CSV-Protokoll benötigt Referenzzeilen für Temperatur und Feuchte",
+
+	// JSON Lines
+	"Invalid record: %s":                                        "Ungültiger Datensatz: %s",
+	"Missing field %s":                                          "Feld %s fehlt",
+	"Unknown record kind %s":                                    "Unbekannte Datensatzart %s",
+	"No reference record found in the log":                      "Kein Referenzdatensatz im Protokoll gefunden",
+	"unexpected data after the JSON object":                     "unerwartete Daten nach dem JSON-Objekt",
+	"Only one reference record is allowed, this one is ignored": "Nur ein Referenzdatensatz ist erlaubt, dieser wird ignoriert",
+
+	// Format detection
+	"The log is empty, can't detect its format":                     "Das Protokoll ist leer, sein Format kann nicht erkannt werden",
+	"Can't detect the format of the log, set it with -format (%s)":  "Das Format des Protokolls kann nicht erkannt werden, bitte mit -format angeben (%s)",
+	"The log could be in several formats (%s), set it with -format": "Das Protokoll könnte in mehreren Formaten vorliegen (%s), bitte mit -format angeben",
+	"Input format: %s": "Eingabeformat: %s",
+
+	// Command line
+	"Usage: sensor <command> [flags]": "Verwendung: sensor <Befehl> [Optionen]",
+	"Usage: %s":                       "Verwendung: %s",
+	"Commands:":                       "Befehle:",
+	"Flags:":                          "Optionen:",
+	"Unknown command %s":              "Unbekannter Befehl %s",
+	"Without a command, the log is analyzed. Run \"sensor help <command>\" for the flags of a command.": "Ohne Befehl wird das Protokoll analysiert. \"sensor help <Befehl>\" zeigt die Optionen eines Befehls.",
+	"Grade the sensors of a log":                                                                      "Die Sensoren eines Protokolls bewerten",
+	"Check a log against the log format without grading it":                                           "Das Format eines Protokolls prüfen, ohne es zu bewerten",
+	"Grade the sensors of a log and detail the statistics of each of them":                            "Die Sensoren eines Protokolls bewerten und ihre Statistiken aufschlüsseln",
+	"Print the version of the tool":                                                                   "Die Version des Werkzeugs ausgeben",
+	"Print the help of the tool or of a command":                                                      "Die Hilfe des Werkzeugs oder eines Befehls ausgeben",
+	"Calibrations can only be fitted on logs in the %s format":                                        "Kalibrierungen können nur mit Protokollen im Format %s angepasst werden",
+	"-calibration can't be used with -export-calibration, corrections are fitted on the raw readings": "-calibration kann nicht mit -export-calibration verwendet werden, die Korrekturen werden an den unkorrigierten Messwerten angepasst",
+	"  readings: %d | average: %s | standard deviation: %s":                                           "  Messwerte: %d | Mittelwert: %s | Standardabweichung: %s",
+	"n/a":                               "k. A.",
+	"  expanded uncertainty: %s (k=%s)": "  erweiterte Messunsicherheit: %s (k=%s)",
+
+	// Logging
+	"Unknown verbosity %s, expected quiet, info or debug": "Unbekannte Ausführlichkeit %s, erwartet quiet, info oder debug",
+
+	// Interactive mode
+	"Paste or open logs and review their results on an interactive screen":      "Protokolle einfügen oder öffnen und ihre Ergebnisse auf einem interaktiven Bildschirm prüfen",
+	"Loaded %d sensors (%s format)":                                             "%d Sensoren geladen (Format %s)",
+	"Paste the log, then end it with a line containing only \"%s\" (or Ctrl+])": "Protokoll einfügen und mit einer Zeile abschließen, die nur \"%s\" enthält (oder Strg+])",
+	"No sensor %s in the log":                                                   "Kein Sensor %s im Protokoll",
+	"Load a log before exporting its report":                                    "Vor dem Exportieren des Berichts ein Protokoll laden",
+	"Report exported to %s":                                                     "Bericht nach %s exportiert",
+	"No log loaded":                                                             "Kein Protokoll geladen",
+	"Commands: paste | open <file> | quit":                                      "Befehle: paste | open <Datei> | quit",
+	"Commands: back | <number> or show <sensor> | export <file> | paste | open <file> | quit": "Befehle: back | <Nummer> oder show <Sensor> | export <Datei> | paste | open <Datei> | quit",
+	"Commands: <number> or show <sensor> | export <file> | paste | open <file> | quit":        "Befehle: <Nummer> oder show <Sensor> | export <Datei> | paste | open <Datei> | quit",
+	"#\tSensor\tType\tReadings\tAverage\tStd. deviation\tRating":                              "#\tSensor\tTyp\tMesswerte\tMittelwert\tStandardabw.\tBewertung",
+
+	// Watch mode
+	"Follow a log as it's written and print provisional ratings":  "Ein Protokoll während des Schreibens verfolgen und vorläufige Bewertungen ausgeben",
+	"Provisional ratings at %s (%d readings)":                     "Vorläufige Bewertungen um %s (%d Messwerte)",
+	"  waiting for the reference line":                            "  warte auf die Referenzzeile",
+	"  %s: no readings yet":                                       "  %s: noch keine Messwerte",
+	"  %s: %s | %d readings | average %s | standard deviation %s": "  %s: %s | %d Messwerte | Mittelwert %s | Standardabweichung %s",
+	" | can't reach %s, pull the unit":                            " | kann %s nicht erreichen, Gerät entnehmen",
+	"%s was truncated, reading it again":                          "%s wurde gekürzt, wird erneut gelesen",
+
+	// Spool directory
+	"Unknown report format %s, expected %s": "Unbekanntes Berichtsformat %s, erwartet %s",
+	"Can't grade %s: %s":                    "%s kann nicht bewertet werden: %s",
+	"%s graded and archived":                "%s bewertet und archiviert",
+
+	// HTTP API
+	"Listening on %s":                              "Lausche auf %s",
+	"The log is larger than %d bytes":              "Das Log ist größer als %d Bytes",
+	"The decompressed log is larger than %d bytes": "Das entpackte Protokoll ist größer als %d Bytes",
+	"Method not allowed, expected %s":              "Methode nicht erlaubt, erwartet %s",
+
+	// Job queue
+	"Can't load the job %s: %s":          "Auftrag %s kann nicht geladen werden: %s",
+	"Job %s %s":                          "Auftrag %s %s",
+	"Jobs aren't enabled on this server": "Aufträge sind auf diesem Server nicht aktiviert",
+	"No job %s":                          "Kein Auftrag %s",
+
+	// Live readings
+	"No such page %s":           "Keine Seite %s",
+	"Streaming isn't supported": "Streaming wird nicht unterstützt",
+
+	// MQTT mode
+	"Unexpected MQTT packet %d, expected CONNACK":                            "Unerwartetes MQTT-Paket %d, erwartet CONNACK",
+	"The MQTT broker refused the connection (return code %d)":                "Der MQTT-Broker hat die Verbindung abgelehnt (Rückgabecode %d)",
+	"The MQTT broker refused the subscription to %s":                         "Der MQTT-Broker hat das Abonnement von %s abgelehnt",
+	"Malformed MQTT packet":                                                  "Fehlerhaftes MQTT-Paket",
+	"Invalid reference on %s: %s":                                            "Ungültige Referenz auf %s: %s",
+	"Message on unexpected topic %s":                                         "Nachricht auf unerwartetem Topic %s",
+	"Invalid reading on %s: %s":                                              "Ungültiger Messwert auf %s: %s",
+	"The topic or the payload must give the type and the name of the sensor": "Topic oder Nutzdaten müssen Typ und Namen des Sensors angeben",
+	"Can't grade the session %s: %s":                                         "Sitzung %s kann nicht bewertet werden: %s",
+	"Session %s ended with %d sensors":                                       "Sitzung %s mit %d Sensoren beendet",
+	"Session %s":                                                             "Sitzung %s",
+	"Expected [timestamp] <value> [<value2>]":                                "Erwartet [Zeitstempel] <Wert> [<Wert2>]",
+
+	// Line sources
+	"Connection to %s lost (%s), reconnecting": "Verbindung zu %s verloren (%s), neuer Verbindungsversuch",
+	"Can't open %s: %s":                        "%s kann nicht geöffnet werden: %s",
+	"No line received before the timeout":      "Vor Ablauf der Zeit wurde keine Zeile empfangen",
+
+	// Line sources
+	"Connection to %s lost too many times":                  "Verbindung zu %s zu oft verloren",
+	"Can't read more lines (%s), grading the %d lines read": "Keine weiteren Zeilen lesbar (%s), die %d gelesenen Zeilen werden bewertet",
+
+	// Reported diagnostics
+	"Diagnostics:": "Diagnosen:",
+	"Loaded %d sensors (%s format), %d records discarded": "%d Sensoren geladen (Format %s), %d Datensätze verworfen",
+
+	// Combo channels
+	"  temperature channel:": "  Temperaturkanal:",
+	"  humidity channel:":    "  Feuchtekanal:",
+
+	// Dew point
+	"  dew point: %s | reference: %s | deviation: %s": "  Taupunkt: %s | Referenz: %s | Abweichung: %s",
+
+	// Live rigs
+	"No rig %s, post its readings first": "Kein Prüfstand %s, zuerst seine Messwerte senden",
+	"Line %d is longer than %d bytes":    "Zeile %d ist länger als %d Bytes",
+
+	// Run metadata
+	"write the results as a JSON report, along with the run metadata": "die Ergebnisse als JSON-Bericht schreiben, zusammen mit den Metadaten des Laufs",
+
+	// Watch targets
+	"The target of the thermometers must be a tier (%s, %s or %s)": "Das Ziel der Thermometer muss eine Stufe sein (%s, %s oder %s)",
+
+	// Job retention
+	"how long finished jobs are kept, 0 keeps them forever": "wie lange abgeschlossene Aufträge aufbewahrt werden, 0 bewahrt sie für immer auf",
+
+	// MQTT connections
+	"An MQTT password can't be given without a user name":                                                           "Ein MQTT-Passwort kann nicht ohne Benutzernamen angegeben werden",
+	"Connection to the MQTT broker lost (%s), connecting again in %s":                                               "Verbindung zum MQTT-Broker verloren (%s), neuer Verbindungsversuch in %s",
+	"read the password given to the broker from this file, else from the SENSOR_MQTT_PASSWORD environment variable": "das dem Broker übergebene Passwort aus dieser Datei lesen, sonst aus der Umgebungsvariable SENSOR_MQTT_PASSWORD",
+
+	// Spool directory
+	"%s can't be moved out of the inbox, it won't be graded again until it changes": "%s kann nicht aus dem Eingangsordner verschoben werden, es wird erst nach einer Änderung erneut bewertet",
+
+	// Calibration
+	"Error while fitting the calibration: measured and reference points don't match": "Fehler beim Anpassen der Kalibrierung: Mess- und Referenzpunkte stimmen nicht überein",
+	"Can't calibrate sensor %s: no reference for sensor type %s":                     "Sensor %s kann nicht kalibriert werden: keine Referenz für den Sensortyp %s",
+	"Can't calibrate sensor %s: no readings found":                                   "Sensor %s kann nicht kalibriert werden: keine Messwerte gefunden",
+	"Error while parsing the calibration table at line %d: %s":                       "Fehler beim Lesen der Kalibriertabelle in Zeile %d: %s",
+	"not enough elements":                             "nicht genügend Elemente",
+	"too many elements for an offset/gain correction": "zu viele Elemente für eine Offset/Verstärkungs-Korrektur",
+
+	// Interactive mode
+	"%d more messages logged": "%d weitere Meldungen protokolliert",
+
+	// Command summaries and flags
+	"Grade the logs dropped in a directory and archive them with their reports":                                    "Die in einem Verzeichnis abgelegten Protokolle bewerten und mit ihren Berichten archivieren",
+	"Grade the logs posted to an HTTP API":                                                                         "Die an eine HTTP-API gesendeten Protokolle bewerten",
+	"Grade the test sessions published to an MQTT broker (host:port)":                                              "Die an einen MQTT-Broker (Host:Port) veröffentlichten Testsitzungen bewerten",
+	"address the HTTP server listens on":                                                                           "Adresse, auf der der HTTP-Server lauscht",
+	"apply the corrections of this calibration table to the readings":                                              "die Korrekturen dieser Kalibriertabelle auf die Messwerte anwenden",
+	"client identifier given to the broker":                                                                        "dem Broker übergebene Client-Kennung",
+	"decimal separator of the numbers in plain text payloads":                                                      "Dezimaltrennzeichen der Zahlen in Klartext-Nachrichten",
+	"decimal separator of the numbers in the log":                                                                  "Dezimaltrennzeichen der Zahlen im Protokoll",
+	"decimal separator of the numbers in the logs":                                                                 "Dezimaltrennzeichen der Zahlen in den Protokollen",
+	"enable the job queue, saving the logs posted to /jobs and their results in this directory":                    "die Auftragswarteschlange aktivieren und die an /jobs gesendeten Protokolle samt Ergebnissen in diesem Verzeichnis speichern",
+	"fit offset/gain corrections on a multi-setpoint log and export them to this file":                             "Offset/Verstärkungs-Korrekturen an einem Protokoll mit mehreren Sollwerten anpassen und in diese Datei exportieren",
+	"format of the log (auto, log, csv, jsonl), detected from its first lines by default":                          "Format des Protokolls (auto, log, csv, jsonl), standardmäßig anhand seiner ersten Zeilen erkannt",
+	"format of the logs (auto, log, csv, jsonl), detected from their first lines by default":                       "Format der Protokolle (auto, log, csv, jsonl), standardmäßig anhand ihrer ersten Zeilen erkannt",
+	"header names of the CSV columns, e.g. timestamp=time,sensor=name":                                             "Kopfzeilennamen der CSV-Spalten, z. B. timestamp=time,sensor=name",
+	"how long the broker may stay silent before the connection is considered lost":                                 "wie lange der Broker schweigen darf, bevor die Verbindung als verloren gilt",
+	"how long the size of a log must stay the same before it's graded, unless a <log>.done marker is dropped":      "wie lange die Größe eines Protokolls gleich bleiben muss, bevor es bewertet wird, sofern keine Markierung <Protokoll>.done abgelegt wird",
+	"how often the inbox is checked for new logs":                                                                  "wie oft der Eingangsordner auf neue Protokolle geprüft wird",
+	"how often the provisional ratings are printed":                                                                "wie oft die vorläufigen Bewertungen ausgegeben werden",
+	"language of the error messages (en, es, de), defaults to the locale environment":                              "Sprache der Fehlermeldungen (en, es, de), standardmäßig die der Umgebung",
+	"language of the report (en, es, de), defaults to the locale environment":                                      "Sprache des Berichts (en, es, de), standardmäßig die der Umgebung",
+	"language of the reports (en, es, de), defaults to the locale environment":                                     "Sprache der Berichte (en, es, de), standardmäßig die der Umgebung",
+	"move the graded logs and their reports to this directory, defaults to <inbox>/archive":                        "die bewerteten Protokolle und ihre Berichte in dieses Verzeichnis verschieben, standardmäßig <Eingang>/archive",
+	"move the logs which can't be graded to this directory, defaults to <inbox>/failed":                            "die nicht bewertbaren Protokolle in dieses Verzeichnis verschieben, standardmäßig <Eingang>/failed",
+	"number of jobs graded at the same time":                                                                       "Anzahl der gleichzeitig bewerteten Aufträge",
+	"number of readings per sensor at the end of the run, to flag the thermometers which can't reach their target": "Anzahl der Messwerte pro Sensor am Ende des Laufs, um die Thermometer zu markieren, die ihr Ziel nicht erreichen können",
+	"output profile used to word the ratings (spec, internal, customer)":                                           "Ausgabeprofil für die Formulierung der Bewertungen (spec, internal, customer)",
+	"rating thermometers are expected to reach":                                                                    "Bewertung, die Thermometer erreichen sollen",
+	"read the log from this file (- for stdin), it can be compressed with gzip or bzip2":                           "das Protokoll aus dieser Datei lesen (- für die Standardeingabe), es kann mit gzip oder bzip2 komprimiert sein",
+	"reports written for each session (text, report, json), separated by commas":                                   "für jede Sitzung geschriebene Berichte (text, report, json), durch Kommas getrennt",
+	"reports written next to each log (text, report, json), separated by commas":                                   "neben jedes Protokoll geschriebene Berichte (text, report, json), durch Kommas getrennt",
+	"thousands separator of the numbers in plain text payloads, if any":                                            "Tausendertrennzeichen der Zahlen in Klartext-Nachrichten, falls vorhanden",
+	"thousands separator of the numbers in the log, if any":                                                        "Tausendertrennzeichen der Zahlen im Protokoll, falls vorhanden",
+	"thousands separator of the numbers in the logs, if any":                                                       "Tausendertrennzeichen der Zahlen in den Protokollen, falls vorhanden",
+	"topic ending the test session, which is then graded":                                                          "Topic, das die Testsitzung beendet, die dann bewertet wird",
+	"topic filters to subscribe to, separated by commas, derived from the topics by default":                       "zu abonnierende Topic-Filter, durch Kommas getrennt, standardmäßig aus den Topics abgeleitet",
+	"topic of the readings, {type} and {sensor} standing for the type and name of the sensor":                      "Topic der Messwerte, {type} und {sensor} stehen für Typ und Name des Sensors",
+	"topic of the reference":                "Topic der Referenz",
+	"user name given to the broker, if any": "dem Broker übergebener Benutzername, falls vorhanden",
+	"what is logged to stderr: quiet, info (discarded lines) or debug (also the address listened on)":                               "was auf stderr protokolliert wird: quiet, info (verworfene Zeilen) oder debug (auch die Adresse, auf der gelauscht wird)",
+	"what is logged to stderr: quiet, info (discarded lines) or debug (also the run metadata and what was read from the log)":       "was auf stderr protokolliert wird: quiet, info (verworfene Zeilen) oder debug (auch die Metadaten des Laufs und was aus dem Protokoll gelesen wurde)",
+	"what is logged to stderr: quiet, info (discarded lines) or debug":                                                              "was auf stderr protokolliert wird: quiet, info (verworfene Zeilen) oder debug",
+	"what is logged to stderr: quiet, info (discarded records) or debug (also the run metadata)":                                    "was auf stderr protokolliert wird: quiet, info (verworfene Datensätze) oder debug (auch die Metadaten des Laufs)",
+	"what is logged to stderr: quiet, info (logs which can't be graded, discarded lines) or debug (also the logs graded)":           "was auf stderr protokolliert wird: quiet, info (nicht bewertbare Protokolle, verworfene Zeilen) oder debug (auch die bewerteten Protokolle)",
+	"what is logged under the status line: quiet, info (discarded lines) or debug":                                                  "was unter der Statuszeile protokolliert wird: quiet, info (verworfene Zeilen) oder debug",
+	"when the input is a rig (tcp://host:port or a serial port), how long it may stay silent before the log is considered complete": "wenn die Eingabe ein Prüfstand ist (tcp://host:port oder eine serielle Schnittstelle), wie lange er schweigen darf, bevor das Protokoll als vollständig gilt",
+	"when the input is a rig, how many times in all a lost connection is opened again":                                              "wenn die Eingabe ein Prüfstand ist, wie oft insgesamt eine verlorene Verbindung erneut geöffnet wird",
+	"word the ratings with the labels of this file instead of the output profile":                                                   "die Bewertungen mit den Bezeichnungen dieser Datei statt des Ausgabeprofils formulieren",
+	"write the results to this file": "die Ergebnisse in diese Datei schreiben",
+
+	// Ratings, labels and number formats
+	"Unknown rating %s":         "Unbekannte Bewertung %s",
+	"Unknown output profile %s": "Unbekanntes Ausgabeprofil %s",
+	"Error while parsing the rating labels at line %d: missing =": "Fehler beim Lesen der Bewertungsbezeichnungen in Zeile %d: = fehlt",
+	"Error while parsing the rating labels at line %d: %s":        "Fehler beim Lesen der Bewertungsbezeichnungen in Zeile %d: %s",
+	"Invalid decimal separator \"%s\"":                            "Ungültiges Dezimaltrennzeichen \"%s\"",
+	"Invalid thousands separator \"%s\"":                          "Ungültiges Tausendertrennzeichen \"%s\"",
+	"Decimal and thousands separators must be different":          "Dezimal- und Tausendertrennzeichen müssen verschieden sein",
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"flag"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetLocale_UnsupportedLocale(t *testing.T) {
+	err := SetLocale("fr")
+
+	assert.NotNil(t, err)
+	assert.Equal(t, "Unsupported locale fr", err.Error())
+	assert.Equal(t, DefaultLocale, GetLocale())
+}
+
+func TestTranslate_DefaultLocale(t *testing.T) {
+	assert.Equal(t, "Found 4 sensors", Translate("Found %d sensors", 4))
+	assert.Equal(t, "Enter log content:", Translate("Enter log content:"))
+}
+
+func TestTranslate_Spanish(t *testing.T) {
+	SetLocale("es")
+	defer SetLocale(DefaultLocale)
+
+	assert.Equal(t, "Se encontraron 4 sensores", Translate("Found %d sensors", 4))
+	// Messages missing from the catalog are left in English
+	assert.Equal(t, "Not in the catalog", Translate("Not in the catalog"))
+}
+
+func TestTranslate_SpanishSettings(t *testing.T) {
+	SetLocale("es")
+	defer SetLocale(DefaultLocale)
+
+	_, err := GetRatingLabels("potato")
+	assert.Equal(t, "Perfil de salida desconocido potato", err.Error())
+
+	_, err = ReadRatingLabels(strings.NewReader("potato = Potato\n"))
+	assert.Equal(t, "Error al analizar las etiquetas de calificación en la línea 1: Calificación desconocida potato", err.Error())
+
+	_, err = NewNumberFormat(",", ",")
+	assert.Equal(t, "Los separadores decimal y de miles deben ser distintos", err.Error())
+}
+
+func TestTranslate_GermanDiagnostics(t *testing.T) {
+	SetLocale("de")
+	defer SetLocale(DefaultLocale)
+
+	sensor := NewSensor("Potato", "Potato-sensor")
+	_, err := sensor.CalculateRating(NewRefTemperatureHumidity(70.0, 45.0))
+
+	assert.NotNil(t, err)
+	assert.Equal(t, "Ungültiger Sensortyp Potato. Weiter mit dem nächsten Sensor", err.Error())
+}
+
+func TestTranslate_RatingLabels(t *testing.T) {
+	SetLocale("es")
+	defer SetLocale(DefaultLocale)
+
+	labels, _ := GetRatingLabels(SpecProfile)
+
+	assert.Equal(t, "descartar", labels.Label(RatingRejected))
+	assert.Equal(t, "ultra preciso", labels.Label(RatingUltraPrecise))
+	// Stable names aren't localized
+	assert.Equal(t, "ultra precise", RatingUltraPrecise.String())
+}
+
+func TestFormatNumber(t *testing.T) {
+	assert.Equal(t, "70.50", FormatNumber(70.5, 2))
+
+	SetLocale("de")
+	defer SetLocale(DefaultLocale)
+
+	assert.Equal(t, "70,50", FormatNumber(70.5, 2))
+	assert.Equal(t, "-1,2346", FormatNumber(-1.23456, 4))
+}
+
+func TestDetectLocale(t *testing.T) {
+	t.Setenv("LC_ALL", "")
+	t.Setenv("LC_MESSAGES", "")
+	t.Setenv("LANG", "de_DE.UTF-8")
+
+	assert.Equal(t, "de", DetectLocale())
+
+	t.Setenv("LC_MESSAGES", "es_MX.UTF-8")
+	assert.Equal(t, "es", DetectLocale())
+
+	t.Setenv("LC_ALL", "C")
+	assert.Equal(t, DefaultLocale, DetectLocale())
+
+	t.Setenv("LC_ALL", "")
+	t.Setenv("LC_MESSAGES", "")
+	t.Setenv("LANG", "")
+	assert.Equal(t, DefaultLocale, DetectLocale())
+}
+
+func TestCatalogs_CommandLine(t *testing.T) {
+	// Summaries and help of the flags of each command
+	var messages []string
+	for _, command := range Commands {
+		messages = append(messages, command.Summary)
+		if command.Flags == nil {
+			continue
+		}
+		fs := flag.NewFlagSet(command.Name, flag.ContinueOnError)
+		command.Flags(fs, &CLIOptions{})
+		fs.VisitAll(func(f *flag.Flag) {
+			messages = append(messages, f.Usage)
+		})
+	}
+
+	// Messages translated in cli.go
+	file, err := parser.ParseFile(token.NewFileSet(), "cli.go", nil, 0)
+	assert.Nil(t, err)
+	ast.Inspect(file, func(node ast.Node) bool {
+		call, ok := node.(*ast.CallExpr)
+		if !ok || len(call.Args) == 0 {
+			return true
+		}
+		if name, ok := call.Fun.(*ast.Ident); !ok || name.Name != "Translate" {
+			return true
+		}
+		if literal, ok := call.Args[0].(*ast.BasicLit); ok && literal.Kind == token.STRING {
+			message, err := strconv.Unquote(literal.Value)
+			assert.Nil(t, err)
+			messages = append(messages, message)
+		}
+		return true
+	})
+
+	for _, name := range []string{"es", "de"} {
+		for _, message := range messages {
+			_, found := locales[name].messages[message]
+			assert.True(t, found, "%q missing from the %s catalog", message, name)
+		}
+	}
+}
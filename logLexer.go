@@ -0,0 +1,97 @@
+package main
+
+import (
+	"strings"
+	"time"
+)
+
+/**
+ * Lexer of the log format
+ *   Splits a line into tokens (see Tokenize) and classifies each of them, so the
+ *   parser can check lines against the grammar without re-reading raw text.
+ */
+type TokenKind int
+
+const (
+	TokenWord TokenKind = iota
+	TokenKeyword
+	TokenSensorType
+	TokenTimestamp
+	TokenNumber
+	TokenKeyValue
+)
+
+// Keywords starting a reference or a metadata line
+const ReferenceKeyword = "reference"
+const MetadataKeyword = "meta"
+
+// Accepted layouts for reading timestamps
+var TimestampLayouts = []string{
+	"2006-01-02T15:04",
+	"2006-01-02T15:04:05",
+	time.RFC3339,
+}
+
+type Token struct {
+	Kind TokenKind
+	Text string
+}
+
+func LexLine(line string) ([]Token, error) {
+	words, err := Tokenize(line)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := make([]Token, len(words))
+	for i, word := range words {
+		tokens[i] = Token{Kind: classifyToken(word), Text: word}
+	}
+
+	return tokens, nil
+}
+
+func IsSensorType(word string) bool {
+	switch word {
+	case Thermometer, HumiditySensor, ComboSensor, PressureSensor, CO2Sensor:
+		return true
+	}
+
+	return false
+}
+
+func ParseTimestamp(word string) (time.Time, error) {
+	var err error
+	for _, layout := range TimestampLayouts {
+		var timestamp time.Time
+		timestamp, err = time.Parse(layout, word)
+		if err == nil {
+			return timestamp, nil
+		}
+	}
+
+	return time.Time{}, err
+}
+
+func classifyToken(word string) TokenKind {
+	switch {
+	case word == ReferenceKeyword || word == MetadataKeyword:
+		return TokenKeyword
+	case IsSensorType(word):
+		return TokenSensorType
+	}
+
+	if _, err := ParseNumber(word); err == nil {
+		return TokenNumber
+	}
+
+	if _, err := ParseTimestamp(word); err == nil {
+		return TokenTimestamp
+	}
+
+	if strings.Contains(word, "=") {
+		return TokenKeyValue
+	}
+
+	return TokenWord
+}
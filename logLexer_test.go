@@ -0,0 +1,56 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLexLine_Reading(t *testing.T) {
+	res, err := LexLine("2007-04-05T22:00 temp-1 72.4")
+
+	assert.Nil(t, err)
+	assert.Equal(t, []Token{
+		{Kind: TokenTimestamp, Text: "2007-04-05T22:00"},
+		{Kind: TokenWord, Text: "temp-1"},
+		{Kind: TokenNumber, Text: "72.4"},
+	}, res)
+}
+
+func TestLexLine_ReferenceWithQuantities(t *testing.T) {
+	res, err := LexLine("reference 70 45 pressure=1013.25")
+
+	assert.Nil(t, err)
+	assert.Equal(t, []Token{
+		{Kind: TokenKeyword, Text: "reference"},
+		{Kind: TokenNumber, Text: "70"},
+		{Kind: TokenNumber, Text: "45"},
+		{Kind: TokenKeyValue, Text: "pressure=1013.25"},
+	}, res)
+}
+
+func TestLexLine_Declaration(t *testing.T) {
+	res, err := LexLine("combo \"combo 1\"")
+
+	assert.Nil(t, err)
+	assert.Equal(t, []Token{
+		{Kind: TokenSensorType, Text: "combo"},
+		{Kind: TokenWord, Text: "combo 1"},
+	}, res)
+}
+
+func TestLexLine_UnterminatedQuote(t *testing.T) {
+	_, err := LexLine("thermometer \"temp-1")
+
+	assert.NotNil(t, err)
+}
+
+func TestParseTimestamp_Layouts(t *testing.T) {
+	for _, timestamp := range []string{"2007-04-05T22:00", "2007-04-05T22:00:30", "2007-04-05T22:00:30+02:00"} {
+		_, err := ParseTimestamp(timestamp)
+		assert.Nil(t, err, timestamp)
+	}
+
+	_, err := ParseTimestamp("yesterday")
+	assert.NotNil(t, err)
+}
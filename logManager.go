@@ -2,6 +2,8 @@ package main
 
 import (
 	"bufio"
+	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -14,103 +16,513 @@ import (
  *   We also assume they're copied and pasted directly in the console as inputs.
  *   Obviously, for more complex logs (files stored on disk, weighting several Gb), other
  *   solutions would fit better (https://pkg.go.dev/io/ioutil#ReadAll or https://pkg.go.dev/bufio)
+ *   Large logs submitted as jobs are such files: they're graded as a stream, see GradeLogStream.
  */
 
 /**
  * Reding stdin from console line
  */
-func ReadInput(stdin io.Reader) []string {
+func ReadInput(stdin io.Reader) ([]string, error) {
 	var lines []string
 
 	scan := bufio.NewScanner(stdin)
 
-	// Scan until break char
-	for {
+	// Prompt for input, only when it's typed: piped logs don't need it
+	if IsTerminal(stdin) {
+		fmt.Fprintln(os.Stderr, Translate("Enter log content:"))
+	}
 
-		// Prompt for input
-		fmt.Println("Enter log content:")
-		for scan.Scan() {
+	// Scan until break char or the end of the input
+	for scan.Scan() {
 
-			line := scan.Text()
-			if len(line) == 1 {
-				// Set break char as Ctrl+]
-				if line[0] == '\x1D' {
-					return lines
-				}
+		line := scan.Text()
+		if len(line) == 1 {
+			// Set break char as Ctrl+]
+			if line[0] == '\x1D' {
+				return lines, nil
 			}
-			// aggregate lines in an array
-			lines = append(lines, line)
+		}
+		// aggregate lines in an array
+		lines = append(lines, line)
+	}
+
+	// In case something goes wrong, stop reading the input
+	if err := scan.Err(); err != nil {
+		return nil, err
+	}
+
+	return lines, nil
+}
+
+// Input formats
+const LegacyFormat = "log"
+const CSVFormat = "csv"
+const JSONLinesFormat = "jsonl"
+
+var InputFormats = []string{LegacyFormat, CSVFormat, JSONLinesFormat}
+
+/**
+ * Reading the log from stdin and finding out its format, detected from its
+ * first lines unless it's forced
+ */
+func ReadLogInput(stdin io.Reader, format string, mapping CSVColumnMapping) ([]string, string, error) {
+	var lines []string
+	if IsTerminal(stdin) {
+		// Typed logs aren't compressed, and looking for the magic bytes would wait for the first line before the prompt
+		var err error
+		if lines, err = ReadInput(stdin); err != nil {
+			return nil, "", err
+		}
+	} else {
+		// A truncated or corrupted stream is reported, not graded
+		var err error
+		if lines, err = ReadLines(stdin); err != nil {
+			return nil, "", err
+		}
+		// Piped logs may end with the break char too
+		for i, line := range lines {
+			if line == "\x1D" {
+				lines = lines[:i]
+				break
+			}
+		}
+	}
+
+	format, err := ResolveFormat(lines, format, mapping)
+	return lines, format, err
+}
+
+/**
+ * Detecting the format of the log, unless it's forced
+ */
+func ResolveFormat(lines []string, format string, mapping CSVColumnMapping) (string, error) {
+	if format != AutoFormat {
+		return format, nil
+	}
+
+	return DetectFormat(lines, mapping)
+}
+
+/**
+ * Extracting the reference and the sensors of a log in the given format. Readers
+ * checking a schema return the records they discarded as diagnostics
+ */
+func ExtractLog(lines []string, format string, mapping CSVColumnMapping, table CalibrationTable) (ReferenceInterface, []SensorInterface, []Diagnostic, error) {
+	switch format {
+	case AutoFormat:
+		detected, err := DetectFormat(lines, mapping)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		return ExtractLog(lines, detected, mapping, table)
+	case LegacyFormat:
+		ref, sensors, err := ExtractLegacyLog(lines, table)
+		return ref, sensors, nil, err
+	}
+
+	return ReadLog(strings.NewReader(strings.Join(lines, "\n")), format, mapping, table)
+}
+
+/**
+ * Same as ExtractLog, reading the log from a stream in a known format
+ */
+func ReadLog(r io.Reader, format string, mapping CSVColumnMapping, table CalibrationTable) (ReferenceInterface, []SensorInterface, []Diagnostic, error) {
+	switch format {
+	case LegacyFormat:
+		ref, sensors, err := ReadLegacyLog(r, table)
+		return ref, sensors, nil, err
+	case CSVFormat:
+		return ReadCSVLog(r, mapping, table)
+	case JSONLinesFormat:
+		return ReadJSONLog(r, table)
+	}
+
+	return nil, nil, nil, errors.New(Translate("Unknown input format %s", format))
+}
+
+// Reader of the lines of a log, read one by one
+type logReader interface {
+	readLine(line string)
+	// Reference, sensors and diagnostics of the lines read so far
+	results() (ReferenceInterface, []SensorInterface, []Diagnostic, error)
+}
+
+func newLogReader(format string, mapping CSVColumnMapping, table CalibrationTable) (logReader, error) {
+	switch format {
+	case LegacyFormat:
+		return newLegacyLogReader(table), nil
+	case CSVFormat:
+		return newCSVLogReader(mapping, table), nil
+	case JSONLinesFormat:
+		return newJSONLogReader(table), nil
+	}
+
+	return nil, errors.New(Translate("Unknown input format %s", format))
+}
+
+/**
+ * Reading a log and grading its sensors. The diagnostics of the reader are
+ * returned to be reported with the results, even when the log can't be graded,
+ * with the format the log was read in
+ */
+func GradeLog(lines []string, format string, mapping CSVColumnMapping, table CalibrationTable) (ReferenceInterface, []SensorInterface, []Diagnostic, string, error) {
+	format, err := ResolveFormat(lines, format, mapping)
+	if err != nil {
+		return nil, nil, nil, "", err
+	}
+
+	ref, sensors, diagnostics, err := ExtractLog(lines, format, mapping, table)
+	if err != nil {
+		return nil, nil, diagnostics, format, err
+	}
+
+	ComputeResults(sensors, ref)
+
+	return ref, sensors, diagnostics, format, nil
+}
+
+/**
+ * Same as GradeLog for a log read from a stream, compressed or not. The log isn't
+ * loaded in memory: only its first lines are kept, to detect its format
+ */
+func GradeLogStream(r io.Reader, format string, mapping CSVColumnMapping, table CalibrationTable) (ReferenceInterface, []SensorInterface, []Diagnostic, string, error) {
+	input, err := Decompress(r)
+	if err != nil {
+		return nil, nil, nil, "", err
+	}
+
+	if format == AutoFormat {
+		buffered := bufio.NewReader(input)
+		head, lines, err := readDetectionHead(buffered)
+		if err != nil {
+			return nil, nil, nil, "", err
+		}
+
+		if format, err = DetectFormat(lines, mapping); err != nil {
+			return nil, nil, nil, "", err
+		}
+
+		// The lines the format was detected from are read again
+		input = io.MultiReader(bytes.NewReader(head), buffered)
+	}
+
+	ref, sensors, diagnostics, err := ReadLog(input, format, mapping, table)
+	if err != nil {
+		return nil, nil, diagnostics, format, err
+	}
+
+	ComputeResults(sensors, ref)
+
+	return ref, sensors, diagnostics, format, nil
+}
+
+// Reading lines until there are enough to detect the format, returns them along with the bytes read
+func readDetectionHead(r *bufio.Reader) ([]byte, []string, error) {
+	var head []byte
+	var lines []string
+	for len(getDetectionSample(lines)) < DetectionSampleSize {
+		line, err := r.ReadString('\n')
+		if line != "" {
+			head = append(head, line...)
+			lines = append(lines, strings.TrimRight(line, "\r\n"))
+		}
+
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return head, lines, nil
+}
+
+/**
+ * Extracting the reference and the sensors of a space-separated log
+ */
+func ExtractLegacyLog(lines []string, table CalibrationTable) (ReferenceInterface, []SensorInterface, error) {
+	header, lines := ExtractHeader(lines)
+
+	ref, err := ExtractRef(header)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if lines == nil {
+		return ref, nil, errors.New(Translate("No content found for sensors, exiting now"))
+	}
+
+	return ref, ExtractCalibratedSensorData(lines, table), nil
+}
+
+/**
+ * Same as ExtractLegacyLog, reading the lines of the log one by one from a stream
+ */
+func ReadLegacyLog(r io.Reader, table CalibrationTable) (ReferenceInterface, []SensorInterface, error) {
+	reader := newLegacyLogReader(table)
+
+	scan := bufio.NewScanner(r)
+	for scan.Scan() {
+		reader.readLine(scan.Text())
+	}
+	if err := scan.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	ref, sensors, _, err := reader.results()
+	return ref, sensors, err
+}
+
+type legacyLogReader struct {
+	header     string
+	seenHeader bool
+	hasContent bool
+
+	sensors *legacySensorReader
+}
+
+func newLegacyLogReader(table CalibrationTable) *legacyLogReader {
+	return &legacyLogReader{sensors: newLegacySensorReader(table)}
+}
+
+func (r *legacyLogReader) readLine(line string) {
+	if !r.seenHeader {
+		if !isPreambleLine(line) {
+			r.header, r.seenHeader = line, true
+		}
+		return
+	}
+
+	r.sensors.read(line)
+	r.hasContent = true
+}
+
+// Reference and sensors of the lines read so far, the space-separated format has no diagnostics
+func (r *legacyLogReader) results() (ReferenceInterface, []SensorInterface, []Diagnostic, error) {
+	ref, err := ExtractRef(r.header)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if !r.hasContent {
+		return ref, nil, nil, errors.New(Translate("No content found for sensors, exiting now"))
+	}
+
+	return ref, r.sensors.finish(), nil, nil
+}
+
+/**
+ * Finding the header (reference line) of the log: the first line which isn't blank,
+ * a comment or metadata. Returns the header and the lines following it, if any
+ */
+func ExtractHeader(lines []string) (string, []string) {
+	for i, line := range lines {
+		if isPreambleLine(line) {
+			continue
 		}
 
-		// In case something goes wrong, stop reading the input
-		if err := scan.Err(); err != nil {
-			fmt.Println(err)
-			os.Exit(1)
+		if i+1 < len(lines) {
+			return line, lines[i+1:]
 		}
+		return line, nil
+	}
+
+	return "", nil
+}
+
+// Blank lines, comments and metadata may come before the reference line
+func isPreambleLine(line string) bool {
+	tokens, err := Tokenize(line)
+	return err == nil && (len(tokens) == 0 || tokens[0] == MetadataKeyword)
+}
+
+/**
+ * Reading a log stored on disk, compressed or not
+ */
+func ReadLogFile(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
 	}
+	defer file.Close()
 
-	return lines
+	return ReadLines(file)
+}
+
+/**
+ * Reading the lines of a log which may be compressed
+ */
+func ReadLines(r io.Reader) ([]string, error) {
+	input, err := Decompress(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return ScanLines(input)
+}
+
+// Reading the lines of a log which isn't compressed, or was decompressed
+func ScanLines(r io.Reader) ([]string, error) {
+	var lines []string
+	scan := bufio.NewScanner(r)
+	for scan.Scan() {
+		lines = append(lines, scan.Text())
+	}
+
+	return lines, scan.Err()
 }
 
 func ExtractSensorData(lines []string) []SensorInterface {
+	return ExtractCalibratedSensorData(lines, nil)
+}
 
-	/**
-	 * Probably a better approach for this function would be to have a map of all sensors
-	 * and adding data as we read lines. However, this means accessing data on each and
-	 * every line we get and possibly updating the inmemory store, which is ressource
-	 * intensive.
-	 * Instead, we'll be using a buffering approach where we assume we read lines until we
-	 * find a new sensor, then we add data to this sensor until we find another line with
-	 * a new sensor. By buffering the data like this, we make sure we're only writing to
-	 * the slice.
-	 */
+/**
+ * Same as ExtractSensorData, applying the correction found in the calibration
+ * table (if any) to the readings of each sensor
+ */
+func ExtractCalibratedSensorData(lines []string, table CalibrationTable) []SensorInterface {
+	reader := newLegacySensorReader(table)
+	for _, line := range lines {
+		reader.read(line)
+	}
+
+	return reader.finish()
+}
+
+/**
+ * Probably a better approach for this reader would be to have a map of all sensors
+ * and adding data as we read lines. However, this means accessing data on each and
+ * every line we get and possibly updating the inmemory store, which is ressource
+ * intensive.
+ * Instead, we'll be using a buffering approach where we assume we read lines until we
+ * find a new sensor, then we add data to this sensor until we find another line with
+ * a new sensor. By buffering the data like this, we make sure we're only writing to
+ * the slice.
+ */
+type legacySensorReader struct {
+	table CalibrationTable
 
 	// Are we expecting a new sensor or some sensor data?
-	expectingSensor := true
-	expectingData := false
+	expectingSensor bool
+	expectingData   bool
 
-	// Init sensors
-	sensors := make([]SensorInterface, 0)
-	var currentSensor SensorInterface
+	sensors       []SensorInterface
+	currentSensor SensorInterface
+}
 
-	// We arbitrarilly decide that all data from a given sensor are within the sensor's block
-	for _, line := range lines {
-		data := strings.Split(strings.TrimSpace(line), " ")
+func newLegacySensorReader(table CalibrationTable) *legacySensorReader {
+	return &legacySensorReader{
+		table:           table,
+		expectingSensor: true,
+		sensors:         make([]SensorInterface, 0),
+	}
+}
 
-		if expectingSensor && len(data) == 3 {
-			// We are looking for some new Sensor and we are reading data, skip the line
-			continue
+// We arbitrarilly decide that all data from a given sensor are within the sensor's block
+func (r *legacySensorReader) read(line string) {
+	data, err := Tokenize(line)
+	if err != nil {
+		logger.Info(err.Error())
+		return
+	}
+
+	// Blank lines, comments and metadata
+	if len(data) == 0 || data[0] == MetadataKeyword {
+		return
+	}
+
+	if r.expectingSensor && len(data) != 2 {
+		// We are looking for some new Sensor and we are reading data, skip the line
+		return
+	}
+
+	if r.expectingData && len(data) == 2 {
+		// We are getting a new sensor, which means the data from last sensor are all fetched
+		// 1. append the sensor data to the returned slice
+		r.sensors = append(r.sensors, r.currentSensor)
+
+		// 2. swap flags
+		r.expectingSensor = true
+		r.expectingData = false
+	}
+
+	if r.expectingData {
+		// 1. Append values to current sensor, don't care about errors (arbitrary choice)
+		err := r.currentSensor.AppendData(data)
+		if err != nil {
+			logger.Info(err.Error())
 		}
+	}
 
-		if expectingData && len(data) == 2 {
-			// We are getting a new sensor, which means the data from last sensor are all fetched
-			// 1. append the sensor data to the returned slice
-			sensors = append(sensors, currentSensor)
+	if r.expectingSensor {
+		// 1. Create a new sensor
+		r.currentSensor = NewSensor(data[0], data[1])
+		r.table.Calibrate(r.currentSensor)
+		// 2. swap flags
+		r.expectingSensor = false
+		r.expectingData = true
+	}
+}
+
+// Sensors read so far, more lines can be read afterwards
+func (r *legacySensorReader) finish() []SensorInterface {
+	// Don't forget to add the last sensor to the list
+	if r.currentSensor != nil {
+		return append(r.sensors[:len(r.sensors):len(r.sensors)], r.currentSensor)
+	}
+
+	return r.sensors
+}
 
-			// 2. swap flags
-			expectingSensor = true
-			expectingData = false
+/**
+ * Splitting a multi-setpoint log: every "reference" line starts a new setpoint
+ * and is followed by the sensors logged at these reference values
+ */
+func ExtractSetpoints(lines []string) ([]Setpoint, error) {
+	var setpoints []Setpoint
+	var header string
+	var block []string
+
+	flush := func() error {
+		if header == "" {
+			return nil
 		}
 
-		if expectingData {
-			// 1. Append values to current sensor, don't care about errors (arbitrary choice)
-			err := currentSensor.AppendData(data)
-			if err != nil {
-				fmt.Fprintln(os.Stderr, err.Error())
-			}
+		ref, err := ExtractRef(header)
+		if err != nil {
+			return err
+		}
+
+		var sensors []SensorInterface
+		if len(block) > 0 {
+			sensors = ExtractSensorData(block)
 		}
+		setpoints = append(setpoints, Setpoint{Ref: ref, Sensors: sensors})
+
+		return nil
+	}
 
-		if expectingSensor {
-			// 1. Create a new sensor
-			currentSensor = NewSensor(data[0], data[1])
-			// 2. swap flags
-			expectingSensor = false
-			expectingData = true
+	for _, line := range lines {
+		data, err := Tokenize(line)
+		if err != nil || len(data) == 0 || data[0] != "reference" {
+			block = append(block, line)
+			continue
 		}
+
+		if err := flush(); err != nil {
+			return nil, err
+		}
+		header, block = line, nil
 	}
 
-	// Don't forget to add the last sensor to the list
-	sensors = append(sensors, currentSensor)
+	if err := flush(); err != nil {
+		return nil, err
+	}
+
+	if len(setpoints) == 0 {
+		return nil, errors.New(Translate("No reference found in the log, can't extract setpoints"))
+	}
 
-	return sensors
+	return setpoints, nil
 }
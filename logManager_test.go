@@ -1,7 +1,9 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -12,7 +14,8 @@ func TestReadInput_HappyPath(t *testing.T) {
 
 	stdin.Write([]byte("reference 70.0 45.0\nthermometer temp-1\n2007-04-05T22:00 temp-1 72.4\n\x1D"))
 
-	res := ReadInput(&stdin)
+	res, err := ReadInput(&stdin)
+	assert.Nil(t, err)
 	assert.Equal(t, 3, len(res))
 }
 
@@ -21,10 +24,20 @@ func TestReadInput_NoLines(t *testing.T) {
 
 	stdin.Write([]byte("\x1D"))
 
-	res := ReadInput(&stdin)
+	res, err := ReadInput(&stdin)
+	assert.Nil(t, err)
 	assert.Equal(t, 0, len(res))
 }
 
+func TestReadInput_LineTooLong(t *testing.T) {
+	stdin := strings.NewReader(strings.Repeat("7", bufio.MaxScanTokenSize+1))
+
+	res, err := ReadInput(stdin)
+
+	assert.Equal(t, bufio.ErrTooLong, err)
+	assert.Nil(t, res)
+}
+
 func TestExtractSensorData_HappyPath(t *testing.T) {
 	var lines []string
 
@@ -213,3 +226,194 @@ func TestExtractSensorData_TwoSensorsNoData(t *testing.T) {
 	assert.Equal(t, 0, len(sensor2.GetValues()))
 	assert.Equal(t, expectedValues2, sensor2.GetValues())
 }
+
+func TestExtractSetpoints_HappyPath(t *testing.T) {
+	lines := []string{
+		"reference 60.0 45.0",
+		"thermometer temp-1",
+		"2007-04-05T22:00 temp-1 61.0",
+		"reference 80.0 50.0",
+		"thermometer temp-1",
+		"2007-04-05T23:00 temp-1 81.0",
+		"humidity hum-1",
+		"2007-04-05T23:00 hum-1 50.2",
+	}
+
+	res, err := ExtractSetpoints(lines)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(res))
+	assert.Equal(t, 60.0, res[0].Ref.GetRefTemperature())
+	assert.Equal(t, 1, len(res[0].Sensors))
+	assert.Equal(t, 80.0, res[1].Ref.GetRefTemperature())
+	assert.Equal(t, 50.0, res[1].Ref.GetRefHumidity())
+	assert.Equal(t, 2, len(res[1].Sensors))
+	assert.Equal(t, []float64{50.2}, res[1].Sensors[1].GetValues())
+}
+
+func TestExtractSetpoints_NoReference(t *testing.T) {
+	lines := []string{
+		"thermometer temp-1",
+		"2007-04-05T22:00 temp-1 61.0",
+	}
+
+	_, err := ExtractSetpoints(lines)
+
+	assert.NotNil(t, err)
+	assert.Equal(t, "No reference found in the log, can't extract setpoints", err.Error())
+}
+
+func TestExtractCalibratedSensorData_AppliesCorrection(t *testing.T) {
+	lines := []string{
+		"thermometer temp-1",
+		"2007-04-05T22:00 temp-1 71.0",
+		"2007-04-05T22:01 temp-1 72.0",
+		"humidity hum-1",
+		"2007-04-05T22:00 hum-1 45.2",
+	}
+	table := CalibrationTable{
+		"temp-1": NewLinearCorrection(-1, 1),
+	}
+
+	res := ExtractCalibratedSensorData(lines, table)
+
+	assert.Equal(t, 2, len(res))
+	assert.Equal(t, []float64{70.0, 71.0}, res[0].GetValues())
+	// Sensors missing from the table are left untouched
+	assert.Equal(t, []float64{45.2}, res[1].GetValues())
+}
+
+func TestExtractSensorData_WhitespaceCommentsAndQuotes(t *testing.T) {
+	lines := []string{
+		"# rig 3",
+		"thermometer\t\"temp 1\"\r",
+		"",
+		"2007-04-05T22:00  \"temp 1\"\t72.4 # first reading\r",
+		"   ",
+		"2007-04-05T22:01 \"temp 1\" 76.0\r",
+		"humidity  hum-1",
+		"2007-04-05T22:04\thum-1\t45.2",
+	}
+
+	res := ExtractSensorData(lines)
+
+	assert.Equal(t, 2, len(res))
+	assert.Equal(t, "temp 1", res[0].GetName())
+	assert.Equal(t, []float64{72.4, 76.0}, res[0].GetValues())
+	assert.Equal(t, "hum-1", res[1].GetName())
+	assert.Equal(t, []float64{45.2}, res[1].GetValues())
+}
+
+func TestExtractSensorData_NoSensor(t *testing.T) {
+	res := ExtractSensorData([]string{"", "# nothing logged"})
+
+	assert.NotNil(t, res)
+	assert.Equal(t, 0, len(res))
+}
+
+func TestExtractHeader_SkipsBlankLinesAndComments(t *testing.T) {
+	lines := []string{"", "# chamber B", "reference\t70.0  45.0\r", "thermometer temp-1"}
+
+	header, res := ExtractHeader(lines)
+
+	assert.Equal(t, "reference\t70.0  45.0\r", header)
+	assert.Equal(t, []string{"thermometer temp-1"}, res)
+
+	ref, err := ExtractRef(header)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 70.0, ref.GetRefTemperature())
+	assert.Equal(t, 45.0, ref.GetRefHumidity())
+}
+
+func TestExtractHeader_NoContent(t *testing.T) {
+	header, res := ExtractHeader([]string{"reference 70.0 45.0"})
+	assert.Equal(t, "reference 70.0 45.0", header)
+	assert.Nil(t, res)
+
+	header, res = ExtractHeader(nil)
+	assert.Equal(t, "", header)
+	assert.Nil(t, res)
+}
+
+func TestExtractSensorData_SkipsMetadata(t *testing.T) {
+	lines := []string{
+		"thermometer temp-1",
+		"meta door=open",
+		"2007-04-05T22:00 temp-1 72.4",
+	}
+
+	res := ExtractSensorData(lines)
+
+	assert.Equal(t, 1, len(res))
+	assert.Equal(t, []float64{72.4}, res[0].GetValues())
+}
+
+func TestExtractLog_Formats(t *testing.T) {
+	legacy := []string{"reference 70.0 45.0", "thermometer temp-1", "2007-04-05T22:00 temp-1 72.4"}
+	csvLines := []string{"timestamp,sensor,type,value", ",temperature,reference,70.0", ",humidity,reference,45.0", "2007-04-05T22:00,temp-1,thermometer,72.4"}
+	jsonLines := []string{
+		`{"kind": "reference", "temperature": 70.0, "humidity": 45.0}`,
+		`{"kind": "declaration", "type": "thermometer", "sensor": "temp-1"}`,
+		`{"kind": "reading", "timestamp": "2007-04-05T22:00", "sensor": "temp-1", "value": 72.4}`,
+	}
+
+	for format, lines := range map[string][]string{LegacyFormat: legacy, CSVFormat: csvLines, JSONLinesFormat: jsonLines} {
+		ref, sensors, diagnostics, err := ExtractLog(lines, format, DefaultCSVColumnMapping, nil)
+
+		assert.Nil(t, err, format)
+		assert.Equal(t, 0, len(diagnostics), format)
+		assert.Equal(t, 70.0, ref.GetRefTemperature(), format)
+		assert.Equal(t, 1, len(sensors), format)
+		assert.Equal(t, []float64{72.4}, sensors[0].GetValues(), format)
+	}
+
+	_, _, _, err := ExtractLog(legacy, "potato", DefaultCSVColumnMapping, nil)
+
+	assert.NotNil(t, err)
+	assert.Equal(t, "Unknown input format potato", err.Error())
+}
+
+func TestExtractLegacyLog_NoContent(t *testing.T) {
+	_, _, err := ExtractLegacyLog([]string{"reference 70.0 45.0"}, nil)
+
+	assert.NotNil(t, err)
+	assert.Equal(t, "No content found for sensors, exiting now", err.Error())
+}
+
+func TestExtractLog_AutoFormat(t *testing.T) {
+	lines := []string{`{"kind": "reference", "temperature": 70.0, "humidity": 45.0}`}
+
+	ref, sensors, _, err := ExtractLog(lines, AutoFormat, DefaultCSVColumnMapping, nil)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 70.0, ref.GetRefTemperature())
+	assert.Equal(t, 0, len(sensors))
+}
+
+func TestGradeLogStream_MatchesGradeLog(t *testing.T) {
+	legacy := "# chamber B\nreference 70.0 45.0\nthermometer temp-1\n2007-04-05T22:00 temp-1 72.4\n2007-04-05T22:01 temp-1 72.6\n"
+	csvLog := "timestamp,sensor,type,value\n,temperature,reference,70.0\n,humidity,reference,45.0\n2007-04-05T22:00,temp-1,thermometer,72.4\n2007-04-05T22:01,temp-1,thermometer,72.6"
+	jsonLog := `{"kind": "reference", "temperature": 70.0, "humidity": 45.0}
+{"kind": "declaration", "type": "thermometer", "sensor": "temp-1"}
+{"kind": "reading", "timestamp": "2007-04-05T22:00", "sensor": "temp-1", "value": 72.4}
+{"kind": "reading", "timestamp": "2007-04-05T22:01", "sensor": "temp-1", "value": 72.6}
+`
+
+	for expected, log := range map[string]string{LegacyFormat: legacy, CSVFormat: csvLog, JSONLinesFormat: jsonLog} {
+		lines, _ := ReadLines(strings.NewReader(log))
+		_, batch, _, _, err := GradeLog(lines, AutoFormat, DefaultCSVColumnMapping, nil)
+		assert.Nil(t, err, expected)
+
+		// The format is detected from the first lines, which are graded too
+		ref, sensors, _, format, err := GradeLogStream(strings.NewReader(log), AutoFormat, DefaultCSVColumnMapping, nil)
+		assert.Nil(t, err, expected)
+		assert.Equal(t, expected, format)
+		assert.Equal(t, 70.0, ref.GetRefTemperature(), expected)
+		assert.Equal(t, []float64{72.4, 72.6}, sensors[0].GetValues(), expected)
+		assert.Equal(t, batch[0].GetRating(), sensors[0].GetRating(), expected)
+	}
+
+	_, _, _, _, err := GradeLogStream(strings.NewReader("reference 70.0 45.0\n"), LegacyFormat, DefaultCSVColumnMapping, nil)
+	assert.Equal(t, "No content found for sensors, exiting now", err.Error())
+}
@@ -0,0 +1,302 @@
+package main
+
+import (
+	"strings"
+)
+
+/**
+ * Parser of the log format
+ *   The grammar (see README) is:
+ *
+ *     log         = { blank | comment | metadata } reference { statement } .
+ *     statement   = blank | comment | metadata | reference | declaration | reading .
+ *     reference   = "reference" number number { quantity } .
+ *     quantity    = ( "pressure" | "co2" | uncertainty ) "=" number .
+ *     uncertainty = "temperature_uncertainty" | "humidity_uncertainty"
+ *                 | "pressure_uncertainty" | "co2_uncertainty" .
+ *     metadata    = "meta" pair { pair } .
+ *     pair        = key "=" value .
+ *     declaration = type name .
+ *     type        = "thermometer" | "humidity" | "combo" | "pressure" | "co2" .
+ *     reading     = timestamp name number [ number ] .
+ *
+ *   Readings follow the declaration of their sensor, combo sensors have 2 values
+ *   per reading, and uncertainties can't be negative. Unlike ExtractSensorData, which discards what it can't use, the
+ *   parser reports every violation of the grammar.
+ */
+type StatementKind int
+
+const (
+	StatementReference StatementKind = iota
+	StatementMetadata
+	StatementDeclaration
+	StatementReading
+)
+
+type Statement struct {
+	Kind   StatementKind
+	Line   int
+	Tokens []Token
+}
+
+// A diagnostic is attached to a line of the input, when there's one (Line > 0)
+// Line is 0 when the diagnostic isn't about a line
+type Diagnostic struct {
+	Line    int    `json:"line,omitempty"`
+	Message string `json:"message"`
+}
+
+func (d Diagnostic) String() string {
+	if d.Line <= 0 {
+		return d.Message
+	}
+
+	return Translate("line %d: %s", d.Line, d.Message)
+}
+
+type logParser struct {
+	statements  []Statement
+	diagnostics []Diagnostic
+
+	seenReference bool
+	currentName   string
+	currentType   string
+	declared      map[string]bool
+}
+
+func ParseLog(lines []string) ([]Statement, []Diagnostic) {
+	parser := &logParser{
+		declared: make(map[string]bool),
+	}
+
+	for i, line := range lines {
+		parser.parseLine(i+1, line)
+	}
+
+	if !parser.seenReference {
+		parser.report(0, Translate("The log must start with a reference line"))
+	}
+
+	return parser.statements, parser.diagnostics
+}
+
+/**
+ * Checking a log against the grammar without grading it
+ */
+func ValidateLog(lines []string) []Diagnostic {
+	_, diagnostics := ParseLog(lines)
+
+	return diagnostics
+}
+
+/**
+ * Checking a log in any input format. The space-separated format is checked
+ * against the grammar, the other readers report what they can't read
+ */
+func ValidateLogFormat(lines []string, format string, mapping CSVColumnMapping) []Diagnostic {
+	if format == LegacyFormat {
+		return ValidateLog(lines)
+	}
+
+	_, _, diagnostics, err := ExtractLog(lines, format, mapping, nil)
+	if err != nil {
+		diagnostics = append(diagnostics, Diagnostic{Message: err.Error()})
+	}
+
+	return diagnostics
+}
+
+func (p *logParser) parseLine(lineNumber int, line string) {
+	tokens, err := LexLine(line)
+	if err != nil {
+		p.report(lineNumber, err.Error())
+		return
+	}
+
+	// Blank lines and comments
+	if len(tokens) == 0 {
+		return
+	}
+
+	switch {
+	case tokens[0].Text == ReferenceKeyword:
+		p.parseReference(lineNumber, tokens)
+	case tokens[0].Text == MetadataKeyword:
+		p.parseMetadata(lineNumber, tokens)
+	case tokens[0].Kind == TokenTimestamp || len(tokens) > 2:
+		p.parseReading(lineNumber, tokens)
+	case len(tokens) == 2:
+		p.parseDeclaration(lineNumber, tokens)
+	default:
+		p.report(lineNumber, Translate("Unrecognized line"))
+	}
+}
+
+func (p *logParser) parseReference(lineNumber int, tokens []Token) {
+	// A new reference starts a new setpoint, sensors are declared again
+	p.seenReference = true
+	p.currentName, p.currentType = "", ""
+	p.declared = make(map[string]bool)
+
+	if len(tokens) < 3 {
+		p.report(lineNumber, Translate("A reference line needs a temperature and a humidity"))
+		return
+	}
+
+	valid := true
+	for _, token := range tokens[1:3] {
+		if token.Kind != TokenNumber {
+			p.report(lineNumber, Translate("Invalid reference value %s", token.Text))
+			valid = false
+		}
+	}
+
+	for _, token := range tokens[3:] {
+		keyValue := strings.SplitN(token.Text, "=", 2)
+		if len(keyValue) != 2 || !isRefQuantity(keyValue[0]) {
+			p.report(lineNumber, Translate("Invalid reference quantity %s, expected <key>=<value> with a key among %s", token.Text, strings.Join(RefQuantities, ", ")))
+			valid = false
+			continue
+		}
+
+		value, err := ParseNumber(keyValue[1])
+		if err != nil {
+			p.report(lineNumber, Translate("Invalid reference value %s", keyValue[1]))
+			valid = false
+			continue
+		}
+
+		// Checked like when the log is graded, e.g. uncertainties can't be negative
+		if err := SetRefQuantity(&RefTemperatureHumidity{}, keyValue[0], value); err != nil {
+			p.report(lineNumber, err.Error())
+			valid = false
+		}
+	}
+
+	if valid {
+		p.add(StatementReference, lineNumber, tokens)
+	}
+}
+
+func isRefQuantity(key string) bool {
+	for _, quantity := range RefQuantities {
+		if key == quantity {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p *logParser) parseMetadata(lineNumber int, tokens []Token) {
+	if len(tokens) < 2 {
+		p.report(lineNumber, Translate("A metadata line needs at least one key=value pair"))
+		return
+	}
+
+	valid := true
+	for _, token := range tokens[1:] {
+		keyValue := strings.SplitN(token.Text, "=", 2)
+		if len(keyValue) != 2 || keyValue[0] == "" {
+			p.report(lineNumber, Translate("Invalid metadata %s, expected key=value", token.Text))
+			valid = false
+		}
+	}
+
+	if valid {
+		p.add(StatementMetadata, lineNumber, tokens)
+	}
+}
+
+func (p *logParser) parseDeclaration(lineNumber int, tokens []Token) {
+	if !p.checkReference(lineNumber) {
+		return
+	}
+
+	sType, name := tokens[0].Text, tokens[1].Text
+
+	// Even when the declaration is invalid, the readings following it belong to this sensor
+	p.currentName, p.currentType = name, sType
+
+	if !IsSensorType(sType) {
+		p.report(lineNumber, Translate("Unknown sensor type %s", sType))
+		return
+	}
+
+	if p.declared[name] {
+		p.report(lineNumber, Translate("Sensor %s is declared twice", name))
+		return
+	}
+	p.declared[name] = true
+
+	p.add(StatementDeclaration, lineNumber, tokens)
+}
+
+func (p *logParser) parseReading(lineNumber int, tokens []Token) {
+	if !p.checkReference(lineNumber) {
+		return
+	}
+
+	if len(tokens) < 3 {
+		p.report(lineNumber, Translate("A reading needs a timestamp, a sensor name and a value"))
+		return
+	}
+
+	valid := true
+
+	if tokens[0].Kind != TokenTimestamp {
+		p.report(lineNumber, Translate("Invalid timestamp %s", tokens[0].Text))
+		valid = false
+	}
+
+	name := tokens[1].Text
+	switch {
+	case p.currentName == "":
+		p.report(lineNumber, Translate("Reading for sensor %s before any sensor declaration", name))
+		return
+	case name != p.currentName:
+		p.report(lineNumber, Translate("Reading for sensor %s in the block of sensor %s", name, p.currentName))
+		return
+	}
+
+	values := tokens[2:]
+	expectedValues := 1
+	if p.currentType == ComboSensor {
+		expectedValues = 2
+	}
+	if len(values) != expectedValues {
+		p.report(lineNumber, Translate("Sensor %s expects %d value(s), got %d", name, expectedValues, len(values)))
+		valid = false
+	}
+
+	for _, value := range values {
+		if value.Kind != TokenNumber {
+			p.report(lineNumber, Translate("Invalid value %s for sensor %s", value.Text, name))
+			valid = false
+		}
+	}
+
+	if valid {
+		p.add(StatementReading, lineNumber, tokens)
+	}
+}
+
+func (p *logParser) checkReference(lineNumber int) bool {
+	if p.seenReference {
+		return true
+	}
+
+	p.report(lineNumber, Translate("The log must start with a reference line"))
+	// Only report the missing reference once
+	p.seenReference = true
+
+	return false
+}
+
+func (p *logParser) add(kind StatementKind, lineNumber int, tokens []Token) {
+	p.statements = append(p.statements, Statement{Kind: kind, Line: lineNumber, Tokens: tokens})
+}
+
+func (p *logParser) report(lineNumber int, message string) {
+	p.diagnostics = append(p.diagnostics, Diagnostic{Line: lineNumber, Message: message})
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLog_HappyPath(t *testing.T) {
+	lines := []string{
+		"meta rig=3 operator=jdoe",
+		"reference 70.0 45.0",
+		"thermometer temp-1",
+		"2007-04-05T22:00 temp-1 72.4",
+		"# door opened",
+		"combo combo-1",
+		"2007-04-05T22:00 combo-1 70.1 45.2",
+	}
+
+	statements, diagnostics := ParseLog(lines)
+
+	assert.Equal(t, 0, len(diagnostics))
+	assert.Equal(t, 6, len(statements))
+	assert.Equal(t, StatementMetadata, statements[0].Kind)
+	assert.Equal(t, StatementReference, statements[1].Kind)
+	assert.Equal(t, StatementDeclaration, statements[2].Kind)
+	assert.Equal(t, StatementReading, statements[3].Kind)
+	assert.Equal(t, 4, statements[3].Line)
+	assert.Equal(t, StatementDeclaration, statements[4].Kind)
+	assert.Equal(t, StatementReading, statements[5].Kind)
+}
+
+func TestValidateLog_ReportsEveryViolation(t *testing.T) {
+	lines := []string{
+		"reference 70.0 potato co2",
+		"meta rig",
+		"barometer baro-1",
+		"thermometer temp-1",
+		"yesterday temp-1 72.4",
+		"2007-04-05T22:01 temp-2 72.4",
+		"2007-04-05T22:02 temp-1 72.4 45.0",
+		"2007-04-05T22:03 temp-1 hot",
+		"thermometer temp-1",
+		"thermometer \"temp-3",
+		"oops",
+	}
+
+	res := ValidateLog(lines)
+
+	assert.Equal(t, []Diagnostic{
+		{Line: 1, Message: "Invalid reference value potato"},
+		{Line: 1, Message: "Invalid reference quantity co2, expected <key>=<value> with a key among pressure, co2, temperature_uncertainty, humidity_uncertainty, pressure_uncertainty, co2_uncertainty"},
+		{Line: 2, Message: "Invalid metadata rig, expected key=value"},
+		{Line: 3, Message: "Unknown sensor type barometer"},
+		{Line: 5, Message: "Invalid timestamp yesterday"},
+		{Line: 6, Message: "Reading for sensor temp-2 in the block of sensor temp-1"},
+		{Line: 7, Message: "Sensor temp-1 expects 1 value(s), got 2"},
+		{Line: 8, Message: "Invalid value hot for sensor temp-1"},
+		{Line: 9, Message: "Sensor temp-1 is declared twice"},
+		{Line: 10, Message: "Unterminated quote in line: thermometer \"temp-3"},
+		{Line: 11, Message: "Unrecognized line"},
+	}, res)
+}
+
+func TestValidateLog_NegativeUncertainty(t *testing.T) {
+	lines := []string{
+		"reference 70.0 45.0",
+		"thermometer temp-1",
+		"reference 70.0 45.0 co2=400 temperature_uncertainty=-0.05",
+	}
+
+	res := ValidateLog(lines)
+
+	assert.Equal(t, []Diagnostic{{Line: 3, Message: "The uncertainty of the reference can't be negative"}}, res)
+}
+
+func TestValidateLog_MissingReference(t *testing.T) {
+	res := ValidateLog([]string{"thermometer temp-1", "2007-04-05T22:00 temp-1 72.4"})
+
+	assert.Equal(t, []Diagnostic{
+		{Line: 1, Message: "The log must start with a reference line"},
+		{Line: 2, Message: "Reading for sensor temp-1 before any sensor declaration"},
+	}, res)
+}
+
+func TestValidateLog_EmptyLog(t *testing.T) {
+	res := ValidateLog(nil)
+
+	assert.Equal(t, []Diagnostic{{Line: 0, Message: "The log must start with a reference line"}}, res)
+	assert.Equal(t, "The log must start with a reference line", res[0].String())
+}
+
+func TestValidateLog_SetpointsRedeclareSensors(t *testing.T) {
+	lines := []string{
+		"reference 60.0 45.0",
+		"thermometer temp-1",
+		"reference 80.0 45.0",
+		"thermometer temp-1",
+	}
+
+	assert.Equal(t, 0, len(ValidateLog(lines)))
+}
+
+func TestDiagnosticString(t *testing.T) {
+	assert.Equal(t, "line 3: Unrecognized line", Diagnostic{Line: 3, Message: "Unrecognized line"}.String())
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+/**
+ * Logging
+ *   Results are the only thing written to the output. What the tool tells about
+ *   the run (discarded lines, diagnostics, what was read from the log...) goes to
+ *   Stderr through the logger, filtered by level:
+ *     - quiet: nothing
+ *     - info: lines and records which were discarded
+ *     - debug: also the run metadata and what was read from the log
+ */
+type LogLevel int
+
+const (
+	LogQuiet LogLevel = iota
+	LogInfo
+	LogDebug
+)
+
+var logLevelNames = map[LogLevel]string{
+	LogQuiet: "quiet",
+	LogInfo:  "info",
+	LogDebug: "debug",
+}
+
+const DefaultLogLevel = LogInfo
+
+func (l LogLevel) String() string {
+	return logLevelNames[l]
+}
+
+func ParseLogLevel(name string) (LogLevel, error) {
+	for level, levelName := range logLevelNames {
+		if levelName == name {
+			return level, nil
+		}
+	}
+
+	return DefaultLogLevel, errors.New(Translate("Unknown verbosity %s, expected quiet, info or debug", name))
+}
+
+type LoggerInterface interface {
+	Info(message string)
+	Debug(message string)
+
+	GetLevel() LogLevel
+	// Writer of the messages of the given level, discarding them when the level isn't logged
+	Writer(level LogLevel) io.Writer
+}
+
+type Logger struct {
+	output io.Writer
+	level  LogLevel
+}
+
+func NewLogger(output io.Writer, level LogLevel) LoggerInterface {
+	return &Logger{
+		output: output,
+		level:  level,
+	}
+}
+
+var logger = NewLogger(os.Stderr, DefaultLogLevel)
+
+func SetLogger(l LoggerInterface) {
+	logger = l
+}
+
+func GetLogger() LoggerInterface {
+	return logger
+}
+
+func (l *Logger) Info(message string) {
+	fmt.Fprintln(l.Writer(LogInfo), message)
+}
+
+func (l *Logger) Debug(message string) {
+	fmt.Fprintln(l.Writer(LogDebug), message)
+}
+
+func (l *Logger) GetLevel() LogLevel {
+	return l.level
+}
+
+func (l *Logger) Writer(level LogLevel) io.Writer {
+	if level > l.level {
+		return ioutil.Discard
+	}
+
+	return l.output
+}
+
+/**
+ * Telling whether a stream is a terminal: the user is then prompted for the log,
+ * and can get an interactive screen
+ */
+func IsTerminal(stream interface{}) bool {
+	file, ok := stream.(*os.File)
+	if !ok {
+		return false
+	}
+
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
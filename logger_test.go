@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogger_Levels(t *testing.T) {
+	var out bytes.Buffer
+
+	for level, expected := range map[LogLevel]string{
+		LogQuiet: "",
+		LogInfo:  "skipped line\n",
+		LogDebug: "skipped line\nfound 2 sensors\n",
+	} {
+		out.Reset()
+		l := NewLogger(&out, level)
+
+		l.Info("skipped line")
+		l.Debug("found 2 sensors")
+
+		assert.Equal(t, expected, out.String(), level.String())
+		assert.Equal(t, level, l.GetLevel())
+	}
+}
+
+func TestParseLogLevel(t *testing.T) {
+	for _, level := range []LogLevel{LogQuiet, LogInfo, LogDebug} {
+		res, err := ParseLogLevel(level.String())
+
+		assert.Nil(t, err)
+		assert.Equal(t, level, res)
+	}
+
+	_, err := ParseLogLevel("loud")
+
+	assert.NotNil(t, err)
+}
+
+func TestIsTerminal(t *testing.T) {
+	assert.False(t, IsTerminal(&bytes.Buffer{}))
+
+	file, err := os.Create(filepath.Join(t.TempDir(), "run.log"))
+	assert.Nil(t, err)
+	defer file.Close()
+
+	assert.False(t, IsTerminal(file))
+}
@@ -2,61 +2,63 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"sync"
 )
 
 func main() {
-	lines := ReadInput(os.Stdin)
-	var header string
+	// The language defaults to the locale environment, the -lang flag of the commands overrides it
+	SetLocale(DetectLocale())
 
-	/** debugging **/
-	// fmt.Printf("Found %d lines\n", len(lines))
+	os.Exit(RunCLI(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
 
-	if len(lines) > 1 {
-		header, lines = lines[0], lines[1:]
-	} else {
-		header = lines[0]
-		lines = nil
+func PrintResults(w io.Writer, sensors []SensorInterface, labels RatingLabels) {
+	for _, sensor := range sensors {
+		if sensor.GetRating().IsError() {
+			fmt.Fprintf(w, "%s: %s\n", sensor.GetName(), sensor.GetRatingError())
+			continue
+		}
+		fmt.Fprintf(w, "%s: %s\n", sensor.GetName(), labels.Label(sensor.GetRating()))
 	}
+}
 
-	ref, err := ExtractRef(header)
-	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
-	}
+/**
+ * Detailing the statistics each rating is based on
+ */
+func PrintReport(w io.Writer, sensors []SensorInterface, ref ReferenceInterface, labels RatingLabels) {
+	for i, sensor := range sensors {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
 
-	/** debugging **/
-	fmt.Printf("\nRef. Temperature is %f | Ref. Humidity is %f\n\n", ref.GetRefTemperature(), ref.GetRefHumidity())
+		if sensor.GetRating().IsError() {
+			fmt.Fprintf(w, "%s (%s): %s\n", sensor.GetName(), sensor.GetType(), sensor.GetRatingError())
+			continue
+		}
+		fmt.Fprintf(w, "%s (%s): %s\n", sensor.GetName(), sensor.GetType(), labels.Label(sensor.GetRating()))
 
-	if lines == nil {
-		fmt.Println("No content found for sensors, exiting now")
-		os.Exit(1)
+		PrintStatistics(w, sensor, ref, "")
 	}
+}
 
-	sensors := ExtractSensorData(lines)
-
-	/** debugging **/
-	fmt.Printf("Found %d sensors\n\n", len(sensors))
-
-	/** debugging **/
-	/* Testing synchroneously
-	   for _, sensor := range sensors {
-	       fmt.Printf("Data for sensor %s\n", sensor.GetName())
-	       fmt.Println(sensor.GetValues())
-	       fmt.Printf("Average value: %f\n", sensor.GetAverageValue())
-	       fmt.Printf("Standard deviation: %f\n", sensor.GetStandardDeviation())
-	       fmt.Printf("Max deviation: %f%%\n", sensor.GetMaxDeviationPercentage(ref.GetRefHumidity()))
-	       fmt.Printf("Rating: %s\n\n", sensor.CalculateRating(ref))
-	   }
-	*/
-
-	ComputeResults(sensors, ref)
-
-	// Printing results
-	for _, sensor := range sensors {
-		fmt.Printf("%s: %s\n", sensor.GetName(), sensor.GetRating())
+/**
+ * Statistics and uncertainty budget of a sensor, of each channel for combo sensors
+ */
+func PrintStatistics(w io.Writer, sensor SensorInterface, ref ReferenceInterface, indent string) {
+	if combo, isCombo := sensor.(*CombinedSensor); isCombo {
+		fmt.Fprintln(w, indent+Translate("  temperature channel:"))
+		PrintStatistics(w, combo.GetTemperatureChannel(), ref, indent+"  ")
+		fmt.Fprintln(w, indent+Translate("  humidity channel:"))
+		PrintStatistics(w, combo.GetHumidityChannel(), ref, indent+"  ")
+		fmt.Fprintln(w, indent+Translate("  dew point: %s | reference: %s | deviation: %s", FormatNumber(combo.GetAverageDewPoint(), 2), FormatNumber(ref.GetRefDewPoint(), 2), FormatNumber(combo.GetDewPointDeviation(ref), 2)))
+		return
 	}
+
+	budget := sensor.GetUncertaintyBudget(ref)
+	fmt.Fprintln(w, indent+Translate("  readings: %d | average: %s | standard deviation: %s", len(sensor.GetValues()), FormatNumber(sensor.GetAverageValue(), 2), FormatStandardDeviation(len(sensor.GetValues()), sensor.GetStandardDeviation(), 2)))
+	fmt.Fprintln(w, indent+Translate("  expanded uncertainty: %s (k=%s)", FormatNumber(budget.Expanded, 2), FormatNumber(budget.CoverageFactor, 0)))
 }
 
 func ComputeResults(sensors []SensorInterface, ref ReferenceInterface) {
@@ -72,3 +74,28 @@ func ComputeResults(sensors []SensorInterface, ref ReferenceInterface) {
 	}
 	wg.Wait()
 }
+
+func RunCalibration(w io.Writer, lines []string, exportPath string, labels RatingLabels) error {
+	setpoints, err := ExtractSetpoints(lines)
+	if err != nil {
+		return err
+	}
+
+	results := FitCalibrations(setpoints)
+
+	// Printing results
+	for _, result := range results {
+		fmt.Fprintln(w, Translate("%s: offset %s, gain %s", result.SensorName, FormatNumber(result.Offset, 4), FormatNumber(result.Gain, 4)))
+		for i := range result.RatingsBefore {
+			fmt.Fprintln(w, Translate("  setpoint %d: %s -> %s (residual %s)", result.Setpoints[i]+1, labels.Label(result.RatingsBefore[i]), labels.Label(result.RatingsAfter[i]), FormatNumber(result.Residuals[i], 4)))
+		}
+	}
+
+	file, err := os.Create(exportPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return ExportCalibrations(file, results)
+}
@@ -0,0 +1,365 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+/**
+ * MQTT client
+ *   Chamber controllers publish their readings to an MQTT broker. Only what's needed
+ *   to follow topics is implemented, from MQTT 3.1.1: connecting (with optional
+ *   credentials), subscribing, receiving messages published with QoS 0 or 1, and
+ *   keeping the connection alive. Lost connections are opened again by the
+ *   subscriber.
+ */
+const mqttConnect = 1
+const mqttConnack = 2
+const mqttPublish = 3
+const mqttPuback = 4
+const mqttSubscribe = 8
+const mqttSuback = 9
+const mqttPingreq = 12
+const mqttPingresp = 13
+const mqttDisconnect = 14
+
+const mqttProtocolLevel = 4
+
+// Return code of SUBACK when the broker refused a subscription
+const mqttSubscriptionFailure = 0x80
+
+const DefaultMQTTKeepAlive = 30 * time.Second
+
+// Environment variable holding the password of the broker, unless it's read from a file
+const MQTTPasswordVariable = "SENSOR_MQTT_PASSWORD"
+
+// How long connecting to the broker may take
+const MQTTConnectTimeout = 10 * time.Second
+
+type MQTTMessage struct {
+	Topic   string
+	Payload []byte
+}
+
+type MQTTClientInterface interface {
+	Subscribe(filters []string) error
+	// Waiting for the next message published on a subscribed topic
+	Receive() (MQTTMessage, error)
+	Close() error
+}
+
+type MQTTOptions struct {
+	ClientID  string
+	Username  string
+	Password  string
+	KeepAlive time.Duration
+}
+
+type MQTTClient struct {
+	conn      net.Conn
+	reader    *bufio.Reader
+	keepAlive time.Duration
+
+	// Writes come from Receive (acknowledgements), the pings and Close
+	writeLock sync.Mutex
+	closed    chan struct{}
+	closeOnce sync.Once
+	packetID  uint16
+	// Messages received while waiting for a SUBACK
+	pending []MQTTMessage
+}
+
+type mqttPacket struct {
+	kind  byte
+	flags byte
+	body  []byte
+}
+
+/**
+ * Connecting to the broker at the given address (host:port)
+ */
+func DialMQTT(address string, options MQTTOptions) (*MQTTClient, error) {
+	conn, err := net.DialTimeout("tcp", address, MQTTConnectTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := NewMQTTClient(conn, options)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return client, nil
+}
+
+/**
+ * Opening an MQTT session over a connection to the broker
+ */
+func NewMQTTClient(conn net.Conn, options MQTTOptions) (*MQTTClient, error) {
+	// MQTT 3.1.1 only allows a password along with a user name (3.1.2.9)
+	if options.Password != "" && options.Username == "" {
+		return nil, errors.New(Translate("An MQTT password can't be given without a user name"))
+	}
+
+	client := &MQTTClient{
+		conn:      conn,
+		reader:    bufio.NewReader(conn),
+		keepAlive: options.KeepAlive,
+		closed:    make(chan struct{}),
+	}
+
+	// Clean session: subscriptions aren't kept by the broker between connections
+	flags := byte(0x02)
+	body := appendMQTTString(nil, "MQTT")
+	body = append(body, mqttProtocolLevel)
+	if options.Username != "" {
+		flags |= 0x80
+	}
+	if options.Password != "" {
+		flags |= 0x40
+	}
+	body = append(body, flags)
+	body = appendMQTTUint16(body, uint16(options.KeepAlive/time.Second))
+	body = appendMQTTString(body, options.ClientID)
+	if options.Username != "" {
+		body = appendMQTTString(body, options.Username)
+	}
+	if options.Password != "" {
+		body = appendMQTTString(body, options.Password)
+	}
+
+	if err := client.write(mqttPacket{kind: mqttConnect, body: body}); err != nil {
+		return nil, err
+	}
+
+	conn.SetReadDeadline(time.Now().Add(MQTTConnectTimeout))
+	packet, err := readMQTTPacket(client.reader)
+	conn.SetReadDeadline(time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	if packet.kind != mqttConnack || len(packet.body) != 2 {
+		return nil, errors.New(Translate("Unexpected MQTT packet %d, expected CONNACK", packet.kind))
+	}
+	if packet.body[1] != 0 {
+		return nil, errors.New(Translate("The MQTT broker refused the connection (return code %d)", packet.body[1]))
+	}
+
+	if options.KeepAlive > 0 {
+		go client.ping()
+	}
+
+	return client, nil
+}
+
+/**
+ * Subscribing to topic filters (with + and # wildcards), with QoS 1 so messages
+ * aren't lost while the broker holds them
+ */
+func (c *MQTTClient) Subscribe(filters []string) error {
+	c.packetID++
+	id := c.packetID
+
+	body := appendMQTTUint16(nil, id)
+	for _, filter := range filters {
+		body = appendMQTTString(body, filter)
+		body = append(body, 1)
+	}
+
+	if err := c.write(mqttPacket{kind: mqttSubscribe, flags: 0x02, body: body}); err != nil {
+		return err
+	}
+
+	for {
+		packet, err := c.readPacket()
+		if err != nil {
+			return err
+		}
+
+		if packet.kind == mqttPublish {
+			message, err := c.handlePublish(packet)
+			if err != nil {
+				return err
+			}
+			c.pending = append(c.pending, message)
+			continue
+		}
+
+		if packet.kind == mqttSuback && len(packet.body) >= 2 && binary.BigEndian.Uint16(packet.body) == id {
+			for i, code := range packet.body[2:] {
+				if code == mqttSubscriptionFailure && i < len(filters) {
+					return errors.New(Translate("The MQTT broker refused the subscription to %s", filters[i]))
+				}
+			}
+			return nil
+		}
+	}
+}
+
+func (c *MQTTClient) Receive() (MQTTMessage, error) {
+	if len(c.pending) > 0 {
+		message := c.pending[0]
+		c.pending = c.pending[1:]
+		return message, nil
+	}
+
+	for {
+		packet, err := c.readPacket()
+		if err != nil {
+			return MQTTMessage{}, err
+		}
+
+		if packet.kind == mqttPublish {
+			return c.handlePublish(packet)
+		}
+	}
+}
+
+func (c *MQTTClient) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		close(c.closed)
+		// The broker may not be reading anymore
+		c.conn.SetWriteDeadline(time.Now().Add(time.Second))
+		c.write(mqttPacket{kind: mqttDisconnect})
+		err = c.conn.Close()
+	})
+
+	return err
+}
+
+/**
+ * Reading the next packet. The broker is pinged every half keep alive, it's
+ * considered gone when nothing, not even a ping response, came for longer than that
+ */
+func (c *MQTTClient) readPacket() (mqttPacket, error) {
+	for {
+		if c.keepAlive > 0 {
+			c.conn.SetReadDeadline(time.Now().Add(c.keepAlive))
+		}
+
+		packet, err := readMQTTPacket(c.reader)
+		if err != nil || packet.kind != mqttPingresp {
+			return packet, err
+		}
+	}
+}
+
+func (c *MQTTClient) ping() {
+	ticker := time.NewTicker(c.keepAlive / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.closed:
+			return
+		case <-ticker.C:
+			if err := c.write(mqttPacket{kind: mqttPingreq}); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (c *MQTTClient) handlePublish(packet mqttPacket) (MQTTMessage, error) {
+	topic, rest, err := readMQTTString(packet.body)
+	if err != nil {
+		return MQTTMessage{}, err
+	}
+
+	qos := (packet.flags >> 1) & 0x03
+	if qos > 0 {
+		if len(rest) < 2 {
+			return MQTTMessage{}, errors.New(Translate("Malformed MQTT packet"))
+		}
+		// Acknowledging QoS 1 messages, QoS 2 isn't subscribed to
+		if err := c.write(mqttPacket{kind: mqttPuback, body: rest[:2]}); err != nil {
+			return MQTTMessage{}, err
+		}
+		rest = rest[2:]
+	}
+
+	return MQTTMessage{Topic: topic, Payload: rest}, nil
+}
+
+func (c *MQTTClient) write(packet mqttPacket) error {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+
+	return writeMQTTPacket(c.conn, packet)
+}
+
+func writeMQTTPacket(w io.Writer, packet mqttPacket) error {
+	header := []byte{packet.kind<<4 | packet.flags}
+
+	// Remaining length, 7 bits per byte
+	length := len(packet.body)
+	for {
+		digit := byte(length % 128)
+		length /= 128
+		if length > 0 {
+			digit |= 0x80
+		}
+		header = append(header, digit)
+		if length == 0 {
+			break
+		}
+	}
+
+	_, err := w.Write(append(header, packet.body...))
+	return err
+}
+
+func readMQTTPacket(r *bufio.Reader) (mqttPacket, error) {
+	first, err := r.ReadByte()
+	if err != nil {
+		return mqttPacket{}, err
+	}
+
+	length, multiplier := 0, 1
+	for i := 0; ; i++ {
+		digit, err := r.ReadByte()
+		if err != nil {
+			return mqttPacket{}, err
+		}
+		if i == 3 && digit&0x80 != 0 {
+			return mqttPacket{}, errors.New(Translate("Malformed MQTT packet"))
+		}
+
+		length += int(digit&0x7F) * multiplier
+		multiplier *= 128
+		if digit&0x80 == 0 {
+			break
+		}
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return mqttPacket{}, err
+	}
+
+	return mqttPacket{kind: first >> 4, flags: first & 0x0F, body: body}, nil
+}
+
+func appendMQTTUint16(b []byte, value uint16) []byte {
+	return append(b, byte(value>>8), byte(value))
+}
+
+func appendMQTTString(b []byte, value string) []byte {
+	return append(appendMQTTUint16(b, uint16(len(value))), value...)
+}
+
+func readMQTTString(b []byte) (string, []byte, error) {
+	if len(b) < 2 || len(b) < 2+int(binary.BigEndian.Uint16(b)) {
+		return "", nil, errors.New(Translate("Malformed MQTT packet"))
+	}
+
+	length := 2 + int(binary.BigEndian.Uint16(b))
+	return string(b[2:length]), b[length:], nil
+}
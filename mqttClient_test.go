@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bufio"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+/**
+ * In-process broker stand-in, serving a single client over a pipe
+ */
+type testBroker struct {
+	conn    net.Conn
+	reader  *bufio.Reader
+	connect chan mqttPacket
+	filters chan []string
+	acks    chan mqttPacket
+}
+
+func startTestBroker(t *testing.T) (net.Conn, *testBroker) {
+	client, server := net.Pipe()
+	broker := &testBroker{
+		conn:    server,
+		reader:  bufio.NewReader(server),
+		connect: make(chan mqttPacket, 1),
+		filters: make(chan []string, 1),
+		acks:    make(chan mqttPacket, 10),
+	}
+	go broker.serve()
+	t.Cleanup(func() { server.Close() })
+
+	return client, broker
+}
+
+func (b *testBroker) serve() {
+	for {
+		packet, err := readMQTTPacket(b.reader)
+		if err != nil {
+			return
+		}
+
+		switch packet.kind {
+		case mqttConnect:
+			b.connect <- packet
+			writeMQTTPacket(b.conn, mqttPacket{kind: mqttConnack, body: []byte{0, 0}})
+		case mqttSubscribe:
+			var filters []string
+			codes := append([]byte(nil), packet.body[:2]...)
+			for rest := packet.body[2:]; len(rest) > 0; rest = rest[1:] {
+				var filter string
+				filter, rest, _ = readMQTTString(rest)
+				filters = append(filters, filter)
+				codes = append(codes, rest[0])
+			}
+			b.filters <- filters
+			writeMQTTPacket(b.conn, mqttPacket{kind: mqttSuback, body: codes})
+		case mqttPingreq:
+			writeMQTTPacket(b.conn, mqttPacket{kind: mqttPingresp})
+		default:
+			b.acks <- packet
+		}
+	}
+}
+
+func (b *testBroker) publish(topic string, payload string, packetID uint16) {
+	body := appendMQTTString(nil, topic)
+	flags := byte(0)
+	if packetID > 0 {
+		flags = 0x02
+		body = appendMQTTUint16(body, packetID)
+	}
+
+	writeMQTTPacket(b.conn, mqttPacket{kind: mqttPublish, flags: flags, body: append(body, payload...)})
+}
+
+func TestMQTTClient_ConnectAndSubscribe(t *testing.T) {
+	conn, broker := startTestBroker(t)
+
+	client, err := NewMQTTClient(conn, MQTTOptions{ClientID: "rig-3", Username: "qc", KeepAlive: time.Minute})
+	assert.Nil(t, err)
+	defer client.Close()
+
+	connect := <-broker.connect
+	assert.Equal(t, "\x00\x04MQTT\x04\x82\x00\x3c\x00\x05rig-3\x00\x02qc", string(connect.body))
+
+	assert.Nil(t, client.Subscribe([]string{"sensors/+/+", "sensors/reference"}))
+	assert.Equal(t, []string{"sensors/+/+", "sensors/reference"}, <-broker.filters)
+}
+
+func TestMQTTClient_Receive(t *testing.T) {
+	conn, broker := startTestBroker(t)
+	client, _ := NewMQTTClient(conn, MQTTOptions{ClientID: "rig-3", KeepAlive: time.Minute})
+	defer client.Close()
+
+	go func() {
+		broker.publish("sensors/thermometer/temp-1", "70.1", 0)
+		broker.publish("sensors/thermometer/temp-1", "69.9", 7)
+	}()
+
+	message, err := client.Receive()
+	assert.Nil(t, err)
+	assert.Equal(t, MQTTMessage{Topic: "sensors/thermometer/temp-1", Payload: []byte("70.1")}, message)
+
+	// QoS 1 messages are acknowledged
+	message, _ = client.Receive()
+	assert.Equal(t, "69.9", string(message.Payload))
+	assert.Equal(t, mqttPacket{kind: mqttPuback, body: []byte{0, 7}}, <-broker.acks)
+}
+
+func TestMQTTClient_KeepAlive(t *testing.T) {
+	conn, broker := startTestBroker(t)
+	client, _ := NewMQTTClient(conn, MQTTOptions{ClientID: "rig-3", KeepAlive: 100 * time.Millisecond})
+	defer client.Close()
+
+	// The broker answers the pings, the connection stays up while it's silent
+	go func() {
+		time.Sleep(300 * time.Millisecond)
+		broker.publish("sensors/session/end", "", 0)
+	}()
+
+	message, err := client.Receive()
+	assert.Nil(t, err)
+	assert.Equal(t, "sensors/session/end", message.Topic)
+}
+
+func TestWriteMQTTPacket_RemainingLength(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	body := make([]byte, 321)
+	go writeMQTTPacket(client, mqttPacket{kind: mqttPublish, body: body})
+
+	header := make([]byte, 3)
+	_, err := server.Read(header)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{0x30, 0xC1, 0x02}, header)
+}
+
+func TestNewMQTTClient_PasswordWithoutUsername(t *testing.T) {
+	conn, _ := startTestBroker(t)
+
+	_, err := NewMQTTClient(conn, MQTTOptions{ClientID: "rig-3", Password: "secret"})
+
+	assert.NotNil(t, err)
+	assert.Equal(t, "An MQTT password can't be given without a user name", err.Error())
+}
@@ -0,0 +1,416 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+/**
+ * MQTT mode
+ *   Chamber controllers publish each reading on the topic of its sensor, e.g.
+ *   "sensors/thermometer/temp-1", the reference on the reference topic, and end the
+ *   test session on the end topic. Readings are gathered as JSON Lines records
+ *   until the session ends, the session is then graded like a JSON Lines log.
+ *   Payloads are either plain text or a JSON object with the fields of the JSON
+ *   Lines records:
+ *     reading:   [timestamp] <value> [<value2>], or {"value": 70.1, "timestamp": "..."}
+ *     reference: <temperature> <humidity> [<key>=<value>...], or {"temperature": 70.0, "humidity": 45.0}
+ *     end:       name of the session, if any
+ */
+const DefaultMQTTSensorTopic = "sensors/{type}/{sensor}"
+const DefaultMQTTReferenceTopic = "sensors/reference"
+const DefaultMQTTEndTopic = "sensors/session/end"
+
+// Placeholders of the sensor topic
+const mqttTypePlaceholder = "{type}"
+const mqttSensorPlaceholder = "{sensor}"
+
+// Timestamp of the readings published without one
+const mqttTimestampLayout = "2006-01-02T15:04:05"
+
+// Delays between the attempts to connect again to the broker, doubled after each failure
+const MQTTReconnectMinDelay = time.Second
+const MQTTReconnectMaxDelay = time.Minute
+
+// Connecting to the broker again once the connection was lost
+type MQTTDialer func() (MQTTClientInterface, error)
+
+type MQTTTopics struct {
+	// Topic of the readings, {type} and {sensor} standing for the type and name of the sensor
+	Sensor    string
+	Reference string
+	End       string
+	// Topic filters subscribed to, derived from the topics when empty
+	Subscriptions []string
+}
+
+var DefaultMQTTTopics = MQTTTopics{
+	Sensor:    DefaultMQTTSensorTopic,
+	Reference: DefaultMQTTReferenceTopic,
+	End:       DefaultMQTTEndTopic,
+}
+
+type MQTTSubscriber struct {
+	client  MQTTClientInterface
+	dial    MQTTDialer
+	out     io.Writer
+	topics  MQTTTopics
+	formats []string
+	labels  RatingLabels
+	profile string
+	table   CalibrationTable
+
+	minDelay time.Duration
+	maxDelay time.Duration
+
+	// Session being recorded, kept when the connection is opened again
+	reference string
+	records   []string
+	declared  map[string]string
+	sessions  int
+}
+
+func NewMQTTSubscriber(client MQTTClientInterface, out io.Writer) *MQTTSubscriber {
+	return &MQTTSubscriber{
+		client:   client,
+		out:      out,
+		topics:   DefaultMQTTTopics,
+		formats:  []string{TextReport},
+		labels:   outputProfiles[DefaultProfile],
+		profile:  DefaultProfile,
+		minDelay: MQTTReconnectMinDelay,
+		maxDelay: MQTTReconnectMaxDelay,
+		declared: make(map[string]string),
+	}
+}
+
+// Lost connections are opened again with the dialer, Run returns the error without it
+func (s *MQTTSubscriber) SetDialer(dial MQTTDialer) {
+	s.dial = dial
+}
+
+func (s *MQTTSubscriber) SetTopics(topics MQTTTopics) {
+	s.topics = topics
+}
+
+func (s *MQTTSubscriber) SetReportFormats(formats []string) {
+	s.formats = formats
+}
+
+func (s *MQTTSubscriber) SetLabels(labels RatingLabels) {
+	s.labels = labels
+}
+
+// Output profile recorded in the metadata of the reports
+func (s *MQTTSubscriber) SetProfile(profile string) {
+	s.profile = profile
+}
+
+func (s *MQTTSubscriber) SetCalibrationTable(table CalibrationTable) {
+	s.table = table
+}
+
+/**
+ * Subscribing to the topics and grading the sessions until the context is done.
+ * A lost connection is opened again and the topics subscribed to again, waiting
+ * longer after each failed attempt
+ */
+func (s *MQTTSubscriber) Run(ctx context.Context) error {
+	// A subscription refused right away is a setup error, not a lost connection
+	subscribed, err := s.follow(ctx)
+	if !subscribed {
+		return err
+	}
+
+	delay := s.minDelay
+	for ctx.Err() == nil {
+		if s.dial == nil {
+			return err
+		}
+		logger.Info(Translate("Connection to the MQTT broker lost (%s), connecting again in %s", err, delay))
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(delay):
+		}
+
+		var client MQTTClientInterface
+		if client, err = s.dial(); err == nil {
+			s.client = client
+			subscribed, err = s.follow(ctx)
+		}
+
+		if subscribed {
+			delay = s.minDelay
+			continue
+		}
+		if delay *= 2; delay > s.maxDelay {
+			delay = s.maxDelay
+		}
+	}
+
+	return nil
+}
+
+/**
+ * Subscribing to the topics and handling the messages until the connection is lost
+ * or the context is done. Tells whether the topics were subscribed to
+ */
+func (s *MQTTSubscriber) follow(ctx context.Context) (bool, error) {
+	client := s.client
+	defer client.Close()
+
+	if err := client.Subscribe(s.topics.GetSubscriptions()); err != nil {
+		return false, err
+	}
+
+	// Receive only returns once the connection is closed
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			client.Close()
+		case <-stop:
+		}
+	}()
+
+	for {
+		message, err := client.Receive()
+		if ctx.Err() != nil {
+			return true, nil
+		}
+		if err != nil {
+			return true, err
+		}
+
+		s.HandleMessage(message)
+	}
+}
+
+/**
+ * Recording a message in the session, or grading the session when it's the end
+ * message
+ */
+func (s *MQTTSubscriber) HandleMessage(message MQTTMessage) {
+	payload := strings.TrimSpace(string(message.Payload))
+
+	switch {
+	case message.Topic == s.topics.End:
+		s.EndSession(payload)
+	case message.Topic == s.topics.Reference:
+		record, err := parseMQTTReference(payload)
+		if err != nil {
+			logger.Info(Translate("Invalid reference on %s: %s", message.Topic, err))
+			return
+		}
+		// Controllers may publish the reference again, the last one is used
+		s.reference = record
+	default:
+		sType, name, found := s.topics.matchSensor(message.Topic)
+		if !found {
+			logger.Info(Translate("Message on unexpected topic %s", message.Topic))
+			return
+		}
+
+		if err := s.recordReading(sType, name, payload); err != nil {
+			logger.Info(Translate("Invalid reading on %s: %s", message.Topic, err))
+		}
+	}
+}
+
+func (s *MQTTSubscriber) recordReading(sType string, name string, payload string) error {
+	record, err := parseMQTTReading(payload)
+	if err != nil {
+		return err
+	}
+
+	// The payload has the last word
+	if record.Type != "" {
+		sType = record.Type
+	}
+	if record.Sensor != "" {
+		name = record.Sensor
+	}
+	if sType == "" || name == "" {
+		return errors.New(Translate("The topic or the payload must give the type and the name of the sensor"))
+	}
+
+	if _, found := s.declared[name]; !found {
+		s.declared[name] = sType
+		if err := s.addRecord(jsonRecord{Kind: JSONDeclarationKind, Type: sType, Sensor: name}); err != nil {
+			return err
+		}
+	}
+
+	record.Kind, record.Type, record.Sensor = JSONReadingKind, "", name
+	if record.Timestamp == "" {
+		record.Timestamp = time.Now().Format(mqttTimestampLayout)
+	}
+
+	return s.addRecord(record)
+}
+
+func (s *MQTTSubscriber) addRecord(record jsonRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	s.records = append(s.records, string(line))
+
+	return nil
+}
+
+/**
+ * Grading the session recorded so far and writing its reports. A new session
+ * starts right after
+ */
+func (s *MQTTSubscriber) EndSession(name string) {
+	s.sessions++
+	if name == "" {
+		name = fmt.Sprint(s.sessions)
+	}
+
+	lines := s.records
+	if s.reference != "" {
+		lines = append([]string{s.reference}, lines...)
+	}
+	s.reference, s.records, s.declared = "", nil, make(map[string]string)
+
+	metadata := NewRunMetadata(s.profile)
+	metadata.InputFormat = JSONLinesFormat
+	ref, sensors, diagnostics, _, err := GradeLog(lines, JSONLinesFormat, DefaultCSVColumnMapping, s.table)
+	if err != nil {
+		logger.Info(Translate("Can't grade the session %s: %s", name, err))
+		return
+	}
+
+	logger.Debug(Translate("Session %s ended with %d sensors", name, len(sensors)))
+	for _, format := range s.formats {
+		// JSON reports stay a stream of JSON documents
+		if format != JSONReport {
+			fmt.Fprintln(s.out, Translate("Session %s", name))
+		}
+		if err := WriteReport(s.out, format, sensors, ref, diagnostics, metadata, s.labels); err != nil {
+			logger.Info(err.Error())
+		}
+	}
+}
+
+func parseMQTTReading(payload string) (jsonRecord, error) {
+	if strings.HasPrefix(payload, "{") {
+		return decodeJSONRecord(payload)
+	}
+
+	var record jsonRecord
+	tokens, err := Tokenize(payload)
+	if err != nil {
+		return record, err
+	}
+
+	// The timestamp is the only field which isn't a number
+	if len(tokens) > 0 {
+		if _, err := ParseNumber(tokens[0]); err != nil {
+			record.Timestamp, tokens = tokens[0], tokens[1:]
+		}
+	}
+	if len(tokens) == 0 || len(tokens) > 2 {
+		return record, errors.New(Translate("Expected [timestamp] <value> [<value2>]"))
+	}
+
+	values := make([]float64, len(tokens))
+	for i, token := range tokens {
+		if values[i], err = ParseNumber(token); err != nil {
+			return record, err
+		}
+	}
+	record.Value = &values[0]
+	if len(values) == 2 {
+		record.Value2 = &values[1]
+	}
+
+	return record, nil
+}
+
+// Returns the reference as a JSON Lines record
+func parseMQTTReference(payload string) (string, error) {
+	record := jsonRecord{Kind: JSONReferenceKind}
+
+	if strings.HasPrefix(payload, "{") {
+		decoded, err := decodeJSONRecord(payload)
+		if err != nil {
+			return "", err
+		}
+		record.Temperature, record.Humidity, record.Pressure, record.CO2 = decoded.Temperature, decoded.Humidity, decoded.Pressure, decoded.CO2
+		record.TemperatureUncertainty, record.HumidityUncertainty = decoded.TemperatureUncertainty, decoded.HumidityUncertainty
+		record.PressureUncertainty, record.CO2Uncertainty = decoded.PressureUncertainty, decoded.CO2Uncertainty
+	} else {
+		ref, err := ExtractRef(ReferenceKeyword + " " + payload)
+		if err != nil {
+			return "", err
+		}
+		temperature, humidity := ref.GetRefTemperature(), ref.GetRefHumidity()
+		record.Temperature, record.Humidity = &temperature, &humidity
+		if ref.HasRefPressure() {
+			pressure, u := ref.GetRefPressure(), ref.GetRefPressureUncertainty()
+			record.Pressure, record.PressureUncertainty = &pressure, &u
+		}
+		if ref.HasRefCO2() {
+			co2, u := ref.GetRefCO2(), ref.GetRefCO2Uncertainty()
+			record.CO2, record.CO2Uncertainty = &co2, &u
+		}
+		if u := ref.GetRefTemperatureUncertainty(); u > 0 {
+			record.TemperatureUncertainty = &u
+		}
+		if u := ref.GetRefHumidityUncertainty(); u > 0 {
+			record.HumidityUncertainty = &u
+		}
+	}
+
+	line, err := json.Marshal(record)
+	return string(line), err
+}
+
+/**
+ * Topic filters to subscribe to: the placeholders of the sensor topic match any
+ * level
+ */
+func (t MQTTTopics) GetSubscriptions() []string {
+	if len(t.Subscriptions) > 0 {
+		return t.Subscriptions
+	}
+
+	sensor := strings.NewReplacer(mqttTypePlaceholder, "+", mqttSensorPlaceholder, "+").Replace(t.Sensor)
+	return []string{sensor, t.Reference, t.End}
+}
+
+// Finding the type and name of the sensor in a topic matching the sensor topic
+func (t MQTTTopics) matchSensor(topic string) (string, string, bool) {
+	pattern, levels := strings.Split(t.Sensor, "/"), strings.Split(topic, "/")
+	if len(pattern) != len(levels) {
+		return "", "", false
+	}
+
+	var sType, name string
+	for i, level := range pattern {
+		switch level {
+		case mqttTypePlaceholder:
+			sType = levels[i]
+		case mqttSensorPlaceholder:
+			name = levels[i]
+		case "+":
+		default:
+			if level != levels[i] {
+				return "", "", false
+			}
+		}
+	}
+
+	return sType, name, true
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func publishSession(subscriber *MQTTSubscriber, messages [][2]string) {
+	for _, message := range messages {
+		subscriber.HandleMessage(MQTTMessage{Topic: message[0], Payload: []byte(message[1])})
+	}
+}
+
+func TestMQTTTopics_GetSubscriptions(t *testing.T) {
+	assert.Equal(t, []string{"sensors/+/+", "sensors/reference", "sensors/session/end"}, DefaultMQTTTopics.GetSubscriptions())
+
+	topics := DefaultMQTTTopics
+	topics.Subscriptions = []string{"#"}
+	assert.Equal(t, []string{"#"}, topics.GetSubscriptions())
+}
+
+func TestMQTTTopics_MatchSensor(t *testing.T) {
+	topics := MQTTTopics{Sensor: "chambers/+/{sensor}/{type}"}
+
+	sType, name, found := topics.matchSensor("chambers/3/temp-1/thermometer")
+	assert.True(t, found)
+	assert.Equal(t, Thermometer, sType)
+	assert.Equal(t, "temp-1", name)
+
+	_, _, found = topics.matchSensor("chambers/3/temp-1")
+	assert.False(t, found)
+	_, _, found = topics.matchSensor("rigs/3/temp-1/thermometer")
+	assert.False(t, found)
+}
+
+func TestMQTTSubscriber_Session(t *testing.T) {
+	var out bytes.Buffer
+	subscriber := NewMQTTSubscriber(nil, &out)
+
+	publishSession(subscriber, [][2]string{
+		{"sensors/reference", "70.0 45.0"},
+		{"sensors/thermometer/temp-1", "2007-04-05T22:00 70.1"},
+		{"sensors/humidity/hum-1", `{"value": 45.2, "timestamp": "2007-04-05T22:00"}`},
+		{"sensors/thermometer/temp-1", "69.9"},
+		{"sensors/humidity/hum-1", "45.4"},
+		{"sensors/humidity/hum-1", "wet"},
+		{"sensors/session/end", "burn-in-42"},
+	})
+
+	assert.Equal(t, "Session burn-in-42\ntemp-1: ultra precise\nhum-1: OK\n", out.String())
+
+	// The next session starts from scratch
+	out.Reset()
+	publishSession(subscriber, [][2]string{
+		{"sensors/reference", `{"temperature": 70.0, "humidity": 45.0}`},
+		{"sensors/humidity/hum-2", "40.0"},
+		{"sensors/session/end", ""},
+	})
+
+	assert.Equal(t, "Session 2\nhum-2: discard\n", out.String())
+}
+
+func TestMQTTSubscriber_SessionWithoutReference(t *testing.T) {
+	var out bytes.Buffer
+	subscriber := NewMQTTSubscriber(nil, &out)
+
+	publishSession(subscriber, [][2]string{
+		{"sensors/thermometer/temp-1", "70.1"},
+		{"sensors/session/end", ""},
+	})
+
+	assert.Equal(t, "", out.String())
+}
+
+func TestParseMQTTReading(t *testing.T) {
+	record, err := parseMQTTReading("2007-04-05T22:00 70.1 45.2")
+	assert.Nil(t, err)
+	assert.Equal(t, "2007-04-05T22:00", record.Timestamp)
+	assert.Equal(t, 70.1, *record.Value)
+	assert.Equal(t, 45.2, *record.Value2)
+
+	_, err = parseMQTTReading("70.1 45.2 12")
+	assert.EqualError(t, err, "Expected [timestamp] <value> [<value2>]")
+
+	_, err = parseMQTTReading(`{"value": 70.1, "unit": "F"}`)
+	assert.NotNil(t, err)
+}
+
+func TestMQTTSubscriber_Run(t *testing.T) {
+	conn, broker := startTestBroker(t)
+	client, err := NewMQTTClient(conn, MQTTOptions{ClientID: "sensor", KeepAlive: time.Minute})
+	assert.Nil(t, err)
+
+	var out syncBuffer
+	subscriber := NewMQTTSubscriber(client, &out)
+	subscriber.SetReportFormats([]string{JSONReport})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- subscriber.Run(ctx) }()
+
+	assert.Equal(t, DefaultMQTTTopics.GetSubscriptions(), <-broker.filters)
+	broker.publish("sensors/reference", "70.0 45.0", 1)
+	broker.publish("sensors/combo/combo-1", "70.1 45.2", 2)
+	broker.publish("sensors/combo/combo-1", "69.9 45.4", 3)
+	broker.publish("sensors/session/end", "", 4)
+
+	waitFor(t, func() bool { return strings.Contains(out.String(), "}\n") })
+	assert.Contains(t, out.String(), `"name": "combo-1"`)
+	assert.Contains(t, out.String(), `"input_format": "jsonl"`)
+
+	cancel()
+	assert.Nil(t, <-done)
+}
+
+func TestMQTTSubscriber_Reconnects(t *testing.T) {
+	conn, broker := startTestBroker(t)
+	client, _ := NewMQTTClient(conn, MQTTOptions{ClientID: "sensor", KeepAlive: time.Minute})
+
+	var out syncBuffer
+	subscriber := NewMQTTSubscriber(client, &out)
+	subscriber.minDelay = time.Millisecond
+
+	// The broker can't be reached the first time
+	reconnected := make(chan *testBroker, 1)
+	attempts := 0
+	subscriber.SetDialer(func() (MQTTClientInterface, error) {
+		if attempts++; attempts == 1 {
+			return nil, errors.New("connection refused")
+		}
+		conn, broker := startTestBroker(t)
+		reconnected <- broker
+		return NewMQTTClient(conn, MQTTOptions{ClientID: "sensor", KeepAlive: time.Minute})
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- subscriber.Run(ctx) }()
+
+	<-broker.filters
+	broker.publish("sensors/reference", "70.0 45.0", 0)
+	broker.publish("sensors/thermometer/temp-1", "2007-04-05T22:00 70.1", 0)
+	// The messages written before are read first
+	broker.conn.Close()
+
+	// The topics are subscribed to again and the session goes on
+	broker = <-reconnected
+	assert.Equal(t, DefaultMQTTTopics.GetSubscriptions(), <-broker.filters)
+	broker.publish("sensors/thermometer/temp-1", "2007-04-05T22:01 69.9", 0)
+	broker.publish("sensors/session/end", "burn-in-42", 0)
+
+	waitFor(t, func() bool { return strings.Contains(out.String(), "temp-1") })
+	assert.Equal(t, "Session burn-in-42\ntemp-1: ultra precise\n", out.String())
+	assert.Equal(t, 2, attempts)
+
+	cancel()
+	assert.Nil(t, <-done)
+}
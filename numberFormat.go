@@ -0,0 +1,142 @@
+package main
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+/**
+ * Number format of the logs
+ *   Some rigs emit locale-formatted numbers ("72,4", "1.013,25"). The input number
+ *   format is configured once at startup and used to parse every reference value
+ *   and reading.
+ */
+type NumberFormat struct {
+	DecimalSeparator   string `json:"decimal_separator"`
+	ThousandsSeparator string `json:"thousands_separator"`
+}
+
+var DefaultNumberFormat = NumberFormat{
+	DecimalSeparator:   ".",
+	ThousandsSeparator: "",
+}
+
+var inputNumberFormat = DefaultNumberFormat
+
+func NewNumberFormat(decimalSeparator string, thousandsSeparator string) (NumberFormat, error) {
+	if !isValidSeparator(decimalSeparator) {
+		return DefaultNumberFormat, errors.New(Translate("Invalid decimal separator \"%s\"", decimalSeparator))
+	}
+
+	// The thousands separator is optional
+	if thousandsSeparator != "" && !isValidSeparator(thousandsSeparator) {
+		return DefaultNumberFormat, errors.New(Translate("Invalid thousands separator \"%s\"", thousandsSeparator))
+	}
+
+	if decimalSeparator == thousandsSeparator {
+		return DefaultNumberFormat, errors.New(Translate("Decimal and thousands separators must be different"))
+	}
+
+	return NumberFormat{
+		DecimalSeparator:   decimalSeparator,
+		ThousandsSeparator: thousandsSeparator,
+	}, nil
+}
+
+func SetInputNumberFormat(nf NumberFormat) {
+	inputNumberFormat = nf
+}
+
+func GetInputNumberFormat() NumberFormat {
+	return inputNumberFormat
+}
+
+/**
+ * Parsing a number with the input number format
+ */
+func ParseNumber(raw string) (float64, error) {
+	return inputNumberFormat.Parse(raw)
+}
+
+func (nf NumberFormat) Parse(raw string) (float64, error) {
+	// Most logs use the default format, no need to normalize anything
+	if nf == DefaultNumberFormat {
+		return strconv.ParseFloat(raw, 64)
+	}
+
+	// A "." which isn't one of the configured separators means the number was written in another format
+	if nf.DecimalSeparator != "." && nf.ThousandsSeparator != "." && strings.Contains(raw, ".") {
+		return 0, &strconv.NumError{Func: "ParseFloat", Num: raw, Err: strconv.ErrSyntax}
+	}
+
+	normalized, valid := nf.normalize(raw)
+	if !valid {
+		return 0, &strconv.NumError{Func: "ParseFloat", Num: raw, Err: strconv.ErrSyntax}
+	}
+
+	value, err := strconv.ParseFloat(normalized, 64)
+	if numErr, ok := err.(*strconv.NumError); ok {
+		// Report the number as it was written in the log
+		numErr.Num = raw
+	}
+
+	return value, err
+}
+
+func (nf NumberFormat) String() string {
+	if nf.ThousandsSeparator == "" {
+		return "decimal \"" + nf.DecimalSeparator + "\""
+	}
+
+	return "decimal \"" + nf.DecimalSeparator + "\", thousands \"" + nf.ThousandsSeparator + "\""
+}
+
+/**
+ * Converting a number to the format strconv understands. Thousands separators must
+ * group digits by 3, so a value written in another format ("72.4" with a "."
+ * thousands separator) is rejected rather than silently misread
+ */
+func (nf NumberFormat) normalize(raw string) (string, bool) {
+	integerPart, decimalPart := raw, ""
+	if index := strings.Index(raw, nf.DecimalSeparator); index >= 0 {
+		integerPart, decimalPart = raw[:index], raw[index+len(nf.DecimalSeparator):]
+	}
+
+	if nf.ThousandsSeparator != "" && strings.Contains(integerPart, nf.ThousandsSeparator) {
+		groups := strings.Split(integerPart, nf.ThousandsSeparator)
+
+		firstGroup := strings.TrimLeft(groups[0], "+-")
+		if len(firstGroup) == 0 || len(firstGroup) > 3 {
+			return "", false
+		}
+		for _, group := range groups[1:] {
+			if len(group) != 3 {
+				return "", false
+			}
+		}
+
+		integerPart = strings.Join(groups, "")
+	}
+
+	if nf.ThousandsSeparator != "" && strings.Contains(decimalPart, nf.ThousandsSeparator) {
+		return "", false
+	}
+
+	if decimalPart == "" && !strings.Contains(raw, nf.DecimalSeparator) {
+		return integerPart, true
+	}
+
+	return integerPart + "." + decimalPart, true
+}
+
+func isValidSeparator(separator string) bool {
+	if utf8.RuneCountInString(separator) != 1 {
+		return false
+	}
+
+	r, _ := utf8.DecodeRuneInString(separator)
+	return !unicode.IsDigit(r) && !unicode.IsSpace(r) && r != '+' && r != '-'
+}
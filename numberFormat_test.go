@@ -0,0 +1,108 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewNumberFormat_InvalidSeparators(t *testing.T) {
+	_, err := NewNumberFormat("", "")
+	assert.Equal(t, "Invalid decimal separator \"\"", err.Error())
+
+	_, err = NewNumberFormat(",", " ")
+	assert.Equal(t, "Invalid thousands separator \" \"", err.Error())
+
+	_, err = NewNumberFormat(",", ",")
+	assert.Equal(t, "Decimal and thousands separators must be different", err.Error())
+}
+
+func TestNumberFormatParse_Default(t *testing.T) {
+	res, err := DefaultNumberFormat.Parse("72.4")
+
+	assert.Nil(t, err)
+	assert.Equal(t, 72.4, res)
+}
+
+func TestNumberFormatParse_DecimalComma(t *testing.T) {
+	nf, _ := NewNumberFormat(",", "")
+
+	res, err := nf.Parse("72,4")
+	assert.Nil(t, err)
+	assert.Equal(t, 72.4, res)
+
+	res, err = nf.Parse("-3")
+	assert.Nil(t, err)
+	assert.Equal(t, -3.0, res)
+
+	// A decimal point isn't part of this format
+	_, err = nf.Parse("72.4")
+	assert.NotNil(t, err)
+	assert.Equal(t, "strconv.ParseFloat: parsing \"72.4\": invalid syntax", err.Error())
+}
+
+func TestNumberFormatParse_ThousandsSeparator(t *testing.T) {
+	nf, _ := NewNumberFormat(",", ".")
+
+	res, err := nf.Parse("1.013,25")
+	assert.Nil(t, err)
+	assert.Equal(t, 1013.25, res)
+
+	res, err = nf.Parse("1.000.000")
+	assert.Nil(t, err)
+	assert.Equal(t, 1000000.0, res)
+
+	// Thousands separators must group digits by 3
+	_, err = nf.Parse("72.4")
+	assert.NotNil(t, err)
+
+	_, err = nf.Parse("1013.25")
+	assert.NotNil(t, err)
+
+	_, err = nf.Parse("1,013.25")
+	assert.NotNil(t, err)
+}
+
+func TestNumberFormatParse_ErrorReportsRawValue(t *testing.T) {
+	nf, _ := NewNumberFormat(",", "")
+
+	_, err := nf.Parse("potato,4")
+
+	assert.NotNil(t, err)
+	assert.Equal(t, "strconv.ParseFloat: parsing \"potato,4\": invalid syntax", err.Error())
+}
+
+func TestNumberFormatString(t *testing.T) {
+	nf, _ := NewNumberFormat(",", ".")
+
+	assert.Equal(t, "decimal \".\"", DefaultNumberFormat.String())
+	assert.Equal(t, "decimal \",\", thousands \".\"", nf.String())
+}
+
+func TestAppendData_DecimalCommaInputFormat(t *testing.T) {
+	nf, _ := NewNumberFormat(",", "")
+	SetInputNumberFormat(nf)
+	defer SetInputNumberFormat(DefaultNumberFormat)
+
+	ref, err := ExtractRef("reference 70,0 45,5")
+	assert.Nil(t, err)
+	assert.Equal(t, 45.5, ref.GetRefHumidity())
+
+	sensor := NewSensor(Thermometer, "temp-1")
+	err = sensor.AppendData([]string{"2007-04-05T22:00", "temp-1", "72,4"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []float64{72.4}, sensor.GetValues())
+}
+
+func TestNewRunMetadata_RecordsNumberFormat(t *testing.T) {
+	nf, _ := NewNumberFormat(",", ".")
+	SetInputNumberFormat(nf)
+	defer SetInputNumberFormat(DefaultNumberFormat)
+
+	res := NewRunMetadata(SpecProfile)
+
+	assert.Equal(t, nf, res.NumberFormat)
+	assert.Equal(t, DefaultLocale, res.Locale)
+	assert.Equal(t, SpecProfile, res.Profile)
+}
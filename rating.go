@@ -0,0 +1,141 @@
+package main
+
+import (
+	"errors"
+)
+
+/**
+ * Ratings
+ *   Thermometers are graded in tiers, ordered from worst to best so they can be
+ *   compared ("at least very precise"). The other sensor types only pass (Accepted)
+ *   or fail (Rejected): a pass doesn't compare with the tiers, and a rejection is
+ *   below all of them. Ratings are aggregated with "worst of" for devices with
+ *   several channels, where a pass never downgrades a tier.
+ *   Errors (invalid sensor type, missing reference...) aren't part of the ordering:
+ *   they're reported with RatingError and the error itself is kept on the sensor.
+ */
+type Rating int
+
+const (
+	RatingUnknown Rating = iota
+	RatingError
+	RatingRejected
+	RatingPrecise
+	RatingVeryPrecise
+	RatingUltraPrecise
+	RatingAccepted
+)
+
+var ratingNames = map[Rating]string{
+	RatingUnknown:      "unknown",
+	RatingError:        "error",
+	RatingRejected:     "rejected",
+	RatingPrecise:      "precise",
+	RatingVeryPrecise:  "very precise",
+	RatingUltraPrecise: "ultra precise",
+	RatingAccepted:     "accepted",
+}
+
+func (r Rating) String() string {
+	if name, found := ratingNames[r]; found {
+		return name
+	}
+
+	return ratingNames[RatingUnknown]
+}
+
+func (r Rating) IsError() bool {
+	return r == RatingError
+}
+
+// Quality ratings are the tiers, the pass and the rejection
+func (r Rating) IsValid() bool {
+	return r >= RatingRejected && r <= RatingAccepted
+}
+
+// Tiers grade thermometers, from precise to ultra precise
+func (r Rating) IsTier() bool {
+	return r >= RatingPrecise && r <= RatingUltraPrecise
+}
+
+func (r Rating) IsPass() bool {
+	return r.IsValid() && r != RatingRejected
+}
+
+/**
+ * Whether a rating is as good as another one: any quality rating is at least a
+ * rejection, otherwise both must be tiers or both the pass
+ */
+func (r Rating) IsAtLeast(other Rating) bool {
+	switch {
+	case !r.IsValid() || !other.IsValid():
+		return false
+	case other == RatingRejected:
+		return true
+	case r == RatingRejected || r.IsTier() != other.IsTier():
+		return false
+	}
+
+	return r >= other
+}
+
+/**
+ * Worst of several ratings: an error or an unknown rating wins over any quality
+ * rating, then a rejection, then the worst tier. A pass is only the worst when all
+ * the ratings are passes
+ */
+func WorstRating(ratings ...Rating) Rating {
+	if len(ratings) == 0 {
+		return RatingUnknown
+	}
+
+	unknown, rejected := false, false
+	tier := RatingUnknown
+	for _, rating := range ratings {
+		switch {
+		case rating.IsError():
+			return RatingError
+		case !rating.IsValid():
+			unknown = true
+		case rating == RatingRejected:
+			rejected = true
+		case rating.IsTier() && (tier == RatingUnknown || rating < tier):
+			tier = rating
+		}
+	}
+
+	switch {
+	case unknown:
+		return RatingUnknown
+	case rejected:
+		return RatingRejected
+	case tier != RatingUnknown:
+		return tier
+	}
+
+	return RatingAccepted
+}
+
+func ParseRating(name string) (Rating, error) {
+	for rating, ratingName := range ratingNames {
+		if ratingName == name {
+			return rating, nil
+		}
+	}
+
+	return RatingUnknown, errors.New(Translate("Unknown rating %s", name))
+}
+
+func (r Rating) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+func (r *Rating) UnmarshalText(text []byte) error {
+	rating, err := ParseRating(string(text))
+	if err != nil {
+		return err
+	}
+
+	*r = rating
+	return nil
+}
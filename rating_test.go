@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRatingString(t *testing.T) {
+	assert.Equal(t, "ultra precise", RatingUltraPrecise.String())
+	assert.Equal(t, "very precise", RatingVeryPrecise.String())
+	assert.Equal(t, "precise", RatingPrecise.String())
+	assert.Equal(t, "accepted", RatingAccepted.String())
+	assert.Equal(t, "rejected", RatingRejected.String())
+	assert.Equal(t, "error", RatingError.String())
+	assert.Equal(t, "unknown", RatingUnknown.String())
+	assert.Equal(t, "unknown", Rating(42).String())
+}
+
+func TestRatingIsPass(t *testing.T) {
+	assert.True(t, RatingUltraPrecise.IsPass())
+	assert.True(t, RatingPrecise.IsPass())
+	assert.True(t, RatingAccepted.IsPass())
+	assert.False(t, RatingRejected.IsPass())
+	assert.False(t, RatingError.IsPass())
+	assert.False(t, RatingUnknown.IsPass())
+}
+
+func TestRatingIsAtLeast(t *testing.T) {
+	assert.True(t, RatingUltraPrecise.IsAtLeast(RatingVeryPrecise))
+	assert.True(t, RatingVeryPrecise.IsAtLeast(RatingVeryPrecise))
+	assert.False(t, RatingPrecise.IsAtLeast(RatingVeryPrecise))
+	assert.False(t, RatingRejected.IsAtLeast(RatingPrecise))
+	// Errors aren't part of the ordering
+	assert.False(t, RatingError.IsAtLeast(RatingRejected))
+	assert.False(t, RatingPrecise.IsAtLeast(RatingError))
+}
+
+func TestRatingIsAtLeast_AcrossFamilies(t *testing.T) {
+	// A pass doesn't compare with the tiers of thermometers
+	assert.False(t, RatingAccepted.IsAtLeast(RatingUltraPrecise))
+	assert.False(t, RatingAccepted.IsAtLeast(RatingPrecise))
+	assert.False(t, RatingPrecise.IsAtLeast(RatingAccepted))
+	assert.True(t, RatingAccepted.IsAtLeast(RatingAccepted))
+	// Both are above a rejection
+	assert.True(t, RatingAccepted.IsAtLeast(RatingRejected))
+	assert.True(t, RatingPrecise.IsAtLeast(RatingRejected))
+	assert.False(t, RatingRejected.IsAtLeast(RatingAccepted))
+}
+
+func TestWorstRating(t *testing.T) {
+	assert.Equal(t, RatingVeryPrecise, WorstRating(RatingUltraPrecise, RatingVeryPrecise, RatingAccepted))
+	assert.Equal(t, RatingUltraPrecise, WorstRating(RatingUltraPrecise, RatingAccepted))
+	assert.Equal(t, RatingRejected, WorstRating(RatingUltraPrecise, RatingRejected))
+	assert.Equal(t, RatingError, WorstRating(RatingRejected, RatingError, RatingUnknown))
+	assert.Equal(t, RatingUnknown, WorstRating(RatingRejected, RatingUnknown))
+	assert.Equal(t, RatingUnknown, WorstRating())
+	assert.Equal(t, RatingAccepted, WorstRating(RatingAccepted, RatingAccepted))
+	assert.Equal(t, RatingPrecise, WorstRating(RatingAccepted, RatingPrecise))
+	assert.Equal(t, RatingRejected, WorstRating(RatingAccepted, RatingRejected))
+}
+
+func TestParseRating(t *testing.T) {
+	res, err := ParseRating("very precise")
+
+	assert.Nil(t, err)
+	assert.Equal(t, RatingVeryPrecise, res)
+
+	_, err = ParseRating("potato")
+
+	assert.NotNil(t, err)
+	assert.Equal(t, "Unknown rating potato", err.Error())
+}
+
+func TestRatingJSONMarshalling(t *testing.T) {
+	data, err := json.Marshal(map[string]Rating{"temp-1": RatingUltraPrecise})
+
+	assert.Nil(t, err)
+	assert.Equal(t, `{"temp-1":"ultra precise"}`, string(data))
+
+	var res map[string]Rating
+	err = json.Unmarshal(data, &res)
+
+	assert.Nil(t, err)
+	assert.Equal(t, RatingUltraPrecise, res["temp-1"])
+
+	err = json.Unmarshal([]byte(`{"temp-1":"potato"}`), &res)
+
+	assert.NotNil(t, err)
+}
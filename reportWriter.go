@@ -0,0 +1,225 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+/**
+ * Report formats
+ *   Results can be written as the ratings printed by analyze, the detailed report
+ *   printed by report, or as JSON for other tools.
+ */
+const TextReport = "text"
+const DetailedReport = "report"
+const JSONReport = "json"
+
+var ReportFormats = []string{TextReport, DetailedReport, JSONReport}
+
+// Extension of the report files written next to a log
+var reportExtensions = map[string]string{
+	TextReport:     ".results.txt",
+	DetailedReport: ".report.txt",
+	JSONReport:     ".report.json",
+}
+
+type ReportDocument struct {
+	Reference ReportReference `json:"reference"`
+	Sensors   []ReportSensor  `json:"sensors"`
+	// Records of the log which were discarded
+	Diagnostics []Diagnostic `json:"diagnostics"`
+	// Settings the log was read and graded with
+	Metadata RunMetadata `json:"metadata"`
+}
+
+type ReportReference struct {
+	Temperature float64  `json:"temperature"`
+	Humidity    float64  `json:"humidity"`
+	Pressure    *float64 `json:"pressure,omitempty"`
+	CO2         *float64 `json:"co2,omitempty"`
+	// Standard uncertainties of the reference instruments, when they were given
+	TemperatureUncertainty *float64 `json:"temperature_uncertainty,omitempty"`
+	HumidityUncertainty    *float64 `json:"humidity_uncertainty,omitempty"`
+	PressureUncertainty    *float64 `json:"pressure_uncertainty,omitempty"`
+	CO2Uncertainty         *float64 `json:"co2_uncertainty,omitempty"`
+	DewPoint               *float64 `json:"dew_point"`
+}
+
+// Statistics which can't be computed (standard deviation of a single reading) are null
+type ReportSensor struct {
+	Name                string   `json:"name"`
+	Type                string   `json:"type"`
+	Rating              Rating   `json:"rating"`
+	Label               string   `json:"label,omitempty"`
+	Error               string   `json:"error,omitempty"`
+	Readings            int      `json:"readings"`
+	Average             *float64 `json:"average"`
+	StandardDeviation   *float64 `json:"standard_deviation"`
+	ExpandedUncertainty *float64 `json:"expanded_uncertainty"`
+	// Statistics of each channel of combo sensors, the ones above being of the temperature channel
+	Channels []ReportChannel `json:"channels,omitempty"`
+	// Average dew point of the readings of combo sensors, and its deviation from the dew point of the reference
+	DewPoint          *float64 `json:"dew_point,omitempty"`
+	DewPointDeviation *float64 `json:"dew_point_deviation,omitempty"`
+}
+
+type ReportChannel struct {
+	Channel             string   `json:"channel"`
+	Readings            int      `json:"readings"`
+	Average             *float64 `json:"average"`
+	StandardDeviation   *float64 `json:"standard_deviation"`
+	ExpandedUncertainty *float64 `json:"expanded_uncertainty"`
+}
+
+func ParseReportFormats(spec string) ([]string, error) {
+	var formats []string
+	for _, format := range strings.Split(spec, ",") {
+		format = strings.TrimSpace(format)
+		if _, found := reportExtensions[format]; !found {
+			return nil, errors.New(Translate("Unknown report format %s, expected %s", format, strings.Join(ReportFormats, ", ")))
+		}
+		formats = append(formats, format)
+	}
+
+	return formats, nil
+}
+
+func GetReportExtension(format string) string {
+	return reportExtensions[format]
+}
+
+/**
+ * Writing the results of graded sensors, and the diagnostics of their log, in the
+ * given report format. Only JSON reports carry the run metadata
+ */
+func WriteReport(w io.Writer, format string, sensors []SensorInterface, ref ReferenceInterface, diagnostics []Diagnostic, metadata RunMetadata, labels RatingLabels) error {
+	switch format {
+	case TextReport:
+		PrintResults(w, sensors, labels)
+		PrintDiagnostics(w, diagnostics)
+		return nil
+	case DetailedReport:
+		PrintReport(w, sensors, ref, labels)
+		PrintDiagnostics(w, diagnostics)
+		return nil
+	case JSONReport:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(NewReportDocument(sensors, ref, diagnostics, metadata, labels))
+	}
+
+	return errors.New(Translate("Unknown report format %s, expected %s", format, strings.Join(ReportFormats, ", ")))
+}
+
+func NewReportDocument(sensors []SensorInterface, ref ReferenceInterface, diagnostics []Diagnostic, metadata RunMetadata, labels RatingLabels) ReportDocument {
+	document := ReportDocument{
+		Reference:   NewReportReference(ref),
+		Sensors:     make([]ReportSensor, 0, len(sensors)),
+		Diagnostics: append([]Diagnostic{}, diagnostics...),
+		Metadata:    metadata,
+	}
+
+	for _, sensor := range sensors {
+		result := ReportSensor{
+			Name:              sensor.GetName(),
+			Type:              sensor.GetType(),
+			Rating:            sensor.GetRating(),
+			Readings:          len(sensor.GetValues()),
+			Average:           reportNumber(sensor.GetAverageValue()),
+			StandardDeviation: reportStandardDeviation(len(sensor.GetValues()), sensor.GetStandardDeviation()),
+		}
+
+		if sensor.GetRating().IsError() {
+			result.Error = fmt.Sprint(sensor.GetRatingError())
+		} else {
+			result.Label = labels.Label(sensor.GetRating())
+			result.ExpandedUncertainty = reportNumber(sensor.GetUncertaintyBudget(ref).Expanded)
+		}
+
+		if combo, isCombo := sensor.(*CombinedSensor); isCombo {
+			result.Channels = []ReportChannel{
+				newReportChannel(TemperatureChannel, combo.GetTemperatureChannel(), ref),
+				newReportChannel(HumidityChannel, combo.GetHumidityChannel(), ref),
+			}
+			result.DewPoint = reportNumber(combo.GetAverageDewPoint())
+			result.DewPointDeviation = reportNumber(combo.GetDewPointDeviation(ref))
+		}
+
+		document.Sensors = append(document.Sensors, result)
+	}
+
+	return document
+}
+
+/**
+ * Listing the discarded records after the results, nothing is written when there
+ * are none
+ */
+func PrintDiagnostics(w io.Writer, diagnostics []Diagnostic) {
+	if len(diagnostics) == 0 {
+		return
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, Translate("Diagnostics:"))
+	for _, diagnostic := range diagnostics {
+		fmt.Fprintf(w, "  %s\n", diagnostic)
+	}
+}
+
+func newReportChannel(channel string, sensor SensorInterface, ref ReferenceInterface) ReportChannel {
+	return ReportChannel{
+		Channel:             channel,
+		Readings:            len(sensor.GetValues()),
+		Average:             reportNumber(sensor.GetAverageValue()),
+		StandardDeviation:   reportStandardDeviation(len(sensor.GetValues()), sensor.GetStandardDeviation()),
+		ExpandedUncertainty: reportNumber(sensor.GetUncertaintyBudget(ref).Expanded),
+	}
+}
+
+func NewReportReference(ref ReferenceInterface) ReportReference {
+	reference := ReportReference{
+		Temperature: ref.GetRefTemperature(),
+		Humidity:    ref.GetRefHumidity(),
+		DewPoint:    reportNumber(ref.GetRefDewPoint()),
+	}
+
+	if ref.HasRefPressure() {
+		reference.Pressure = reportNumber(ref.GetRefPressure())
+		reference.PressureUncertainty = reportNumber(ref.GetRefPressureUncertainty())
+	}
+	if ref.HasRefCO2() {
+		reference.CO2 = reportNumber(ref.GetRefCO2())
+		reference.CO2Uncertainty = reportNumber(ref.GetRefCO2Uncertainty())
+	}
+	if u := ref.GetRefTemperatureUncertainty(); u > 0 {
+		reference.TemperatureUncertainty = reportNumber(u)
+	}
+	if u := ref.GetRefHumidityUncertainty(); u > 0 {
+		reference.HumidityUncertainty = reportNumber(u)
+	}
+
+	return reference
+}
+
+// JSON has no NaN nor infinities
+func reportNumber(value float64) *float64 {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return nil
+	}
+
+	return &value
+}
+
+// The standard deviation isn't known until there are 2 readings
+func reportStandardDeviation(readings int, value float64) *float64 {
+	if readings < 2 {
+		return nil
+	}
+
+	return reportNumber(value)
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseReportFormats(t *testing.T) {
+	formats, err := ParseReportFormats("text, json")
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{TextReport, JSONReport}, formats)
+
+	_, err = ParseReportFormats("text,pdf")
+	assert.EqualError(t, err, "Unknown report format pdf, expected text, report, json")
+}
+
+func TestWriteReport_JSON(t *testing.T) {
+	ref, sensors, _, _, err := GradeLog(strings.Split(cliLog+"thermometer temp-2\n2007-04-05T22:00 temp-2 70.0\n", "\n"), LegacyFormat, DefaultCSVColumnMapping, nil)
+	assert.Nil(t, err)
+
+	var out bytes.Buffer
+	assert.Nil(t, WriteReport(&out, JSONReport, sensors, ref, nil, NewRunMetadata(SpecProfile), outputProfiles[DefaultProfile]))
+
+	var document ReportDocument
+	assert.Nil(t, json.Unmarshal(out.Bytes(), &document))
+	assert.Equal(t, 70.0, document.Reference.Temperature)
+	assert.Nil(t, document.Reference.Pressure)
+	assert.Len(t, document.Sensors, 3)
+
+	assert.Equal(t, "temp-1", document.Sensors[0].Name)
+	assert.Equal(t, RatingUltraPrecise, document.Sensors[0].Rating)
+	assert.Equal(t, 2, document.Sensors[0].Readings)
+	assert.InDelta(t, 70.0, *document.Sensors[0].Average, 1e-9)
+
+	// The standard deviation of a single reading isn't known, it's null
+	assert.Equal(t, "temp-2", document.Sensors[2].Name)
+	assert.Nil(t, document.Sensors[2].StandardDeviation)
+	assert.Contains(t, out.String(), `"standard_deviation": null`)
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+/**
+ * Run metadata
+ *   Settings a run was graded with, so a report can be traced back to the way
+ *   the log was read and rendered.
+ */
+type RunMetadata struct {
+	StartedAt    time.Time    `json:"started_at"`
+	Locale       string       `json:"locale"`
+	Profile      string       `json:"profile"`
+	NumberFormat NumberFormat `json:"number_format"`
+	InputFormat  string       `json:"input_format,omitempty"`
+}
+
+func NewRunMetadata(profile string) RunMetadata {
+	return RunMetadata{
+		StartedAt:    time.Now(),
+		Locale:       GetLocale(),
+		Profile:      profile,
+		NumberFormat: GetInputNumberFormat(),
+	}
+}
+
+func (rm RunMetadata) Print(w io.Writer) {
+	fmt.Fprintln(w, Translate("Run started at %s", rm.StartedAt.Format(time.RFC3339)))
+	fmt.Fprintln(w, Translate("Language: %s | Output profile: %s", rm.Locale, rm.Profile))
+	fmt.Fprintln(w, Translate("Input number format: %s", rm.NumberFormat))
+	if rm.InputFormat != "" {
+		fmt.Fprintln(w, Translate("Input format: %s", rm.InputFormat))
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"math"
+)
+
+/**
+ * Running statistics
+ *   Long runs are followed reading by reading: the count, mean and standard deviation
+ *   are updated in constant time with Welford's algorithm instead of going through
+ *   every reading again.
+ */
+type RunningStats struct {
+	count int
+	mean  float64
+	// Sum of the squared differences from the mean
+	m2  float64
+	min float64
+	max float64
+}
+
+func (rs *RunningStats) Add(value float64) {
+	rs.count++
+	if rs.count == 1 {
+		rs.min, rs.max = value, value
+	}
+	rs.min = math.Min(rs.min, value)
+	rs.max = math.Max(rs.max, value)
+
+	delta := value - rs.mean
+	rs.mean += delta / float64(rs.count)
+	rs.m2 += delta * (value - rs.mean)
+}
+
+func (rs *RunningStats) GetCount() int {
+	return rs.count
+}
+
+func (rs *RunningStats) GetMean() float64 {
+	return rs.mean
+}
+
+func (rs *RunningStats) GetMin() float64 {
+	return rs.min
+}
+
+func (rs *RunningStats) GetMax() float64 {
+	return rs.max
+}
+
+// Sample standard deviation, it's 0 until there are 2 readings
+func (rs *RunningStats) GetStandardDeviation() float64 {
+	if rs.count < 2 {
+		return 0
+	}
+
+	return math.Sqrt(rs.m2 / float64(rs.count-1))
+}
+
+/**
+ * Lowest standard deviation the readings can end with once there are totalCount
+ * of them: whatever the readings still to come, the sum of the squared differences
+ * from the mean can't decrease
+ */
+func (rs *RunningStats) GetMinFinalStandardDeviation(totalCount int) float64 {
+	if totalCount <= rs.count {
+		return rs.GetStandardDeviation()
+	}
+
+	return math.Sqrt(rs.m2 / float64(totalCount-1))
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunningStats_MatchesSensor(t *testing.T) {
+	values := []float64{69.5, 70.1, 71.3, 70.7, 69.9, 70.4}
+	sensor := newSingleSensor(Thermometer, "temp-1")
+	stats := &RunningStats{}
+
+	for _, value := range values {
+		sensor.appendValue(value)
+		stats.Add(value)
+	}
+
+	assert.Equal(t, len(values), stats.GetCount())
+	assert.InDelta(t, sensor.GetAverageValue(), stats.GetMean(), 1e-9)
+	assert.InDelta(t, sensor.GetStandardDeviation(), stats.GetStandardDeviation(), 1e-9)
+	assert.Equal(t, 69.5, stats.GetMin())
+	assert.Equal(t, 71.3, stats.GetMax())
+}
+
+func TestRunningStats_Empty(t *testing.T) {
+	stats := &RunningStats{}
+
+	assert.Equal(t, 0, stats.GetCount())
+	assert.Equal(t, 0.0, stats.GetStandardDeviation())
+}
+
+func TestRunningStats_SingleReading(t *testing.T) {
+	stats := &RunningStats{}
+	stats.Add(70.1)
+
+	assert.Equal(t, 70.1, stats.GetMean())
+	assert.Equal(t, 0.0, stats.GetStandardDeviation())
+}
+
+func TestRunningStats_GetMinFinalStandardDeviation(t *testing.T) {
+	stats := &RunningStats{}
+	for _, value := range []float64{60, 80, 60, 80} {
+		stats.Add(value)
+	}
+
+	// m2 is 400: spread over 101 readings, the standard deviation can't go below 2
+	assert.InDelta(t, 2.0, stats.GetMinFinalStandardDeviation(101), 1e-9)
+	assert.Equal(t, stats.GetStandardDeviation(), stats.GetMinFinalStandardDeviation(4))
+	assert.Equal(t, stats.GetStandardDeviation(), stats.GetMinFinalStandardDeviation(2))
+	assert.False(t, math.IsNaN(stats.GetMinFinalStandardDeviation(0)))
+}
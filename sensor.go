@@ -3,38 +3,89 @@ package main
 import (
 	"errors"
 	"math"
-	"strconv"
 	"strings"
 )
 
 // Valid devices
 const Thermometer = "thermometer"
 const HumiditySensor = "humidity"
+const ComboSensor = "combo"
+const PressureSensor = "pressure"
+const CO2Sensor = "co2"
+
+// Optional quantities of the reference line, besides the pressure and the CO2
+// concentration: standard uncertainties of the reference instruments
+const RefTemperatureUncertaintyKey = "temperature_uncertainty"
+const RefHumidityUncertaintyKey = "humidity_uncertainty"
+const RefPressureUncertaintyKey = "pressure_uncertainty"
+const RefCO2UncertaintyKey = "co2_uncertainty"
+
+var RefQuantities = []string{PressureSensor, CO2Sensor, RefTemperatureUncertaintyKey, RefHumidityUncertaintyKey, RefPressureUncertaintyKey, RefCO2UncertaintyKey}
 
 // Ratings
-const ThermometerUltraPrecise = "ultra precise"
-const ThermometerVeryPrecise = "very precise"
-const ThermometerPrecise = "precise"
-const HumidityAccepted = "accepted"
-const HumidityRejected = "rejected"
+const ThermometerUltraPrecise = RatingUltraPrecise
+const ThermometerVeryPrecise = RatingVeryPrecise
+const ThermometerPrecise = RatingPrecise
+const HumidityAccepted = RatingAccepted
+const HumidityRejected = RatingRejected
+const PressureAccepted = RatingAccepted
+const PressureRejected = RatingRejected
+const CO2Accepted = RatingAccepted
+const CO2Rejected = RatingRejected
 
 // Control Values
 const ThermometerAvgRange = 0.5
 const ThermometerUltraPreciseSD = 3
 const ThermometerVeryPreciseSD = 5
 const HumidityAcceptedRange = 0.01
+const PressureAcceptedRange = 1.0       // hPa
+const CO2AcceptedRange = 50.0           // ppm
+const CO2AcceptedRangePercentage = 0.03 // of the reference value
+
+// Magnus formula coefficients for dew point (valid from -45°C to 60°C)
+const DewPointMagnusA = 17.62
+const DewPointMagnusB = 243.12
 
-/** Defining reference Temperature and Humidity **/
+/** Defining reference Temperature, Humidity and optional Pressure and CO2 **/
 type ReferenceInterface interface {
 	GetRefHumidity() float64
 	GetRefTemperature() float64
 	SetRefHumidity(hum float64)
 	SetRefTemperature(tmp float64)
+	GetRefPressure() float64
+	SetRefPressure(pressure float64)
+	HasRefPressure() bool
+	GetRefCO2() float64
+	SetRefCO2(co2 float64)
+	HasRefCO2() bool
+	GetRefDewPoint() float64
+	GetRefHumidityUncertainty() float64
+	GetRefTemperatureUncertainty() float64
+	SetRefHumidityUncertainty(u float64)
+	SetRefTemperatureUncertainty(u float64)
+	GetRefPressureUncertainty() float64
+	SetRefPressureUncertainty(u float64)
+	GetRefCO2Uncertainty() float64
+	SetRefCO2Uncertainty(u float64)
 }
 
 type RefTemperatureHumidity struct {
 	refTemperature float64
 	refHumidity    float64
+
+	// Pressure (hPa) and CO2 (ppm) are only known when the test environment controls them
+	refPressure    float64
+	refCO2         float64
+	hasRefPressure bool
+	hasRefCO2      bool
+
+	// Standard uncertainties (k=1) of the reference values, as stated on the
+	// calibration certificate of the test environment
+	refTemperatureUncertainty float64
+	refHumidityUncertainty    float64
+	// Barometers and CO2 analyzers have a default uncertainty, see uncertainty.go
+	refPressureUncertainty *float64
+	refCO2Uncertainty      *float64
 }
 
 func NewRefTemperatureHumidity(temp float64, hum float64) ReferenceInterface {
@@ -60,33 +111,108 @@ func (rth *RefTemperatureHumidity) SetRefHumidity(hum float64) {
 	rth.refHumidity = hum
 }
 
+func (rth *RefTemperatureHumidity) GetRefPressure() float64 {
+	return rth.refPressure
+}
+
+func (rth *RefTemperatureHumidity) SetRefPressure(pressure float64) {
+	rth.refPressure = pressure
+	rth.hasRefPressure = true
+}
+
+func (rth *RefTemperatureHumidity) HasRefPressure() bool {
+	return rth.hasRefPressure
+}
+
+func (rth *RefTemperatureHumidity) GetRefCO2() float64 {
+	return rth.refCO2
+}
+
+func (rth *RefTemperatureHumidity) SetRefCO2(co2 float64) {
+	rth.refCO2 = co2
+	rth.hasRefCO2 = true
+}
+
+func (rth *RefTemperatureHumidity) HasRefCO2() bool {
+	return rth.hasRefCO2
+}
+
+func (rth *RefTemperatureHumidity) GetRefDewPoint() float64 {
+	return GetDewPoint(rth.refTemperature, rth.refHumidity)
+}
+
+func (rth *RefTemperatureHumidity) GetRefTemperatureUncertainty() float64 {
+	return rth.refTemperatureUncertainty
+}
+
+func (rth *RefTemperatureHumidity) SetRefTemperatureUncertainty(u float64) {
+	rth.refTemperatureUncertainty = u
+}
+
+func (rth *RefTemperatureHumidity) GetRefHumidityUncertainty() float64 {
+	return rth.refHumidityUncertainty
+}
+
+func (rth *RefTemperatureHumidity) SetRefHumidityUncertainty(u float64) {
+	rth.refHumidityUncertainty = u
+}
+
+func (rth *RefTemperatureHumidity) GetRefPressureUncertainty() float64 {
+	if rth.refPressureUncertainty == nil {
+		return PressureDefaultRefUncertainty
+	}
+	return *rth.refPressureUncertainty
+}
+
+func (rth *RefTemperatureHumidity) SetRefPressureUncertainty(u float64) {
+	rth.refPressureUncertainty = &u
+}
+
+func (rth *RefTemperatureHumidity) GetRefCO2Uncertainty() float64 {
+	if rth.refCO2Uncertainty == nil {
+		return CO2DefaultRefUncertainty
+	}
+	return *rth.refCO2Uncertainty
+}
+
+func (rth *RefTemperatureHumidity) SetRefCO2Uncertainty(u float64) {
+	rth.refCO2Uncertainty = &u
+}
+
 /**
  * Extracting reference values
+ *   reference <temperature> <humidity> [pressure=<hPa>] [co2=<ppm>]
+ *     [temperature_uncertainty=<°F>] [humidity_uncertainty=<%RH>]
+ *     [pressure_uncertainty=<hPa>] [co2_uncertainty=<ppm>]
  */
 func ExtractRef(refLine string) (ReferenceInterface, error) {
 	refTH := &RefTemperatureHumidity{}
 
+	ref, err := Tokenize(refLine)
+	if err != nil {
+		return refTH, err
+	}
+
 	// Make sure header has the right number of elements
-	ref := strings.Split(strings.TrimSpace(refLine), " ")
-	if len(ref) != 3 {
-		error := errors.New("Error while parsing the header: not enough elements")
+	if len(ref) < 3 {
+		error := errors.New(Translate("Error while parsing the header: not enough elements"))
 		return refTH, error
 	}
 
 	header := ref[0]
 	if header != "reference" {
-		error := errors.New("First line doesn't seem to contain the reference, stopping now")
+		error := errors.New(Translate("First line doesn't seem to contain the reference, stopping now"))
 		return refTH, error
 	}
 
 	// Make sure the ref. Temperature is set properly
-	temp, err := strconv.ParseFloat(ref[1], 64)
+	temp, err := ParseNumber(ref[1])
 	if err != nil {
 		return refTH, err
 	}
 
 	// Make sure the ref. Humidity is set properly
-	hum, err := strconv.ParseFloat(ref[2], 64)
+	hum, err := ParseNumber(ref[2])
 	if err != nil {
 		return refTH, err
 	}
@@ -94,9 +220,78 @@ func ExtractRef(refLine string) (ReferenceInterface, error) {
 	refTH.SetRefTemperature(temp)
 	refTH.SetRefHumidity(hum)
 
+	// Additional reference quantities are optional and given as key=value pairs
+	for _, quantity := range ref[3:] {
+		if err := extractOptionalRef(refTH, quantity); err != nil {
+			return refTH, err
+		}
+	}
+
 	return refTH, nil
 }
 
+func extractOptionalRef(refTH *RefTemperatureHumidity, quantity string) error {
+	keyValue := strings.SplitN(quantity, "=", 2)
+	if len(keyValue) != 2 {
+		return errors.New(Translate("Error while parsing the header: %s is not a key=value pair", quantity))
+	}
+
+	value, err := ParseNumber(keyValue[1])
+	if err != nil {
+		return err
+	}
+
+	return SetRefQuantity(refTH, keyValue[0], value)
+}
+
+/**
+ * Setting an optional quantity of the reference, shared by the log formats
+ */
+func SetRefQuantity(refTH *RefTemperatureHumidity, quantity string, value float64) error {
+	switch quantity {
+	case PressureSensor:
+		refTH.SetRefPressure(value)
+	case CO2Sensor:
+		refTH.SetRefCO2(value)
+	case RefTemperatureUncertaintyKey, RefHumidityUncertaintyKey, RefPressureUncertaintyKey, RefCO2UncertaintyKey:
+		if value < 0 {
+			return errors.New(Translate("The uncertainty of the reference can't be negative"))
+		}
+		switch quantity {
+		case RefTemperatureUncertaintyKey:
+			refTH.SetRefTemperatureUncertainty(value)
+		case RefHumidityUncertaintyKey:
+			refTH.SetRefHumidityUncertainty(value)
+		case RefPressureUncertaintyKey:
+			refTH.SetRefPressureUncertainty(value)
+		default:
+			refTH.SetRefCO2Uncertainty(value)
+		}
+	default:
+		return errors.New(Translate("Error while parsing the header: unknown reference quantity %s", quantity))
+	}
+
+	return nil
+}
+
+/**
+ * Dew point from a temperature (°F, as in the test environment logs) and a relative humidity (%),
+ * using the Magnus formula. The dew point is returned in °F, NaN when the air is dry:
+ * there's no dew point without humidity
+ */
+func GetDewPoint(temperature float64, humidity float64) float64 {
+	if humidity <= 0 {
+		return math.NaN()
+	}
+
+	celsius := (temperature - 32) * 5 / 9
+
+	gamma := math.Log(humidity/100) + DewPointMagnusA*celsius/(DewPointMagnusB+celsius)
+	dewPoint := DewPointMagnusB * gamma / (DewPointMagnusA - gamma)
+
+	return dewPoint*9/5 + 32
+}
+
 /** Defining sensors **/
 type SensorInterface interface {
 	AppendData(data []string) error
@@ -106,43 +301,70 @@ type SensorInterface interface {
 	GetAverageValue() float64
 	GetStandardDeviation() float64
 	GetMaxDeviationPercentage(refValue float64) float64
-	CalculateRating(ref ReferenceInterface) string
+	CalculateRating(ref ReferenceInterface) (Rating, error)
 	SetRating(ref ReferenceInterface)
-	GetRating() string
+	GetRating() Rating
+	GetRatingError() error
+	GetResolution() float64
+	SetResolution(resolution float64)
+	GetDrift() float64
+	SetDrift(drift float64)
+	GetUncertaintyBudget(ref ReferenceInterface) UncertaintyBudget
+	SetCorrection(correction CorrectionInterface)
 }
 
 type Sensor struct {
 	sensorType   string
 	sensorName   string
 	sensorValues []float64
-	sensorRating string
+	// Statistics of the readings, only the new readings are added to them
+	sensorStats      RunningStats
+	sensorRating     Rating
+	sensorError      error
+	sensorResolution float64
+	sensorDrift      float64
+	sensorCorrection CorrectionInterface
 }
 
 func NewSensor(sType string, sName string) SensorInterface {
+	if sType == ComboSensor {
+		return NewCombinedSensor(sName)
+	}
+
+	return newSingleSensor(sType, sName)
+}
+
+func newSingleSensor(sType string, sName string) *Sensor {
+	resolution, drift := getDefaultResolutionAndDrift(sType)
+
 	return &Sensor{
-		sensorType:   sType,
-		sensorName:   sName,
-		sensorValues: nil,
-		sensorRating: "",
+		sensorType:       sType,
+		sensorName:       sName,
+		sensorValues:     nil,
+		sensorRating:     RatingUnknown,
+		sensorResolution: resolution,
+		sensorDrift:      drift,
 	}
 }
 
 func (s *Sensor) AppendData(data []string) error {
 	// input param is composed of 3 elements: date, sensor name and value recorded
+	if len(data) != 3 {
+		return errors.New(Translate("Data doesn't have the expected number of elements for device %s", s.sensorName))
+	}
 
 	// Here we assume that we're dealing with 1 sensor in particular
 	// If the data corresponds to another sensor, we simply discard the line
 	if data[1] != s.sensorName {
-		return errors.New("Data is not for the right sensor")
+		return errors.New(Translate("Data is not for the right sensor"))
 	}
 
-	value, err := strconv.ParseFloat(data[2], 64)
+	value, err := s.parseValue(data[2])
 	if err != nil {
-		errorMsg := "Error while parsing the recorded measure for devide " + s.sensorName + " :" + err.Error()
-		return errors.New(errorMsg)
+		return err
 	}
 
-	s.sensorValues = append(s.sensorValues, value)
+	s.appendValue(value)
 	return nil
 }
 
@@ -159,83 +381,121 @@ func (s *Sensor) GetValues() []float64 {
 }
 
 func (s *Sensor) GetAverageValue() float64 {
-	var sum float64
-	nbrValues := len(s.sensorValues)
-
-	if nbrValues == 0 {
-		return float64(0)
-	}
-
-	for i := 0; i < nbrValues; i++ {
-		sum += s.sensorValues[i]
-	}
-
-	return sum / float64(nbrValues)
+	return s.GetStats().GetMean()
 }
 
 func (s *Sensor) GetStandardDeviation() float64 {
-	var sd float64
-
-	avg := s.GetAverageValue()
-	nbrValues := len(s.sensorValues)
-
-	if nbrValues == 0 {
-		return float64(0)
-	}
+	// We're using the Standard Deviation formula for samples and not population
+	// Indeed, we're testing random sensors, not all of them
+	return s.GetStats().GetStandardDeviation()
+}
 
-	for i := 0; i < nbrValues; i++ {
-		sd += math.Pow(s.sensorValues[i]-avg, 2)
+/**
+ * Statistics of the readings. They're updated with the readings appended since
+ * they were last asked for, so following a long run doesn't go through every
+ * reading again
+ */
+func (s *Sensor) GetStats() *RunningStats {
+	for _, value := range s.sensorValues[s.sensorStats.GetCount():] {
+		s.sensorStats.Add(value)
 	}
 
-	// We're using the Standard Deviation formula for samples and not population
-	// Indeed, we're testing random sensors, not all of them
-	return math.Sqrt(sd / float64(nbrValues-1))
+	return &s.sensorStats
 }
 
 func (s *Sensor) GetMaxDeviationPercentage(refValue float64) float64 {
-	maxDeviation := float64(0)
-
-	nbrValues := len(s.sensorValues)
-	if nbrValues == 0 {
+	if len(s.sensorValues) == 0 {
 		return float64(0)
 	}
 
-	for i := 0; i < nbrValues; i++ {
-		deviation := getDeviation(refValue, s.sensorValues[i]) / refValue
-		if deviation > maxDeviation {
-			maxDeviation = deviation
-		}
-	}
-
-	return maxDeviation
+	return s.getMaxDeviation(refValue) / refValue
 }
 
-func (s *Sensor) CalculateRating(ref ReferenceInterface) string {
+func (s *Sensor) CalculateRating(ref ReferenceInterface) (Rating, error) {
 	// Reject invalid sensor types
 	if !s.isValidSensorType() {
-		return "Invalid sensor type for type " + s.sensorType + ". Checking next sensor"
+		return RatingError, errors.New(Translate("Invalid sensor type for type %s. Checking next sensor", s.sensorType))
 	}
 
-	if s.sensorType == Thermometer {
-		return s.getThermometerRating(ref.GetRefTemperature())
+	switch s.sensorType {
+	case Thermometer:
+		return s.getThermometerRating(ref.GetRefTemperature()), nil
+	case PressureSensor:
+		if !ref.HasRefPressure() {
+			return RatingError, errors.New(Translate("Missing pressure reference for device %s. Checking next sensor", s.sensorName))
+		}
+		return s.getPressureSensorRating(ref.GetRefPressure()), nil
+	case CO2Sensor:
+		if !ref.HasRefCO2() {
+			return RatingError, errors.New(Translate("Missing CO2 reference for device %s. Checking next sensor", s.sensorName))
+		}
+		return s.getCO2SensorRating(ref.GetRefCO2()), nil
 	}
 
-	return s.getHumiditySensorRating(ref.GetRefHumidity())
+	return s.getHumiditySensorRating(ref.GetRefHumidity()), nil
 }
 
 func (s *Sensor) SetRating(ref ReferenceInterface) {
-	s.sensorRating = s.CalculateRating(ref)
+	s.sensorRating, s.sensorError = s.CalculateRating(ref)
 }
 
-func (s *Sensor) GetRating() string {
+func (s *Sensor) GetRating() Rating {
 	return s.sensorRating
 }
 
+func (s *Sensor) GetRatingError() error {
+	return s.sensorError
+}
+
+func (s *Sensor) GetResolution() float64 {
+	return s.sensorResolution
+}
+
+func (s *Sensor) SetResolution(resolution float64) {
+	s.sensorResolution = resolution
+}
+
+func (s *Sensor) GetDrift() float64 {
+	return s.sensorDrift
+}
+
+func (s *Sensor) SetDrift(drift float64) {
+	s.sensorDrift = drift
+}
+
+func (s *Sensor) SetCorrection(correction CorrectionInterface) {
+	s.sensorCorrection = correction
+}
+
+func (s *Sensor) parseValue(rawValue string) (float64, error) {
+	value, err := ParseNumber(rawValue)
+	if err != nil {
+		errorMsg := Translate("Error while parsing the recorded measure for devide %s :%s", s.sensorName, err.Error())
+		return 0, errors.New(errorMsg)
+	}
+
+	return value, nil
+}
+
+func (s *Sensor) appendValue(value float64) {
+	// Calibrated units: statistics are computed on corrected readings
+	if s.sensorCorrection != nil {
+		value = s.sensorCorrection.Apply(value)
+	}
+
+	s.sensorValues = append(s.sensorValues, value)
+}
+
 func (s *Sensor) isValidSensorType() bool {
-	return s.sensorType == Thermometer || s.sensorType == HumiditySensor
+	switch s.sensorType {
+	case Thermometer, HumiditySensor, PressureSensor, CO2Sensor:
+		return true
+	}
+
+	return false
 }
 
-func (s *Sensor) getThermometerRating(refTemperature float64) string {
+func (s *Sensor) getThermometerRating(refTemperature float64) Rating {
 	// We want the average temperature to be lower than 0.5 degrees from ref
 
 	deviation := getDeviation(refTemperature, s.GetAverageValue())
@@ -244,18 +504,23 @@ func (s *Sensor) getThermometerRating(refTemperature float64) string {
 	}
 
 	// We also need to check the Standard Deviation
-	if s.GetStandardDeviation() <= float64(ThermometerUltraPreciseSD) {
+	return getThermometerTier(s.GetStandardDeviation())
+}
+
+// Best thermometer rating readings with this standard deviation can get
+func getThermometerTier(sd float64) Rating {
+	if sd <= float64(ThermometerUltraPreciseSD) {
 		return ThermometerUltraPrecise
 	}
 
-	if s.GetStandardDeviation() <= float64(ThermometerVeryPreciseSD) {
+	if sd <= float64(ThermometerVeryPreciseSD) {
 		return ThermometerVeryPrecise
 	}
 
 	return ThermometerPrecise
 }
 
-func (s *Sensor) getHumiditySensorRating(refHumidity float64) string {
+func (s *Sensor) getHumiditySensorRating(refHumidity float64) Rating {
 	// For Humidity sensors, we only care about the readings accuracy
 
 	maxDeviation := s.GetMaxDeviationPercentage(refHumidity)
@@ -266,6 +531,35 @@ func (s *Sensor) getHumiditySensorRating(refHumidity float64) string {
 	return HumidityRejected
 }
 
+func (s *Sensor) getPressureSensorRating(refPressure float64) Rating {
+	// All readings must be within a fixed range of the reference pressure
+	if s.getMaxDeviation(refPressure) <= PressureAcceptedRange {
+		return PressureAccepted
+	}
+
+	return PressureRejected
+}
+
+func (s *Sensor) getCO2SensorRating(refCO2 float64) Rating {
+	// CO2 sensors are usually specified as ±(fixed range + percentage of the reading)
+	acceptedRange := CO2AcceptedRange + CO2AcceptedRangePercentage*refCO2
+	if s.getMaxDeviation(refCO2) <= acceptedRange {
+		return CO2Accepted
+	}
+
+	return CO2Rejected
+}
+
+// The readings furthest from the reference are the lowest or the highest one
+func (s *Sensor) getMaxDeviation(refValue float64) float64 {
+	stats := s.GetStats()
+	if stats.GetCount() == 0 {
+		return float64(0)
+	}
+
+	return math.Max(getDeviation(refValue, stats.GetMin()), getDeviation(refValue, stats.GetMax()))
+}
+
 // Additional helper
 func getDeviation(refValue float64, value float64) float64 {
 	return math.Abs(refValue - value)
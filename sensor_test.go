@@ -1,6 +1,7 @@
 package main
 
 import (
+	"math"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -32,6 +33,21 @@ func TestRefTemperatureHumiditySetters_HappyPath(t *testing.T) {
 	assert.Equal(t, expectedHumidity, res.GetRefHumidity())
 }
 
+func TestRefTemperatureHumidityUncertaintySetters_HappyPath(t *testing.T) {
+	expectedTemperatureUncertainty := 0.05
+	expectedHumidityUncertainty := 0.3
+
+	res := NewRefTemperatureHumidity(70.0, 45.0)
+	assert.Equal(t, float64(0), res.GetRefTemperatureUncertainty())
+	assert.Equal(t, float64(0), res.GetRefHumidityUncertainty())
+
+	res.SetRefTemperatureUncertainty(expectedTemperatureUncertainty)
+	res.SetRefHumidityUncertainty(expectedHumidityUncertainty)
+
+	assert.Equal(t, expectedTemperatureUncertainty, res.GetRefTemperatureUncertainty())
+	assert.Equal(t, expectedHumidityUncertainty, res.GetRefHumidityUncertainty())
+}
+
 func TestExtractRefValues_HappyPath(t *testing.T) {
 	line := "reference 70.0 45.0"
 
@@ -61,6 +77,69 @@ func TestExtractRefValues_NotAReference(t *testing.T) {
 	assert.Equal(t, "First line doesn't seem to contain the reference, stopping now", err.Error())
 }
 
+func TestExtractRefValues_OptionalPressureAndCO2(t *testing.T) {
+	line := "reference 70.0 45.0 pressure=1013.25 co2=400"
+
+	res, err := ExtractRef(line)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 70.0, res.GetRefTemperature())
+	assert.Equal(t, 45.0, res.GetRefHumidity())
+	assert.True(t, res.HasRefPressure())
+	assert.Equal(t, 1013.25, res.GetRefPressure())
+	assert.True(t, res.HasRefCO2())
+	assert.Equal(t, 400.0, res.GetRefCO2())
+}
+
+func TestExtractRefValues_Uncertainties(t *testing.T) {
+	res, err := ExtractRef("reference 70.0 45.0 temperature_uncertainty=0.05 humidity_uncertainty=0.8")
+
+	assert.Nil(t, err)
+	assert.Equal(t, 0.05, res.GetRefTemperatureUncertainty())
+	assert.Equal(t, 0.8, res.GetRefHumidityUncertainty())
+
+	_, err = ExtractRef("reference 70.0 45.0 humidity_uncertainty=-1")
+	assert.EqualError(t, err, "The uncertainty of the reference can't be negative")
+}
+
+func TestExtractRefValues_NoOptionalReference(t *testing.T) {
+	res, err := ExtractRef("reference 70.0 45.0")
+
+	assert.Nil(t, err)
+	assert.False(t, res.HasRefPressure())
+	assert.False(t, res.HasRefCO2())
+}
+
+func TestExtractRefValues_OptionalReferenceNotAKeyValuePair(t *testing.T) {
+	_, err := ExtractRef("reference 70.0 45.0 1013.25")
+
+	assert.NotNil(t, err)
+	assert.Equal(t, "Error while parsing the header: 1013.25 is not a key=value pair", err.Error())
+}
+
+func TestExtractRefValues_UnknownOptionalReference(t *testing.T) {
+	_, err := ExtractRef("reference 70.0 45.0 potato=12")
+
+	assert.NotNil(t, err)
+	assert.Equal(t, "Error while parsing the header: unknown reference quantity potato", err.Error())
+}
+
+func TestGetRefDewPoint_HappyPath(t *testing.T) {
+	ref := NewRefTemperatureHumidity(70.0, 45.0)
+
+	assert.InDelta(t, 47.67, ref.GetRefDewPoint(), 0.01)
+}
+
+func TestGetDewPoint_SaturatedAir(t *testing.T) {
+	// At 100% relative humidity the dew point is the temperature itself
+	assert.InDelta(t, 70.0, GetDewPoint(70.0, 100), 1e-9)
+}
+
+func TestGetDewPoint_DryAir(t *testing.T) {
+	assert.True(t, math.IsNaN(GetDewPoint(70.0, 0)))
+	assert.True(t, math.IsNaN(GetDewPoint(70.0, -1)))
+}
+
 func TestExtractRefValues_RefTemperatureNotANumber(t *testing.T) {
 	line := "reference hello 42"
 
@@ -138,6 +217,16 @@ func TestAppendData_NotTheRightSensor(t *testing.T) {
 	assert.Nil(t, sensor.GetValues())
 }
 
+func TestAppendData_WrongNumberOfElements(t *testing.T) {
+	sensor := NewSensor("Potato", "Potato-sensor")
+
+	err := sensor.AppendData([]string{"2000-01-01T00:00:00", "Potato-sensor", "1.23", "4.56"})
+
+	assert.NotNil(t, err)
+	assert.Equal(t, "Data doesn't have the expected number of elements for device Potato-sensor", err.Error())
+	assert.Nil(t, sensor.GetValues())
+}
+
 func TestAppendData_BadDataType(t *testing.T) {
 	expectedType := "Potato"
 	expectedName := "Potato-sensor"
@@ -281,8 +370,9 @@ func TestCalculateRating_HappyPath_ThermometerUltraPrecise(t *testing.T) {
 	sensor.AppendData(lineData1)
 	sensor.AppendData(lineData2)
 
-	res := sensor.CalculateRating(refValues)
+	res, err := sensor.CalculateRating(refValues)
 
+	assert.Nil(t, err)
 	assert.NotNil(t, res)
 	assert.Equal(t, ThermometerUltraPrecise, res)
 }
@@ -300,8 +390,9 @@ func TestCalculateRating_HappyPath_ThermometerVeryPrecise(t *testing.T) {
 	sensor.AppendData(lineData1)
 	sensor.AppendData(lineData2)
 
-	res := sensor.CalculateRating(refValues)
+	res, err := sensor.CalculateRating(refValues)
 
+	assert.Nil(t, err)
 	assert.NotNil(t, res)
 	assert.Equal(t, ThermometerVeryPrecise, res)
 }
@@ -319,8 +410,9 @@ func TestCalculateRating_HappyPath_ThermometerPreciseCorrectAvgButHugeStdDev(t *
 	sensor.AppendData(lineData1)
 	sensor.AppendData(lineData2)
 
-	res := sensor.CalculateRating(refValues)
+	res, err := sensor.CalculateRating(refValues)
 
+	assert.Nil(t, err)
 	assert.NotNil(t, res)
 	assert.Equal(t, ThermometerPrecise, res)
 }
@@ -338,8 +430,9 @@ func TestCalculateRating_HappyPath_ThermometerPreciseCorrectStdDevButTotallyOff(
 	sensor.AppendData(lineData1)
 	sensor.AppendData(lineData2)
 
-	res := sensor.CalculateRating(refValues)
+	res, err := sensor.CalculateRating(refValues)
 
+	assert.Nil(t, err)
 	assert.NotNil(t, res)
 	assert.Equal(t, ThermometerPrecise, res)
 }
@@ -357,8 +450,9 @@ func TestCalculateRating_HappyPath_HumidityAccepted(t *testing.T) {
 	sensor.AppendData(lineData1)
 	sensor.AppendData(lineData2)
 
-	res := sensor.CalculateRating(refValues)
+	res, err := sensor.CalculateRating(refValues)
 
+	assert.Nil(t, err)
 	assert.NotNil(t, res)
 	assert.Equal(t, HumidityAccepted, res)
 }
@@ -376,12 +470,54 @@ func TestCalculateRating_HappyPath_HumidityRejected(t *testing.T) {
 	sensor.AppendData(lineData1)
 	sensor.AppendData(lineData2)
 
-	res := sensor.CalculateRating(refValues)
+	res, err := sensor.CalculateRating(refValues)
 
+	assert.Nil(t, err)
 	assert.NotNil(t, res)
 	assert.Equal(t, HumidityRejected, res)
 }
 
+func TestCalculateRating_Pressure(t *testing.T) {
+	ref := NewRefTemperatureHumidity(70.0, 45.0)
+	ref.SetRefPressure(1013.0)
+
+	accepted := &Sensor{sensorType: PressureSensor, sensorName: "press-1", sensorValues: []float64{1012.5, 1013.9}}
+	rejected := &Sensor{sensorType: PressureSensor, sensorName: "press-2", sensorValues: []float64{1012.5, 1014.1}}
+
+	acceptedRating, _ := accepted.CalculateRating(ref)
+	rejectedRating, _ := rejected.CalculateRating(ref)
+
+	assert.Equal(t, PressureAccepted, acceptedRating)
+	assert.Equal(t, PressureRejected, rejectedRating)
+}
+
+func TestCalculateRating_CO2(t *testing.T) {
+	ref := NewRefTemperatureHumidity(70.0, 45.0)
+	ref.SetRefCO2(1000.0)
+
+	// Accepted range is 50ppm + 3% of 1000ppm = 80ppm
+	accepted := &Sensor{sensorType: CO2Sensor, sensorName: "co2-1", sensorValues: []float64{930, 1080}}
+	rejected := &Sensor{sensorType: CO2Sensor, sensorName: "co2-2", sensorValues: []float64{1000, 1081}}
+
+	acceptedRating, _ := accepted.CalculateRating(ref)
+	rejectedRating, _ := rejected.CalculateRating(ref)
+
+	assert.Equal(t, CO2Accepted, acceptedRating)
+	assert.Equal(t, CO2Rejected, rejectedRating)
+}
+
+func TestCalculateRating_MissingPressureReference(t *testing.T) {
+	ref := NewRefTemperatureHumidity(70.0, 45.0)
+
+	sensor := &Sensor{sensorType: PressureSensor, sensorName: "press-1", sensorValues: []float64{1013}}
+
+	res, err := sensor.CalculateRating(ref)
+
+	assert.Equal(t, RatingError, res)
+	assert.NotNil(t, err)
+	assert.Equal(t, "Missing pressure reference for device press-1. Checking next sensor", err.Error())
+}
+
 func TestCalculateRating_WrongSensorType(t *testing.T) {
 	expectedType := "Potato"
 	expectedName := "Potato-sensor"
@@ -395,10 +531,11 @@ func TestCalculateRating_WrongSensorType(t *testing.T) {
 	sensor.AppendData(lineData1)
 	sensor.AppendData(lineData2)
 
-	res := sensor.CalculateRating(refValues)
+	res, err := sensor.CalculateRating(refValues)
 
-	assert.NotNil(t, res)
-	assert.Equal(t, "Invalid sensor type for type "+expectedType+". Checking next sensor", res)
+	assert.Equal(t, RatingError, res)
+	assert.NotNil(t, err)
+	assert.Equal(t, "Invalid sensor type for type "+expectedType+". Checking next sensor", err.Error())
 }
 
 func TestComputeResults(t *testing.T) {
@@ -411,28 +548,28 @@ func TestComputeResults(t *testing.T) {
 		sensorType:   Thermometer,
 		sensorName:   "temp-1",
 		sensorValues: []float64{72.4, 76.0, 79.1, 75.6, 71.2, 69.2, 65.2, 62.8, 61.4, 64.0, 67.5, 69.4},
-		sensorRating: "",
+		sensorRating: RatingUnknown,
 	}
 
 	sensor2 := &Sensor{
 		sensorType:   Thermometer,
 		sensorName:   "temp-2",
 		sensorValues: []float64{69.5, 70.1, 71.3, 71.5, 69.8},
-		sensorRating: "",
+		sensorRating: RatingUnknown,
 	}
 
 	sensor3 := &Sensor{
 		sensorType:   HumiditySensor,
 		sensorName:   "hum-1",
 		sensorValues: []float64{45.2, 45.3, 45.1},
-		sensorRating: "",
+		sensorRating: RatingUnknown,
 	}
 
 	sensor4 := &Sensor{
 		sensorType:   HumiditySensor,
 		sensorName:   "hum-2",
 		sensorValues: []float64{44.4, 43.9, 44.9, 43.8, 42.1},
-		sensorRating: "",
+		sensorRating: RatingUnknown,
 	}
 
 	sensors := []SensorInterface{sensor1, sensor2, sensor3, sensor4}
@@ -448,3 +585,19 @@ func TestComputeResults(t *testing.T) {
 	assert.NotNil(t, sensor4.sensorRating)
 	assert.Equal(t, HumidityRejected, sensor4.sensorRating)
 }
+
+func TestComputeResults_KeepsRatingError(t *testing.T) {
+	ref := NewRefTemperatureHumidity(70.0, 45.0)
+
+	sensor := &Sensor{
+		sensorType:   "Potato",
+		sensorName:   "Potato-sensor",
+		sensorValues: []float64{70.0},
+	}
+
+	ComputeResults([]SensorInterface{sensor}, ref)
+
+	assert.Equal(t, RatingError, sensor.GetRating())
+	assert.NotNil(t, sensor.GetRatingError())
+	assert.Equal(t, "Invalid sensor type for type Potato. Checking next sensor", sensor.GetRatingError().Error())
+}
@@ -0,0 +1,496 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+/**
+ * HTTP API
+ *   POST /analyze grades the log sent as the request body (space-separated, CSV
+ *   or JSON Lines, compressed or not) and answers with the JSON report. The format
+ *   is given by the "format" query parameter, else by the content type, else it's
+ *   detected from the first lines of the log.
+ *   GET /healthz tells load balancers the server is up.
+ *   When a job queue is set, POST /jobs saves the log as a job graded in the
+ *   background and answers with its ID, GET /jobs/{id} gives its status and result.
+ *   Rigs stream the lines of their log to POST /live/{rig}, dashboards follow the
+ *   provisional results with GET /live/{rig}/events (Server-Sent Events). Rigs which
+ *   didn't post anything yet are unknown.
+ *   Errors are answered as {"error": "..."} with a 4xx status.
+ */
+
+const DefaultListenAddress = ":8080"
+
+// How long requests being handled are waited for when the server stops
+const ServerShutdownTimeout = 30 * time.Second
+
+// Largest log accepted by POST /analyze, in bytes
+const DefaultMaxBodySize = 64 << 20
+
+// Largest log accepted by POST /jobs, in bytes: it's saved to disk rather than read in memory
+const DefaultMaxJobSize = 4 << 30
+
+// Largest request accepted by POST /live/{rig}, in bytes: its lines are read as they arrive rather than kept
+const DefaultMaxLiveSize = 1 << 30
+
+// Interval of the comments keeping the event streams open through proxies
+const LiveKeepAliveInterval = 15 * time.Second
+
+// Formats of the logs sent with these content types
+var contentTypeFormats = map[string]string{
+	"text/csv":             CSVFormat,
+	"application/jsonl":    JSONLinesFormat,
+	"application/x-ndjson": JSONLinesFormat,
+}
+
+type Server struct {
+	mapping     CSVColumnMapping
+	table       CalibrationTable
+	labels      RatingLabels
+	profile     string
+	maxBodySize int64
+	maxJobSize  int64
+	maxLiveSize int64
+	jobs        *JobQueue
+	live        *LiveHub
+
+	mux *http.ServeMux
+}
+
+type serverError struct {
+	Error string `json:"error"`
+}
+
+func NewServer() *Server {
+	server := &Server{
+		mapping:     DefaultCSVColumnMapping,
+		labels:      outputProfiles[DefaultProfile],
+		profile:     DefaultProfile,
+		maxBodySize: DefaultMaxBodySize,
+		maxJobSize:  DefaultMaxJobSize,
+		maxLiveSize: DefaultMaxLiveSize,
+		mux:         http.NewServeMux(),
+	}
+	server.live = NewLiveHub(server.report)
+
+	server.mux.HandleFunc("/analyze", server.handleAnalyze)
+	server.mux.HandleFunc("/healthz", server.handleHealth)
+	server.mux.HandleFunc("/jobs", server.handleSubmitJob)
+	server.mux.HandleFunc("/jobs/", server.handleGetJob)
+	server.mux.HandleFunc("/live/", server.handleLive)
+
+	return server
+}
+
+func (s *Server) SetCSVColumnMapping(mapping CSVColumnMapping) {
+	s.mapping = mapping
+	s.live.SetCSVColumnMapping(mapping)
+}
+
+func (s *Server) SetCalibrationTable(table CalibrationTable) {
+	s.table = table
+	s.live.SetCalibrationTable(table)
+}
+
+func (s *Server) SetLabels(labels RatingLabels) {
+	s.labels = labels
+}
+
+// Output profile recorded in the metadata of the reports
+func (s *Server) SetProfile(profile string) {
+	s.profile = profile
+}
+
+func (s *Server) SetMaxBodySize(maxBodySize int64) {
+	s.maxBodySize = maxBodySize
+}
+
+func (s *Server) SetMaxJobSize(maxJobSize int64) {
+	s.maxJobSize = maxJobSize
+}
+
+func (s *Server) SetMaxLiveSize(maxLiveSize int64) {
+	s.maxLiveSize = maxLiveSize
+}
+
+// Logs posted to /jobs are graded by this queue, jobs are disabled without it
+func (s *Server) SetJobQueue(jobs *JobQueue) {
+	s.jobs = jobs
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+/**
+ * Serving the API on the given address until the context is done
+ */
+func (s *Server) ListenAndServe(ctx context.Context, address string) error {
+	server := &http.Server{Addr: address, Handler: s}
+
+	listening := make(chan error, 1)
+	go func() {
+		listening <- server.ListenAndServe()
+	}()
+	logger.Debug(Translate("Listening on %s", address))
+
+	select {
+	case err := <-listening:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), ServerShutdownTimeout)
+	defer cancel()
+
+	return server.Shutdown(shutdownCtx)
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok", "version": Version})
+}
+
+/**
+ * Grading the log sent in the body of the request
+ */
+func (s *Server) handleAnalyze(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w, http.MethodPost)
+		return
+	}
+
+	format, err := getRequestFormat(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// Compressed logs are limited before and after they're decompressed
+	body := newLimitedBody(w, r.Body, s.maxBodySize)
+	var lines []string
+	input, err := Decompress(body)
+	decompressed := newLimitedStream(input, s.maxBodySize)
+	if err == nil {
+		lines, err = ScanLines(decompressed)
+	}
+	if body.TooLarge() {
+		writeError(w, http.StatusRequestEntityTooLarge, errors.New(Translate("The log is larger than %d bytes", s.maxBodySize)))
+		return
+	}
+	if decompressed.TooLarge() {
+		writeError(w, http.StatusRequestEntityTooLarge, errors.New(Translate("The decompressed log is larger than %d bytes", s.maxBodySize)))
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	document, err := s.grade(lines, format)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, document)
+}
+
+/**
+ * Saving the log sent in the body of the request as a job, graded in the background
+ */
+func (s *Server) handleSubmitJob(w http.ResponseWriter, r *http.Request) {
+	if s.jobs == nil {
+		writeError(w, http.StatusNotFound, errors.New(Translate("Jobs aren't enabled on this server")))
+		return
+	}
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w, http.MethodPost)
+		return
+	}
+
+	format, err := getRequestFormat(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	body := newLimitedBody(w, r.Body, s.maxJobSize)
+	job, err := s.jobs.Submit(body, format)
+	if body.TooLarge() {
+		writeError(w, http.StatusRequestEntityTooLarge, errors.New(Translate("The log is larger than %d bytes", s.maxJobSize)))
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Location", "/jobs/"+job.ID)
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+func (s *Server) handleGetJob(w http.ResponseWriter, r *http.Request) {
+	if s.jobs == nil {
+		writeError(w, http.StatusNotFound, errors.New(Translate("Jobs aren't enabled on this server")))
+		return
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	id := strings.TrimPrefix(r.URL.Path, "/jobs/")
+	job, found := s.jobs.GetJob(id)
+	if !found {
+		writeError(w, http.StatusNotFound, errors.New(Translate("No job %s", id)))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, job)
+}
+
+/**
+ * Routing the requests of a rig: its readings are posted, its status is read or
+ * followed, and it's reset to start a new run
+ */
+func (s *Server) handleLive(w http.ResponseWriter, r *http.Request) {
+	path := strings.Split(strings.TrimPrefix(r.URL.Path, "/live/"), "/")
+	rig, sensor := path[0], r.URL.Query().Get("sensor")
+	if rig == "" || len(path) > 2 || (len(path) == 2 && path[1] != "events") {
+		writeError(w, http.StatusNotFound, errors.New(Translate("No such page %s", r.URL.Path)))
+		return
+	}
+
+	if len(path) == 2 {
+		if r.Method != http.MethodGet {
+			writeMethodNotAllowed(w, http.MethodGet)
+			return
+		}
+		s.streamLiveStatus(w, r, rig, sensor)
+		return
+	}
+
+	// Rigs are created by posting their readings
+	unknownRig := errors.New(Translate("No rig %s, post its readings first", rig))
+	switch r.Method {
+	case http.MethodPost:
+		format, err := getRequestFormat(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		body := newLimitedBody(w, r.Body, s.maxLiveSize)
+		err = s.live.Ingest(rig, format, body)
+		if body.TooLarge() {
+			writeError(w, http.StatusRequestEntityTooLarge, errors.New(Translate("The log is larger than %d bytes", s.maxLiveSize)))
+			return
+		}
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		status, _ := s.live.GetStatus(rig, sensor)
+		writeJSON(w, http.StatusOK, status)
+	case http.MethodGet, http.MethodHead:
+		status, found := s.live.GetStatus(rig, sensor)
+		if !found {
+			writeError(w, http.StatusNotFound, unknownRig)
+			return
+		}
+		writeJSON(w, http.StatusOK, status)
+	case http.MethodDelete:
+		if !s.live.Reset(rig) {
+			writeError(w, http.StatusNotFound, unknownRig)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeMethodNotAllowed(w, "GET, POST, DELETE")
+	}
+}
+
+/**
+ * Pushing the status of a rig as Server-Sent Events until the client disconnects
+ */
+func (s *Server) streamLiveStatus(w http.ResponseWriter, r *http.Request, rig string, sensor string) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New(Translate("Streaming isn't supported")))
+		return
+	}
+
+	subscription, found := s.live.Subscribe(rig, sensor)
+	if !found {
+		writeError(w, http.StatusNotFound, errors.New(Translate("No rig %s, post its readings first", rig)))
+		return
+	}
+	defer s.live.Unsubscribe(rig, subscription)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(LiveKeepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case update := <-subscription.Updates:
+			fmt.Fprintf(w, "event: status\ndata: %s\n\n", update)
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		}
+		flusher.Flush()
+	}
+}
+
+/**
+ * Grading a job's log, streamed from disk
+ */
+func (s *Server) GradeFile(path string, format string) (ReportDocument, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return ReportDocument{}, err
+	}
+	defer file.Close()
+
+	ref, sensors, diagnostics, format, err := GradeLogStream(file, format, s.mapping, s.table)
+	if err != nil {
+		return ReportDocument{}, err
+	}
+
+	return s.report(ref, sensors, diagnostics, format), nil
+}
+
+func (s *Server) grade(lines []string, format string) (ReportDocument, error) {
+	ref, sensors, diagnostics, format, err := GradeLog(lines, format, s.mapping, s.table)
+	if err != nil {
+		return ReportDocument{}, err
+	}
+
+	return s.report(ref, sensors, diagnostics, format), nil
+}
+
+func (s *Server) report(ref ReferenceInterface, sensors []SensorInterface, diagnostics []Diagnostic, format string) ReportDocument {
+	metadata := NewRunMetadata(s.profile)
+	metadata.InputFormat = format
+
+	return NewReportDocument(sensors, ref, diagnostics, metadata, s.labels)
+}
+
+func getRequestFormat(r *http.Request) (string, error) {
+	if format := r.URL.Query().Get("format"); format != "" {
+		for _, known := range append(InputFormats, AutoFormat) {
+			if format == known {
+				return format, nil
+			}
+		}
+		return "", errors.New(Translate("Unknown input format %s", format))
+	}
+
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err == nil {
+		if format, found := contentTypeFormats[mediaType]; found {
+			return format, nil
+		}
+	}
+
+	return AutoFormat, nil
+}
+
+/**
+ * Body of a request limited by http.MaxBytesReader, counting the bytes read to tell
+ * whether it failed because the body is too large
+ */
+type limitedBody struct {
+	reader io.Reader
+	limit  int64
+	read   int64
+	failed bool
+}
+
+func newLimitedBody(w http.ResponseWriter, body io.ReadCloser, limit int64) *limitedBody {
+	return &limitedBody{reader: http.MaxBytesReader(w, body, limit), limit: limit}
+}
+
+// Same limit on any stream, e.g. a decompressed body
+func newLimitedStream(r io.Reader, limit int64) *limitedBody {
+	return &limitedBody{reader: &streamLimit{reader: r, limit: limit, left: limit}, limit: limit}
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.reader.Read(p)
+	b.read += int64(n)
+	if err != nil && err != io.EOF {
+		b.failed = true
+	}
+
+	return n, err
+}
+
+// The reader only fails once the whole limit was read when there's more to read
+func (b *limitedBody) TooLarge() bool {
+	return b.failed && b.read >= b.limit
+}
+
+// Reading up to a number of bytes, then failing if there's more to read
+type streamLimit struct {
+	reader io.Reader
+	limit  int64
+	left   int64
+}
+
+func (l *streamLimit) Read(p []byte) (int, error) {
+	if l.left <= 0 {
+		var next [1]byte
+		if n, err := l.reader.Read(next[:]); n == 0 {
+			return 0, err
+		}
+		return 0, errors.New(Translate("The log is larger than %d bytes", l.limit))
+	}
+
+	if int64(len(p)) > l.left {
+		p = p[:l.left]
+	}
+	n, err := l.reader.Read(p)
+	l.left -= int64(n)
+
+	return n, err
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(body); err != nil {
+		logger.Info(err.Error())
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, serverError{Error: err.Error()})
+}
+
+func writeMethodNotAllowed(w http.ResponseWriter, allowed string) {
+	w.Header().Set("Allow", allowed)
+	writeError(w, http.StatusMethodNotAllowed, errors.New(Translate("Method not allowed, expected %s", allowed)))
+}
@@ -0,0 +1,334 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func postLog(server *Server, target string, contentType string, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+
+	return rec
+}
+
+func TestServer_Healthz(t *testing.T) {
+	rec := httptest.NewRecorder()
+	NewServer().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Body.String(), `"status": "ok"`)
+}
+
+func TestServer_Analyze_RawLog(t *testing.T) {
+	server := NewServer()
+	server.SetProfile(SpecProfile)
+	rec := postLog(server, "/analyze", "text/plain", cliLog)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var document ReportDocument
+	assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &document))
+	assert.Equal(t, 45.0, document.Reference.Humidity)
+	assert.Len(t, document.Sensors, 2)
+	assert.Equal(t, "temp-1", document.Sensors[0].Name)
+	assert.Equal(t, RatingUltraPrecise, document.Sensors[0].Rating)
+	assert.Equal(t, "OK", document.Sensors[1].Label)
+
+	// Recording how the log was read
+	assert.Equal(t, SpecProfile, document.Metadata.Profile)
+	assert.Equal(t, LegacyFormat, document.Metadata.InputFormat)
+	assert.Equal(t, ".", document.Metadata.NumberFormat.DecimalSeparator)
+	assert.False(t, document.Metadata.StartedAt.IsZero())
+}
+
+func TestServer_Analyze_FormatFromContentType(t *testing.T) {
+	body := `{"kind": "reference", "temperature": 70.0, "humidity": 45.0}
+{"kind": "declaration", "type": "humidity", "sensor": "hum-1"}
+{"kind": "reading", "timestamp": "2007-04-05T22:00", "sensor": "hum-1", "value": 45.2}
+{"kind": "reading", "sensor": "hum-1", "value": 45.4}
+`
+	rec := postLog(NewServer(), "/analyze", "application/x-ndjson", body)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"name": "hum-1"`)
+
+	// The discarded record is reported with the results
+	var document ReportDocument
+	assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &document))
+	assert.Equal(t, []Diagnostic{{Line: 4, Message: "Missing field timestamp"}}, document.Diagnostics)
+	assert.Equal(t, JSONLinesFormat, document.Metadata.InputFormat)
+}
+
+func TestServer_Analyze_JSONContentTypeIsDetected(t *testing.T) {
+	// Clients send application/json for anything, it doesn't tell a JSON Lines log
+	rec := postLog(NewServer(), "/analyze", "application/json", cliLog)
+
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Contains(t, rec.Body.String(), `"input_format": "log"`)
+}
+
+func TestServer_Analyze_CSV(t *testing.T) {
+	body := "timestamp,sensor,type,value\n,temperature,reference,70.0\n,humidity,reference,45.0\n2007-04-05T22:00,temp-1,thermometer,70.1\n"
+	rec := postLog(NewServer(), "/analyze?format=csv", "", body)
+
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Contains(t, rec.Body.String(), `"name": "temp-1"`)
+}
+
+func TestServer_Analyze_Errors(t *testing.T) {
+	server := NewServer()
+
+	rec := postLog(server, "/analyze?format=xml", "", cliLog)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, "{\n  \"error\": \"Unknown input format xml\"\n}\n", rec.Body.String())
+
+	rec = postLog(server, "/analyze", "", "reference 70.0\n")
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+
+	// A corrupted log isn't mistaken for a large one
+	rec = postLog(server, "/analyze", "", string(gzipLog(t)[:20]))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	server.SetMaxBodySize(10)
+	rec = postLog(server, "/analyze", "", cliLog)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/analyze", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	assert.Equal(t, http.MethodPost, rec.Header().Get("Allow"))
+}
+
+func TestServer_Analyze_DecompressedTooLarge(t *testing.T) {
+	// A small compressed log can be large once decompressed
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	_, err := writer.Write([]byte(cliLog + strings.Repeat("# padding\n", 100000)))
+	assert.Nil(t, err)
+	assert.Nil(t, writer.Close())
+
+	server := NewServer()
+	server.SetMaxBodySize(64 << 10)
+	assert.Less(t, compressed.Len(), 64<<10)
+
+	rec := postLog(server, "/analyze", "", compressed.String())
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	assert.Contains(t, rec.Body.String(), fmt.Sprintf("The decompressed log is larger than %d bytes", 64<<10))
+
+	// Up to the limit
+	rec = postLog(server, "/analyze", "", string(gzipLog(t)))
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+}
+
+func TestServer_Jobs(t *testing.T) {
+	server := NewServer()
+	queue, err := OpenJobQueue(t.TempDir(), 1, server.GradeFile)
+	assert.Nil(t, err)
+	server.SetJobQueue(queue)
+	queue.Start()
+	defer queue.Stop()
+
+	rec := postLog(server, "/jobs", "text/plain", cliLog)
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	var job Job
+	assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &job))
+	assert.Equal(t, "/jobs/"+job.ID, rec.Header().Get("Location"))
+
+	waitFor(t, func() bool {
+		rec = httptest.NewRecorder()
+		server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/jobs/"+job.ID, nil))
+		return strings.Contains(rec.Body.String(), `"status": "done"`)
+	})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"name": "temp-1"`)
+	assert.Contains(t, rec.Body.String(), `"input_format": "log"`)
+
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/jobs/unknown", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestServer_JobsTooLarge(t *testing.T) {
+	dir := t.TempDir()
+	server := NewServer()
+	queue, _ := OpenJobQueue(dir, 1, server.GradeFile)
+	server.SetJobQueue(queue)
+	server.SetMaxJobSize(int64(len(cliLog)))
+
+	rec := postLog(server, "/jobs", "text/plain", cliLog+"\n")
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	assert.Contains(t, rec.Body.String(), fmt.Sprintf("The log is larger than %d bytes", len(cliLog)))
+	assert.Equal(t, []string{}, listFiles(t, dir))
+
+	// Up to the limit
+	rec = postLog(server, "/jobs", "text/plain", cliLog)
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+}
+
+func TestServer_JobsDisabled(t *testing.T) {
+	rec := postLog(NewServer(), "/jobs", "", cliLog)
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestServer_Live(t *testing.T) {
+	server := NewServer()
+
+	// Rigs are created by their first readings
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/live/rig-3", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	// The last line is cut, the rig completes it in the next request
+	rec = postLog(server, "/live/rig-3", "", "reference 70.0 45.0\nthermometer temp-1\n2007-04-05T22:00 temp-1 70.1\n2007-04-05T22:01 te")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var status LiveStatus
+	assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &status))
+	assert.Equal(t, 3, status.Lines)
+	assert.Equal(t, 1, status.Readings)
+	assert.Equal(t, "temp-1", status.Sensors[0].Name)
+
+	postLog(server, "/live/rig-3", "", "mp-1 69.9\n")
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/live/rig-3?sensor=temp-1", nil))
+	assert.Contains(t, rec.Body.String(), `"readings": 2`)
+	assert.Contains(t, rec.Body.String(), `"rating": "ultra precise"`)
+
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/live/rig-3", nil))
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	status, found := server.live.GetStatus("rig-3", "")
+	assert.True(t, found)
+	assert.Equal(t, 0, status.Lines)
+	assert.Nil(t, status.ReportDocument)
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/live/rig-3/stats", nil),
+		httptest.NewRequest(http.MethodDelete, "/live/rig-4", nil),
+		httptest.NewRequest(http.MethodGet, "/live/rig-4/events", nil),
+	} {
+		rec = httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	}
+}
+
+func TestServer_LiveMatchesBatch(t *testing.T) {
+	logs := []struct {
+		contentType string
+		log         string
+	}{
+		// Readings of a sensor logged in the block of another one are discarded by batch grading, and live
+		{"", "reference 70.0 45.0\nthermometer temp-1\n2007-04-05T22:00 temp-1 70.1\nthermometer temp-2\n2007-04-05T22:00 temp-2 70.0\n2007-04-05T22:01 temp-1 75.0\n"},
+		{"text/csv", "timestamp,sensor,type,value\n,temperature,reference,70.0\n,humidity,reference,45.0\n2007-04-05T22:00,temp-1,thermometer,70.1\n2007-04-05T22:01,temp-1,thermometer,hot\n2007-04-05T22:01,temp-1,thermometer\n2007-04-05T22:02,temp-1,thermometer,69.9\n"},
+		{"", "{\"kind\": \"reference\", \"temperature\": 70.0, \"humidity\": 45.0}\n{\"kind\": \"declaration\", \"type\": \"humidity\", \"sensor\": \"hum-1\"}\n{\"kind\": \"reading\"}\n{\"kind\": \"reading\", \"timestamp\": \"2007-04-05T22:00\", \"sensor\": \"hum-1\", \"value\": 45.2}\n"},
+	}
+
+	for _, c := range logs {
+		server := NewServer()
+
+		batch := postLog(server, "/analyze", c.contentType, c.log)
+		var document ReportDocument
+		assert.Nil(t, json.Unmarshal(batch.Body.Bytes(), &document))
+
+		// The lines are read as they arrive, the format being detected from the first ones
+		postLog(server, "/live/rig-3", c.contentType, c.log[:len(c.log)/2])
+		live := postLog(server, "/live/rig-3", c.contentType, c.log[len(c.log)/2:])
+		var status LiveStatus
+		assert.Nil(t, json.Unmarshal(live.Body.Bytes(), &status))
+		assert.NotNil(t, status.ReportDocument)
+		assert.Equal(t, document.Sensors, status.Sensors)
+		assert.Equal(t, document.Diagnostics, status.Diagnostics)
+		assert.Equal(t, document.Metadata.InputFormat, status.Metadata.InputFormat)
+	}
+}
+
+func TestServer_LiveFormatDetectedAgainOnReset(t *testing.T) {
+	server := NewServer()
+
+	postLog(server, "/live/rig-3", "", cliLog)
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/live/rig-3", nil))
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+
+	rec = postLog(server, "/live/rig-3", "", "timestamp,sensor,type,value\n,temperature,reference,70.0\n,humidity,reference,45.0\n2007-04-05T22:00,temp-1,thermometer,70.1\n")
+	var status LiveStatus
+	assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &status))
+	assert.Equal(t, CSVFormat, status.Metadata.InputFormat)
+	assert.Equal(t, 1, status.Readings)
+}
+
+func TestServer_LiveTooLarge(t *testing.T) {
+	server := NewServer()
+	server.SetMaxLiveSize(int64(len(cliLog)) - 1)
+
+	rec := postLog(server, "/live/rig-3", "", cliLog)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	assert.Contains(t, rec.Body.String(), fmt.Sprintf("The log is larger than %d bytes", len(cliLog)-1))
+
+	// The limit is per request, a rig can keep streaming in the next ones
+	rec = postLog(server, "/live/rig-3", "", cliLog[len(cliLog)/2:])
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestServer_LiveLineTooLong(t *testing.T) {
+	server := NewServer()
+
+	rec := postLog(server, "/live/rig-3", "", "reference 70.0 45.0\n# "+strings.Repeat("x", LiveMaxLineLength))
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), fmt.Sprintf("Line 2 is longer than %d bytes", LiveMaxLineLength))
+}
+
+func TestServer_LiveEvents(t *testing.T) {
+	server := NewServer()
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	postLog(server, "/live/rig-3", "", "")
+	res, err := ts.Client().Get(ts.URL + "/live/rig-3/events?sensor=temp-1")
+	assert.Nil(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, "text/event-stream", res.Header.Get("Content-Type"))
+
+	events := bufio.NewReader(res.Body)
+	readEvent := func() string {
+		var event string
+		for {
+			line, err := events.ReadString('\n')
+			assert.Nil(t, err)
+			if line == "\n" {
+				return event
+			}
+			event += line
+		}
+	}
+
+	// The current status is pushed on subscription, then as readings arrive
+	assert.Equal(t, "event: status\ndata: {\"lines\":0,\"readings\":0}\n", readEvent())
+
+	postLog(server, "/live/rig-3", "", cliLog)
+	event := readEvent()
+	// Statuses pushed while the log was read may come first, the last one is pushed once it's read
+	for !strings.Contains(event, `"lines":7`) {
+		event = readEvent()
+	}
+	assert.Contains(t, event, `"readings":2`)
+	assert.Contains(t, event, `"name":"temp-1"`)
+	assert.NotContains(t, event, `"name":"hum-1"`)
+	assert.Contains(t, event, `"rating":"ultra precise"`)
+}
@@ -0,0 +1,335 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+/**
+ * Spool directory
+ *   Rig PCs drop finished logs into an inbox directory. A log is graded once it's
+ *   complete: either a "<log>.done" marker was dropped next to it, or its size and
+ *   modification time didn't change for the settle delay. The reports are written
+ *   next to the log, which is then moved with them to the archive folder, or to the
+ *   failed folder with the error when it couldn't be graded. A log which can't be
+ *   moved out of the inbox isn't graded again until it changes.
+ */
+const SpoolDoneMarker = ".done"
+const SpoolErrorExtension = ".error.txt"
+const SpoolArchiveFolder = "archive"
+const SpoolFailedFolder = "failed"
+
+const DefaultSpoolSettle = 10 * time.Second
+
+type Spooler struct {
+	inbox   string
+	archive string
+	failed  string
+	settle  time.Duration
+
+	format  string
+	mapping CSVColumnMapping
+	table   CalibrationTable
+	labels  RatingLabels
+	profile string
+	formats []string
+
+	// Size and modification time of the logs last time they were seen
+	pending map[string]spoolFile
+	// Same for the logs processed which couldn't be moved out of the inbox
+	processed map[string]spoolFile
+}
+
+type spoolFile struct {
+	size    int64
+	modTime time.Time
+	since   time.Time
+}
+
+func NewSpooler(inbox string) *Spooler {
+	return &Spooler{
+		inbox:     inbox,
+		archive:   filepath.Join(inbox, SpoolArchiveFolder),
+		failed:    filepath.Join(inbox, SpoolFailedFolder),
+		settle:    DefaultSpoolSettle,
+		format:    AutoFormat,
+		mapping:   DefaultCSVColumnMapping,
+		labels:    outputProfiles[DefaultProfile],
+		profile:   DefaultProfile,
+		formats:   []string{TextReport},
+		pending:   make(map[string]spoolFile),
+		processed: make(map[string]spoolFile),
+	}
+}
+
+func (s *Spooler) SetFolders(archive string, failed string) {
+	s.archive, s.failed = archive, failed
+}
+
+// How long the size of a log must stay the same before it's considered complete
+func (s *Spooler) SetSettle(settle time.Duration) {
+	s.settle = settle
+}
+
+func (s *Spooler) SetInputFormat(format string, mapping CSVColumnMapping) {
+	s.format, s.mapping = format, mapping
+}
+
+func (s *Spooler) SetCalibrationTable(table CalibrationTable) {
+	s.table = table
+}
+
+func (s *Spooler) SetLabels(labels RatingLabels) {
+	s.labels = labels
+}
+
+// Output profile recorded in the metadata of the reports
+func (s *Spooler) SetProfile(profile string) {
+	s.profile = profile
+}
+
+func (s *Spooler) SetReportFormats(formats []string) {
+	s.formats = formats
+}
+
+/**
+ * Checking the inbox every interval until the context is done
+ */
+func (s *Spooler) Run(ctx context.Context, interval time.Duration) error {
+	for _, folder := range []string{s.archive, s.failed} {
+		if err := os.MkdirAll(folder, 0755); err != nil {
+			return err
+		}
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.Scan(time.Now()); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+/**
+ * Going through the inbox once, grading the logs which are complete
+ */
+func (s *Spooler) Scan(now time.Time) error {
+	entries, err := ioutil.ReadDir(s.inbox)
+	if err != nil {
+		return err
+	}
+
+	names := make(map[string]bool)
+	for _, entry := range entries {
+		names[entry.Name()] = true
+	}
+
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		name := entry.Name()
+
+		// Folders, hidden files (e.g. being copied), markers and files written by the spooler aren't logs
+		if entry.IsDir() || strings.HasPrefix(name, ".") || strings.HasSuffix(name, SpoolDoneMarker) || isSpoolOutput(name) {
+			continue
+		}
+		seen[name] = true
+
+		if previous, found := s.processed[name]; found && previous.isSame(entry) {
+			continue
+		}
+		delete(s.processed, name)
+
+		if names[name+SpoolDoneMarker] || s.isSettled(name, entry, now) {
+			delete(s.pending, name)
+			s.Process(name)
+		}
+	}
+
+	// Forget the logs which were removed
+	for name := range s.pending {
+		if !seen[name] {
+			delete(s.pending, name)
+		}
+	}
+	for name := range s.processed {
+		if !seen[name] {
+			delete(s.processed, name)
+		}
+	}
+
+	return nil
+}
+
+// Reports and error files left in the inbox when they couldn't be moved
+func isSpoolOutput(name string) bool {
+	if strings.HasSuffix(name, SpoolErrorExtension) {
+		return true
+	}
+	for _, extension := range reportExtensions {
+		if strings.HasSuffix(name, extension) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *Spooler) isSettled(name string, entry os.FileInfo, now time.Time) bool {
+	previous, found := s.pending[name]
+	if !found || !previous.isSame(entry) {
+		s.pending[name] = spoolFile{size: entry.Size(), modTime: entry.ModTime(), since: now}
+		return false
+	}
+
+	return now.Sub(previous.since) >= s.settle
+}
+
+func (f spoolFile) isSame(entry os.FileInfo) bool {
+	return f.size == entry.Size() && f.modTime.Equal(entry.ModTime())
+}
+
+/**
+ * Grading a log of the inbox and moving it to the archive or failed folder. Returns
+ * whether it was graded
+ */
+func (s *Spooler) Process(name string) bool {
+	path := filepath.Join(s.inbox, name)
+	marker := path + SpoolDoneMarker
+
+	// The log is left alone when it can't be moved, as long as it's the one processed
+	if info, err := os.Stat(path); err == nil {
+		defer s.checkMoved(name, info)
+	}
+
+	reports, err := s.grade(path)
+	if err != nil {
+		logger.Info(Translate("Can't grade %s: %s", name, err))
+		s.moveToFailed(name, err)
+		os.Remove(marker)
+		return false
+	}
+
+	// The log and its reports keep the same names in the archive
+	prefix := getMovePrefix(s.archive, append([]string{path}, reports...), time.Now())
+	if err := moveFile(path, s.archive, prefix); err != nil {
+		logger.Info(err.Error())
+		// The reports would be taken for logs, they're written again once the log changes
+		for _, report := range reports {
+			os.Remove(report)
+		}
+		return true
+	}
+	for _, report := range reports {
+		if err := moveFile(report, s.archive, prefix); err != nil {
+			logger.Info(err.Error())
+		}
+	}
+	os.Remove(marker)
+
+	logger.Debug(Translate("%s graded and archived", name))
+	return true
+}
+
+func (s *Spooler) checkMoved(name string, info os.FileInfo) {
+	if _, err := os.Stat(filepath.Join(s.inbox, name)); err != nil {
+		return
+	}
+
+	logger.Info(Translate("%s can't be moved out of the inbox, it won't be graded again until it changes", name))
+	s.processed[name] = spoolFile{size: info.Size(), modTime: info.ModTime()}
+}
+
+func (s *Spooler) grade(path string) ([]string, error) {
+	lines, err := ReadLogFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := NewRunMetadata(s.profile)
+	ref, sensors, diagnostics, format, err := GradeLog(lines, s.format, s.mapping, s.table)
+	if err != nil {
+		return nil, err
+	}
+	metadata.InputFormat = format
+
+	var reports []string
+	for _, format := range s.formats {
+		reportPath := path + GetReportExtension(format)
+		if err := writeReportFile(reportPath, format, sensors, ref, diagnostics, metadata, s.labels); err != nil {
+			// Don't leave partial results next to a failed log
+			for _, report := range append(reports, reportPath) {
+				os.Remove(report)
+			}
+			return nil, err
+		}
+		reports = append(reports, reportPath)
+	}
+
+	return reports, nil
+}
+
+func (s *Spooler) moveToFailed(name string, gradeErr error) {
+	errorPath := filepath.Join(s.inbox, name+SpoolErrorExtension)
+	if err := ioutil.WriteFile(errorPath, []byte(gradeErr.Error()+"\n"), 0644); err != nil {
+		logger.Info(err.Error())
+	}
+
+	path := filepath.Join(s.inbox, name)
+	prefix := getMovePrefix(s.failed, []string{path, errorPath}, time.Now())
+	if err := moveFile(path, s.failed, prefix); err != nil {
+		logger.Info(err.Error())
+		os.Remove(errorPath)
+		return
+	}
+	if err := moveFile(errorPath, s.failed, prefix); err != nil {
+		logger.Info(err.Error())
+	}
+}
+
+func writeReportFile(path string, format string, sensors []SensorInterface, ref ReferenceInterface, diagnostics []Diagnostic, metadata RunMetadata, labels RatingLabels) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := WriteReport(file, format, sensors, ref, diagnostics, metadata, labels); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+/**
+ * Prefix of the files of a log moved to a folder: the same log can be dropped several
+ * times, so when one of its files is already there they're all prefixed with the time
+ */
+func getMovePrefix(folder string, paths []string, now time.Time) string {
+	for _, path := range paths {
+		if _, err := os.Stat(filepath.Join(folder, filepath.Base(path))); err == nil {
+			return now.Format("20060102T150405.000000000-")
+		}
+	}
+
+	return ""
+}
+
+func moveFile(path string, folder string, prefix string) error {
+	if err := os.MkdirAll(folder, 0755); err != nil {
+		return err
+	}
+
+	return os.Rename(path, filepath.Join(folder, prefix+filepath.Base(path)))
+}
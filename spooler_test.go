@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Names of the files of a folder, sorted
+func listFiles(t *testing.T, folder string) []string {
+	entries, err := ioutil.ReadDir(folder)
+	assert.Nil(t, err)
+
+	names := []string{}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func newTestSpooler(t *testing.T) (*Spooler, string) {
+	inbox := t.TempDir()
+	spooler := NewSpooler(inbox)
+	spooler.SetSettle(time.Minute)
+	spooler.SetReportFormats([]string{TextReport, JSONReport})
+
+	return spooler, inbox
+}
+
+func TestSpooler_Scan_StableSize(t *testing.T) {
+	spooler, inbox := newTestSpooler(t)
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(inbox, "run.log"), []byte(cliLog), 0644))
+	now := time.Now()
+
+	// The log could still be written until its size didn't change for the settle delay
+	assert.Nil(t, spooler.Scan(now))
+	assert.Nil(t, spooler.Scan(now.Add(30*time.Second)))
+	assert.Equal(t, []string{"run.log"}, listFiles(t, inbox))
+
+	assert.Nil(t, spooler.Scan(now.Add(time.Minute)))
+	assert.Equal(t, []string{}, listFiles(t, inbox))
+	assert.Equal(t, []string{"run.log", "run.log.report.json", "run.log.results.txt"}, listFiles(t, filepath.Join(inbox, SpoolArchiveFolder)))
+
+	results, _ := ioutil.ReadFile(filepath.Join(inbox, SpoolArchiveFolder, "run.log.results.txt"))
+	assert.Equal(t, "temp-1: ultra precise\nhum-1: OK\n", string(results))
+
+	report, _ := ioutil.ReadFile(filepath.Join(inbox, SpoolArchiveFolder, "run.log.report.json"))
+	assert.Contains(t, string(report), `"input_format": "log"`)
+}
+
+func TestSpooler_Scan_GrowingLog(t *testing.T) {
+	spooler, inbox := newTestSpooler(t)
+	path := filepath.Join(inbox, "run.log")
+	assert.Nil(t, ioutil.WriteFile(path, []byte(cliLog[:20]), 0644))
+	now := time.Now()
+
+	assert.Nil(t, spooler.Scan(now))
+	assert.Nil(t, ioutil.WriteFile(path, []byte(cliLog), 0644))
+	assert.Nil(t, spooler.Scan(now.Add(time.Minute)))
+
+	// The settle delay starts over when the log grows
+	assert.Equal(t, []string{"run.log"}, listFiles(t, inbox))
+}
+
+func TestSpooler_Scan_DoneMarker(t *testing.T) {
+	spooler, inbox := newTestSpooler(t)
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(inbox, "run.log"), []byte(cliLog), 0644))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(inbox, "run.log.done"), nil, 0644))
+
+	assert.Nil(t, spooler.Scan(time.Now()))
+
+	assert.Equal(t, []string{}, listFiles(t, inbox))
+	assert.Equal(t, []string{"run.log", "run.log.report.json", "run.log.results.txt"}, listFiles(t, filepath.Join(inbox, SpoolArchiveFolder)))
+}
+
+func TestSpooler_Process_Failed(t *testing.T) {
+	spooler, inbox := newTestSpooler(t)
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(inbox, "run.log"), []byte("thermometer temp-1\n"), 0644))
+
+	assert.False(t, spooler.Process("run.log"))
+
+	failed := filepath.Join(inbox, SpoolFailedFolder)
+	assert.Equal(t, []string{}, listFiles(t, inbox))
+	assert.Equal(t, []string{"run.log", "run.log.error.txt"}, listFiles(t, failed))
+	message, _ := ioutil.ReadFile(filepath.Join(failed, "run.log.error.txt"))
+	assert.NotEqual(t, "", string(message))
+}
+
+func TestSpooler_Process_SameNameArchived(t *testing.T) {
+	spooler, inbox := newTestSpooler(t)
+	spooler.SetReportFormats([]string{TextReport})
+
+	for i := 0; i < 2; i++ {
+		assert.Nil(t, ioutil.WriteFile(filepath.Join(inbox, "run.log"), []byte(cliLog), 0644))
+		assert.True(t, spooler.Process("run.log"))
+	}
+
+	// The first log and its report are kept, the second ones have the same prefix
+	archived := listFiles(t, filepath.Join(inbox, SpoolArchiveFolder))
+	assert.Len(t, archived, 4)
+	prefix := strings.TrimSuffix(archived[0], "run.log")
+	assert.NotEqual(t, "", prefix)
+	assert.Equal(t, []string{prefix + "run.log", prefix + "run.log.results.txt", "run.log", "run.log.results.txt"}, archived)
+}
+
+func TestSpooler_Scan_ReportLeftInInbox(t *testing.T) {
+	spooler, inbox := newTestSpooler(t)
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(inbox, "run.log.results.txt"), []byte("temp-1: ultra precise\n"), 0644))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(inbox, "run.log.report.json"), []byte("{}\n"), 0644))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(inbox, "run.log.error.txt"), []byte("Empty log\n"), 0644))
+	now := time.Now()
+
+	assert.Nil(t, spooler.Scan(now))
+	assert.Nil(t, spooler.Scan(now.Add(time.Minute)))
+
+	// They aren't graded as logs
+	assert.Equal(t, []string{"run.log.error.txt", "run.log.report.json", "run.log.results.txt"}, listFiles(t, inbox))
+	assert.NoDirExists(t, filepath.Join(inbox, SpoolFailedFolder))
+}
+
+func TestSpooler_Scan_LogWhichCantBeMoved(t *testing.T) {
+	spooler, inbox := newTestSpooler(t)
+	// The archive can't be created under a file
+	blocker := filepath.Join(t.TempDir(), "blocker")
+	assert.Nil(t, ioutil.WriteFile(blocker, nil, 0644))
+	spooler.SetFolders(filepath.Join(blocker, SpoolArchiveFolder), filepath.Join(inbox, SpoolFailedFolder))
+
+	var logs bytes.Buffer
+	defer SetLogger(GetLogger())
+	SetLogger(NewLogger(&logs, LogInfo))
+
+	log := filepath.Join(inbox, "run.log")
+	assert.Nil(t, ioutil.WriteFile(log, []byte(cliLog), 0644))
+	assert.Nil(t, ioutil.WriteFile(log+SpoolDoneMarker, nil, 0644))
+	now := time.Now()
+
+	// Graded once, the log is left in the inbox without its reports
+	assert.Nil(t, spooler.Scan(now))
+	assert.Equal(t, []string{"run.log", "run.log.done"}, listFiles(t, inbox))
+	assert.Equal(t, 1, strings.Count(logs.String(), "run.log can't be moved out of the inbox"))
+
+	assert.Nil(t, spooler.Scan(now.Add(time.Minute)))
+	assert.Nil(t, spooler.Scan(now.Add(2*time.Minute)))
+	assert.Equal(t, 1, strings.Count(logs.String(), "run.log can't be moved out of the inbox"))
+
+	// Until it changes
+	assert.Nil(t, ioutil.WriteFile(log, []byte(cliLog+"\n"), 0644))
+	assert.Nil(t, spooler.Scan(now.Add(3*time.Minute)))
+	assert.Equal(t, 2, strings.Count(logs.String(), "run.log can't be moved out of the inbox"))
+}
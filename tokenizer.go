@@ -0,0 +1,83 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+)
+
+// Comments start with this character at the beginning of a token and run until the end of the line
+const CommentChar = '#'
+
+/**
+ * Splitting a log line into tokens
+ *   - tokens are separated by any amount of whitespace (spaces, tabs, trailing CR
+ *     from Windows-generated logs...)
+ *   - a token can be quoted with double quotes to contain whitespace ("temp 1"),
+ *     a backslash escapes the next character inside quotes
+ *   - blank lines and comments give no tokens
+ */
+func Tokenize(line string) ([]string, error) {
+	var tokens []string
+	var current strings.Builder
+
+	inToken := false
+	inQuotes := false
+	escaped := false
+
+	for _, r := range line {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+
+		case inQuotes && r == '\\':
+			escaped = true
+
+		case r == '"':
+			// Quotes can open a token or be closed anywhere in it
+			inQuotes = !inQuotes
+			inToken = true
+
+		case inQuotes:
+			current.WriteRune(r)
+
+		case unicode.IsSpace(r):
+			if inToken {
+				tokens = append(tokens, current.String())
+				current.Reset()
+				inToken = false
+			}
+
+		case r == CommentChar && !inToken:
+			return tokens, nil
+
+		default:
+			current.WriteRune(r)
+			inToken = true
+		}
+	}
+
+	if inQuotes {
+		return nil, errors.New(Translate("Unterminated quote in line: %s", line))
+	}
+
+	if inToken {
+		tokens = append(tokens, current.String())
+	}
+
+	return tokens, nil
+}
+
+/**
+ * Writing a token so Tokenize reads it back as it is: tokens with whitespace,
+ * quotes, backslashes or starting a comment are quoted
+ */
+func QuoteToken(token string) string {
+	if token != "" && !strings.ContainsAny(token, "\"\\") && token[0] != CommentChar && strings.IndexFunc(token, unicode.IsSpace) < 0 {
+		return token
+	}
+
+	escaped := strings.NewReplacer("\\", "\\\\", "\"", "\\\"").Replace(token)
+	return "\"" + escaped + "\""
+}
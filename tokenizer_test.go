@@ -0,0 +1,96 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenize_SingleSpaces(t *testing.T) {
+	res, err := Tokenize("2007-04-05T22:00 temp-1 72.4")
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"2007-04-05T22:00", "temp-1", "72.4"}, res)
+}
+
+func TestTokenize_ArbitraryWhitespace(t *testing.T) {
+	res, err := Tokenize("  2007-04-05T22:00\t\ttemp-1  \t 72.4   ")
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"2007-04-05T22:00", "temp-1", "72.4"}, res)
+}
+
+func TestTokenize_TrailingCR(t *testing.T) {
+	res, err := Tokenize("thermometer temp-1\r")
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"thermometer", "temp-1"}, res)
+}
+
+func TestTokenize_BlankLine(t *testing.T) {
+	res, err := Tokenize(" \t \r")
+
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(res))
+}
+
+func TestTokenize_CommentLine(t *testing.T) {
+	res, err := Tokenize("# rig 3, chamber B")
+
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(res))
+}
+
+func TestTokenize_TrailingComment(t *testing.T) {
+	res, err := Tokenize("2007-04-05T22:00 temp-1 72.4 # door opened")
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"2007-04-05T22:00", "temp-1", "72.4"}, res)
+}
+
+func TestTokenize_HashInsideToken(t *testing.T) {
+	res, err := Tokenize("thermometer temp#1")
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"thermometer", "temp#1"}, res)
+}
+
+func TestTokenize_QuotedName(t *testing.T) {
+	res, err := Tokenize("2007-04-05T22:00 \"temp 1 # left\" 72.4")
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"2007-04-05T22:00", "temp 1 # left", "72.4"}, res)
+}
+
+func TestTokenize_EscapedQuote(t *testing.T) {
+	res, err := Tokenize(`thermometer "the \"best\" one"`)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"thermometer", `the "best" one`}, res)
+}
+
+func TestTokenize_EmptyQuotedToken(t *testing.T) {
+	res, err := Tokenize(`thermometer ""`)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"thermometer", ""}, res)
+}
+
+func TestTokenize_UnterminatedQuote(t *testing.T) {
+	_, err := Tokenize("thermometer \"temp 1")
+
+	assert.NotNil(t, err)
+	assert.Equal(t, "Unterminated quote in line: thermometer \"temp 1", err.Error())
+}
+
+func TestQuoteToken(t *testing.T) {
+	assert.Equal(t, "temp-1", QuoteToken("temp-1"))
+	assert.Equal(t, `"temp 1"`, QuoteToken("temp 1"))
+	assert.Equal(t, `""`, QuoteToken(""))
+
+	for _, token := range []string{"temp 1", `probe "B"`, `rack\3`, "#7", "", "tab\there"} {
+		tokens, err := Tokenize(QuoteToken(token) + " 1.0")
+		assert.Nil(t, err)
+		assert.Equal(t, []string{token, "1.0"}, tokens)
+	}
+}
@@ -0,0 +1,388 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+/**
+ * Interactive mode
+ *   Bench technicians paste or open a log, review the table of sensors with their
+ *   statistics and ratings, drill into a sensor to see its readings, and export the
+ *   report. The screen is redrawn after each command, typed on a line of its own,
+ *   so it works in any terminal without switching it to raw mode. What's logged
+ *   meanwhile would be cleared with the screen, it's shown under the status line.
+ */
+
+// Line ending a pasted log, besides Ctrl+]
+const PasteEndLine = "."
+
+// Number of readings listed per line in the sensor view
+const ReadingsPerLine = 8
+
+// Number of logged messages shown under the status line, the last ones
+const LogLinesShown = 5
+
+const ansiClearScreen = "\x1b[H\x1b[2J"
+const ansiGreen = "\x1b[32m"
+const ansiRed = "\x1b[31m"
+const ansiReset = "\x1b[0m"
+
+var sparklineTicks = []rune("▁▂▃▄▅▆▇█")
+
+type TUI struct {
+	in  *bufio.Scanner
+	out io.Writer
+	// Clearing the screen and colors only make sense in a terminal
+	ansi bool
+
+	format  string
+	mapping CSVColumnMapping
+	table   CalibrationTable
+	labels  RatingLabels
+
+	ref         ReferenceInterface
+	sensors     []SensorInterface
+	diagnostics []Diagnostic
+	selected    SensorInterface
+	message     string
+	// Logged since the last command
+	logs bytes.Buffer
+}
+
+// Logger writing its messages to the screen of the TUI
+type tuiLogger struct {
+	tui   *TUI
+	level LogLevel
+}
+
+func NewTUI(in io.Reader, out io.Writer, ansi bool) *TUI {
+	return &TUI{
+		in:      bufio.NewScanner(in),
+		out:     out,
+		ansi:    ansi,
+		format:  AutoFormat,
+		mapping: DefaultCSVColumnMapping,
+		labels:  outputProfiles[DefaultProfile],
+	}
+}
+
+/**
+ * Logger to use while the TUI is shown: messages written to stderr would be cleared
+ * when the screen is redrawn
+ */
+func NewTUILogger(tui *TUI, level LogLevel) LoggerInterface {
+	return &tuiLogger{tui: tui, level: level}
+}
+
+func (t *TUI) SetInputFormat(format string, mapping CSVColumnMapping) {
+	t.format, t.mapping = format, mapping
+}
+
+func (t *TUI) SetCalibrationTable(table CalibrationTable) {
+	t.table = table
+}
+
+func (t *TUI) SetLabels(labels RatingLabels) {
+	t.labels = labels
+}
+
+func (t *TUI) GetSensors() []SensorInterface {
+	return t.sensors
+}
+
+/**
+ * Showing the screen and running the commands until the user quits or the input ends
+ */
+func (t *TUI) Run() error {
+	for {
+		t.render()
+
+		fmt.Fprint(t.out, "> ")
+		if !t.in.Scan() {
+			fmt.Fprintln(t.out)
+			return t.in.Err()
+		}
+
+		if quit := t.handle(t.in.Text()); quit {
+			return nil
+		}
+	}
+}
+
+/**
+ * Loading a log, the table of its sensors is shown
+ */
+func (t *TUI) Load(lines []string) {
+	t.selected = nil
+
+	ref, sensors, diagnostics, format, err := GradeLog(lines, t.format, t.mapping, t.table)
+	if err != nil {
+		t.message = err.Error()
+		return
+	}
+
+	t.ref, t.sensors, t.diagnostics = ref, sensors, diagnostics
+	t.message = Translate("Loaded %d sensors (%s format)", len(sensors), format)
+	if len(diagnostics) > 0 {
+		t.message = Translate("Loaded %d sensors (%s format), %d records discarded", len(sensors), format, len(diagnostics))
+	}
+}
+
+func (t *TUI) handle(line string) bool {
+	t.message = ""
+	t.logs.Reset()
+
+	words, err := Tokenize(line)
+	if err != nil {
+		t.message = err.Error()
+		return false
+	}
+	if len(words) == 0 {
+		return false
+	}
+
+	command, args := words[0], words[1:]
+	switch {
+	case command == "quit" || command == "q":
+		return true
+	case command == "paste":
+		t.Load(t.readPastedLog())
+	case command == "open" && len(args) == 1:
+		lines, err := ReadLogFile(args[0])
+		if err != nil {
+			t.message = err.Error()
+			return false
+		}
+		t.Load(lines)
+	case command == "show" && len(args) == 1:
+		t.selectSensor(args[0])
+	case command == "back":
+		t.selected = nil
+	case command == "export" && len(args) == 1:
+		t.export(args[0])
+	case len(args) == 0 && t.sensors != nil && isSensorNumber(command):
+		t.selectSensor(command)
+	default:
+		t.message = Translate("Unknown command %s", line)
+	}
+
+	return false
+}
+
+func (t *TUI) readPastedLog() []string {
+	fmt.Fprintln(t.out, Translate("Paste the log, then end it with a line containing only \"%s\" (or Ctrl+])", PasteEndLine))
+
+	var lines []string
+	for t.in.Scan() {
+		line := t.in.Text()
+		if line == PasteEndLine || line == "\x1D" {
+			break
+		}
+		lines = append(lines, line)
+	}
+
+	return lines
+}
+
+// Sensors are selected by their number in the table or by their name
+func (t *TUI) selectSensor(nameOrNumber string) {
+	if number, err := strconv.Atoi(nameOrNumber); err == nil && number >= 1 && number <= len(t.sensors) {
+		t.selected = t.sensors[number-1]
+		return
+	}
+
+	for _, sensor := range t.sensors {
+		if sensor.GetName() == nameOrNumber {
+			t.selected = sensor
+			return
+		}
+	}
+
+	t.message = Translate("No sensor %s in the log", nameOrNumber)
+}
+
+func (t *TUI) export(path string) {
+	if t.sensors == nil {
+		t.message = Translate("Load a log before exporting its report")
+		return
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		t.message = err.Error()
+		return
+	}
+
+	PrintReport(file, t.sensors, t.ref, t.labels)
+	PrintDiagnostics(file, t.diagnostics)
+	if err := file.Close(); err != nil {
+		t.message = err.Error()
+		return
+	}
+
+	t.message = Translate("Report exported to %s", path)
+}
+
+func (t *TUI) render() {
+	if t.ansi {
+		fmt.Fprint(t.out, ansiClearScreen)
+	}
+
+	switch {
+	case t.sensors == nil:
+		fmt.Fprintln(t.out, Translate("No log loaded"))
+	case t.selected != nil:
+		t.renderSensor(t.selected)
+	default:
+		t.renderTable()
+	}
+
+	fmt.Fprintln(t.out)
+	if t.message != "" {
+		fmt.Fprintln(t.out, t.message)
+	}
+	t.renderLogs()
+
+	switch {
+	case t.sensors == nil:
+		fmt.Fprintln(t.out, Translate("Commands: paste | open <file> | quit"))
+	case t.selected != nil:
+		fmt.Fprintln(t.out, Translate("Commands: back | <number> or show <sensor> | export <file> | paste | open <file> | quit"))
+	default:
+		fmt.Fprintln(t.out, Translate("Commands: <number> or show <sensor> | export <file> | paste | open <file> | quit"))
+	}
+}
+
+func (t *TUI) renderLogs() {
+	if t.logs.Len() == 0 {
+		return
+	}
+
+	lines := strings.Split(strings.TrimRight(t.logs.String(), "\n"), "\n")
+	if len(lines) > LogLinesShown {
+		fmt.Fprintln(t.out, Translate("%d more messages logged", len(lines)-LogLinesShown))
+		lines = lines[len(lines)-LogLinesShown:]
+	}
+	for _, line := range lines {
+		fmt.Fprintln(t.out, line)
+	}
+}
+
+func (t *TUI) renderTable() {
+	fmt.Fprintln(t.out, Translate("Ref. Temperature is %s | Ref. Humidity is %s", FormatNumber(t.ref.GetRefTemperature(), 2), FormatNumber(t.ref.GetRefHumidity(), 2)))
+	fmt.Fprintln(t.out)
+
+	w := tabwriter.NewWriter(t.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, Translate("#\tSensor\tType\tReadings\tAverage\tStd. deviation\tRating"))
+	for i, sensor := range t.sensors {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%d\t%s\t%s\t%s\n",
+			i+1,
+			sensor.GetName(),
+			sensor.GetType(),
+			len(sensor.GetValues()),
+			FormatNumber(sensor.GetAverageValue(), 2),
+			FormatStandardDeviation(len(sensor.GetValues()), sensor.GetStandardDeviation(), 2),
+			t.ratingText(sensor))
+	}
+	w.Flush()
+}
+
+func (t *TUI) renderSensor(sensor SensorInterface) {
+	fmt.Fprintf(t.out, "%s (%s): %s\n\n", sensor.GetName(), sensor.GetType(), t.ratingText(sensor))
+
+	values := sensor.GetValues()
+	PrintStatistics(t.out, sensor, t.ref, "")
+	fmt.Fprintln(t.out)
+
+	fmt.Fprintf(t.out, "  %s\n\n", Sparkline(values))
+
+	for i := 0; i < len(values); i += ReadingsPerLine {
+		end := i + ReadingsPerLine
+		if end > len(values) {
+			end = len(values)
+		}
+
+		formatted := make([]string, 0, ReadingsPerLine)
+		for _, value := range values[i:end] {
+			formatted = append(formatted, FormatNumber(value, 2))
+		}
+		fmt.Fprintf(t.out, "  %s\n", strings.Join(formatted, "  "))
+	}
+}
+
+func (t *TUI) ratingText(sensor SensorInterface) string {
+	rating := sensor.GetRating()
+	if rating.IsError() {
+		return t.colored(sensor.GetRatingError().Error(), ansiRed)
+	}
+
+	if rating.IsPass() {
+		return t.colored(t.labels.Label(rating), ansiGreen)
+	}
+
+	return t.colored(t.labels.Label(rating), ansiRed)
+}
+
+func (t *TUI) colored(text string, color string) string {
+	if !t.ansi {
+		return text
+	}
+
+	return color + text + ansiReset
+}
+
+/**
+ * Drawing the values as a line of bars, from the lowest to the highest value
+ */
+func Sparkline(values []float64) string {
+	min, max := math.Inf(1), math.Inf(-1)
+	for _, value := range values {
+		min = math.Min(min, value)
+		max = math.Max(max, value)
+	}
+
+	ticks := make([]rune, len(values))
+	for i, value := range values {
+		index := len(sparklineTicks) / 2
+		if max > min {
+			index = int((value - min) / (max - min) * float64(len(sparklineTicks)-1))
+		}
+		ticks[i] = sparklineTicks[index]
+	}
+
+	return string(ticks)
+}
+
+func isSensorNumber(word string) bool {
+	_, err := strconv.Atoi(word)
+	return err == nil
+}
+
+func (l *tuiLogger) Info(message string) {
+	fmt.Fprintln(l.Writer(LogInfo), message)
+}
+
+func (l *tuiLogger) Debug(message string) {
+	fmt.Fprintln(l.Writer(LogDebug), message)
+}
+
+func (l *tuiLogger) GetLevel() LogLevel {
+	return l.level
+}
+
+func (l *tuiLogger) Writer(level LogLevel) io.Writer {
+	if level > l.level {
+		return ioutil.Discard
+	}
+
+	return &l.tui.logs
+}
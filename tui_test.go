@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSparkline(t *testing.T) {
+	assert.Equal(t, "▁▅█▁", Sparkline([]float64{1, 2, 2.5, 1}))
+	assert.Equal(t, "▅▅", Sparkline([]float64{3, 3}))
+	assert.Equal(t, "", Sparkline(nil))
+}
+
+func TestTUI_PasteAndDrillDown(t *testing.T) {
+	in := strings.NewReader("paste\n" + cliLog + ".\n1\nback\nshow hum-1\nquit\n")
+	var out bytes.Buffer
+
+	tui := NewTUI(in, &out, false)
+	assert.Nil(t, tui.Run())
+
+	screens := strings.Split(out.String(), "\n> ")
+	assert.Equal(t, 6, len(screens))
+	assert.Contains(t, screens[0], "No log loaded\n")
+
+	// Table of sensors
+	assert.Contains(t, screens[1], "Loaded 2 sensors (log format)\n")
+	assert.Contains(t, screens[1], "1  temp-1  thermometer  2         70.00    0.14            ultra precise\n")
+	assert.Contains(t, screens[1], "2  hum-1   humidity     2         45.30    0.14            OK\n")
+
+	// Sensor view
+	assert.Contains(t, screens[2], "temp-1 (thermometer): ultra precise\n")
+	assert.Contains(t, screens[2], "  █▁\n")
+	assert.Contains(t, screens[2], "  70.10  69.90\n")
+
+	assert.Contains(t, screens[3], "1  temp-1")
+	assert.Contains(t, screens[4], "hum-1 (humidity): OK\n")
+}
+
+func TestTUI_OpenAndExport(t *testing.T) {
+	dir := t.TempDir()
+	input := writeLog(t, "run.log", cliLog)
+	report := filepath.Join(dir, "report.txt")
+	in := strings.NewReader("open " + input + "\nexport \"" + report + "\"\n")
+	var out bytes.Buffer
+
+	tui := NewTUI(in, &out, false)
+	assert.Nil(t, tui.Run())
+
+	assert.Equal(t, 2, len(tui.GetSensors()))
+	assert.Contains(t, out.String(), "Report exported to "+report+"\n")
+	res, _ := ioutil.ReadFile(report)
+	assert.True(t, strings.HasPrefix(string(res), "temp-1 (thermometer): ultra precise\n"))
+}
+
+func TestTUI_Errors(t *testing.T) {
+	in := strings.NewReader("export report.txt\nopen " + filepath.Join(t.TempDir(), "missing.log") + "\npaste\npotato\n.\nshow temp-9\ndance\n")
+	var out bytes.Buffer
+
+	tui := NewTUI(in, &out, false)
+	assert.Nil(t, tui.Run())
+
+	assert.Contains(t, out.String(), "Load a log before exporting its report\n")
+	assert.Contains(t, out.String(), "missing.log: no such file or directory\n")
+	assert.Contains(t, out.String(), "Can't detect the format of the log")
+	assert.Contains(t, out.String(), "No sensor temp-9 in the log\n")
+	assert.Contains(t, out.String(), "Unknown command dance\n")
+}
+
+func TestTUI_ANSI(t *testing.T) {
+	in := strings.NewReader("paste\n" + cliLog + "\x1D\nquit\n")
+	var out bytes.Buffer
+
+	tui := NewTUI(in, &out, true)
+	assert.Nil(t, tui.Run())
+
+	assert.True(t, strings.HasPrefix(out.String(), ansiClearScreen))
+	assert.Contains(t, out.String(), ansiGreen+"ultra precise"+ansiReset)
+}
+
+func TestRunCLI_Interactive(t *testing.T) {
+	input := writeLog(t, "run.log", cliLog)
+	var stdout, stderr bytes.Buffer
+
+	code := RunCLI([]string{"interactive", "-input", input, "-profile", CustomerProfile}, strings.NewReader("2\nquit\n"), &stdout, &stderr)
+
+	assert.Equal(t, 0, code)
+	assert.Contains(t, stdout.String(), "hum-1 (humidity): Certified\n")
+}
+
+func TestTUI_Logs(t *testing.T) {
+	log := strings.Replace(cliLog, "2007-04-05T22:01 temp-1 69.9", "2007-04-05T22:01 temp-1 wet", 1)
+	in := strings.NewReader("paste\n" + log + ".\nquit\n")
+	var out bytes.Buffer
+
+	tui := NewTUI(in, &out, false)
+	defer SetLogger(GetLogger())
+	SetLogger(NewTUILogger(tui, LogInfo))
+	assert.Nil(t, tui.Run())
+
+	// Shown under the status line until the next command
+	screens := strings.Split(out.String(), "\n> ")
+	assert.Contains(t, screens[1], "Loaded 2 sensors (log format)\nError while parsing the recorded measure for devide temp-1 :")
+	assert.NotContains(t, screens[2], "Error while parsing")
+}
+
+func TestTUI_LogsShownLast(t *testing.T) {
+	var out bytes.Buffer
+	tui := NewTUI(strings.NewReader(""), &out, false)
+	logger := NewTUILogger(tui, LogInfo)
+
+	for i := 1; i <= LogLinesShown+2; i++ {
+		logger.Info(fmt.Sprintf("message %d", i))
+	}
+	logger.Debug("not logged")
+	tui.render()
+
+	assert.Contains(t, out.String(), "2 more messages logged\nmessage 3\n")
+	assert.Contains(t, out.String(), "message 7\n")
+	assert.NotContains(t, out.String(), "not logged")
+}
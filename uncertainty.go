@@ -0,0 +1,105 @@
+package main
+
+import (
+	"math"
+)
+
+/**
+ * Measurement uncertainty
+ *   The budget follows the GUM approach: every contribution is converted to a
+ *   standard uncertainty, contributions are combined in quadrature and the result
+ *   is expanded with a coverage factor k=2 (~95% confidence level).
+ */
+
+// Coverage factor used for the expanded uncertainty
+const UncertaintyCoverageFactor = 2
+
+// Default resolution (smallest displayed increment) and drift (maximum expected
+// drift between two calibrations) per device type
+const ThermometerDefaultResolution = 0.1
+const ThermometerDefaultDrift = 0.1
+const HumidityDefaultResolution = 0.1
+const HumidityDefaultDrift = 0.1
+const PressureDefaultResolution = 0.1
+const PressureDefaultDrift = 0.5
+const CO2DefaultResolution = 1
+const CO2DefaultDrift = 10
+
+// Default standard uncertainties of the reference barometer and CO2 analyzer, used
+// when the log doesn't state them
+const PressureDefaultRefUncertainty = 0.2
+const CO2DefaultRefUncertainty = 5
+
+type UncertaintyBudget struct {
+	// Standard uncertainties (k=1) of each contribution
+	Reference     float64
+	Repeatability float64
+	Resolution    float64
+	Drift         float64
+
+	// Combined standard uncertainty and expanded uncertainty
+	Combined       float64
+	CoverageFactor float64
+	Expanded       float64
+}
+
+func (s *Sensor) GetUncertaintyBudget(ref ReferenceInterface) UncertaintyBudget {
+	var refUncertainty float64
+
+	switch s.sensorType {
+	case Thermometer:
+		refUncertainty = ref.GetRefTemperatureUncertainty()
+	case HumiditySensor:
+		refUncertainty = ref.GetRefHumidityUncertainty()
+	case PressureSensor:
+		refUncertainty = ref.GetRefPressureUncertainty()
+	case CO2Sensor:
+		refUncertainty = ref.GetRefCO2Uncertainty()
+	}
+
+	// Repeatability is the experimental standard deviation of a single reading (type A
+	// evaluation): a unit shows one reading, not the mean of a run. It can't be estimated
+	// with less than 2 readings
+	var repeatability float64
+	if len(s.sensorValues) > 1 {
+		repeatability = s.GetStandardDeviation()
+	}
+
+	return computeUncertaintyBudget(refUncertainty, repeatability, s.sensorResolution, s.sensorDrift)
+}
+
+func computeUncertaintyBudget(refUncertainty float64, repeatability float64, resolution float64, drift float64) UncertaintyBudget {
+	budget := UncertaintyBudget{
+		Reference:     refUncertainty,
+		Repeatability: repeatability,
+		// Resolution and drift are bounds: we assume a rectangular distribution.
+		// The resolution bound is half of the displayed increment
+		Resolution:     resolution / (2 * math.Sqrt(3)),
+		Drift:          drift / math.Sqrt(3),
+		CoverageFactor: UncertaintyCoverageFactor,
+	}
+
+	budget.Combined = math.Sqrt(
+		math.Pow(budget.Reference, 2) +
+			math.Pow(budget.Repeatability, 2) +
+			math.Pow(budget.Resolution, 2) +
+			math.Pow(budget.Drift, 2))
+	budget.Expanded = budget.CoverageFactor * budget.Combined
+
+	return budget
+}
+
+func getDefaultResolutionAndDrift(sType string) (float64, float64) {
+	switch sType {
+	case Thermometer:
+		return ThermometerDefaultResolution, ThermometerDefaultDrift
+	case HumiditySensor:
+		return HumidityDefaultResolution, HumidityDefaultDrift
+	case PressureSensor:
+		return PressureDefaultResolution, PressureDefaultDrift
+	case CO2Sensor:
+		return CO2DefaultResolution, CO2DefaultDrift
+	}
+
+	return 0, 0
+}
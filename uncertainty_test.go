@@ -0,0 +1,100 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSensor_DefaultResolutionAndDrift(t *testing.T) {
+	thermometer := NewSensor(Thermometer, "temp-1")
+	humidity := NewSensor(HumiditySensor, "hum-1")
+	unknown := NewSensor("Potato", "Potato-sensor")
+
+	assert.Equal(t, ThermometerDefaultResolution, thermometer.GetResolution())
+	assert.Equal(t, ThermometerDefaultDrift, thermometer.GetDrift())
+	assert.Equal(t, HumidityDefaultResolution, humidity.GetResolution())
+	assert.Equal(t, HumidityDefaultDrift, humidity.GetDrift())
+	assert.Equal(t, float64(0), unknown.GetResolution())
+	assert.Equal(t, float64(0), unknown.GetDrift())
+}
+
+func TestGetUncertaintyBudget_HappyPathThermometer(t *testing.T) {
+	ref := NewRefTemperatureHumidity(70.0, 45.0)
+	ref.SetRefTemperatureUncertainty(0.05)
+	ref.SetRefHumidityUncertainty(1)
+
+	sensor := NewSensor(Thermometer, "temp-1")
+	sensor.SetResolution(0.2)
+	sensor.SetDrift(0.3)
+	sensor.AppendData([]string{"2000-01-01T00:00:00", "temp-1", "69"})
+	sensor.AppendData([]string{"2000-01-01T00:00:00", "temp-1", "71"})
+
+	res := sensor.GetUncertaintyBudget(ref)
+
+	// The standard deviation of a single reading, not of the mean
+	expectedRepeatability := sensor.GetStandardDeviation()
+	expectedResolution := 0.2 / (2 * math.Sqrt(3))
+	expectedDrift := 0.3 / math.Sqrt(3)
+	expectedCombined := math.Sqrt(0.05*0.05 + expectedRepeatability*expectedRepeatability + expectedResolution*expectedResolution + expectedDrift*expectedDrift)
+
+	assert.Equal(t, 0.05, res.Reference)
+	assert.InDelta(t, math.Sqrt(2), res.Repeatability, 1e-9)
+	assert.InDelta(t, expectedResolution, res.Resolution, 1e-12)
+	assert.InDelta(t, expectedDrift, res.Drift, 1e-12)
+	assert.InDelta(t, expectedCombined, res.Combined, 1e-12)
+	assert.Equal(t, float64(UncertaintyCoverageFactor), res.CoverageFactor)
+	assert.InDelta(t, 2*expectedCombined, res.Expanded, 1e-12)
+}
+
+func TestGetUncertaintyBudget_ExampleLog(t *testing.T) {
+	ref := NewRefTemperatureHumidity(70.0, 45.0)
+
+	sensor := NewSensor(Thermometer, "temp-1")
+	for _, value := range []string{"72.4", "76.0", "79.1", "75.6", "71.2", "71.4", "69.2", "65.2", "62.8", "61.4", "64.0", "67.5", "69.4"} {
+		sensor.AppendData([]string{"2007-04-05T22:00", "temp-1", value})
+	}
+
+	// temp-1 of the README: the readings spread over about 5.4 degrees
+	assert.InDelta(t, 10.8, sensor.GetUncertaintyBudget(ref).Expanded, 0.01)
+}
+
+func TestGetUncertaintyBudget_UsesHumidityReference(t *testing.T) {
+	ref := NewRefTemperatureHumidity(70.0, 45.0)
+	ref.SetRefTemperatureUncertainty(0.05)
+	ref.SetRefHumidityUncertainty(0.8)
+
+	sensor := NewSensor(HumiditySensor, "hum-1")
+
+	res := sensor.GetUncertaintyBudget(ref)
+
+	assert.Equal(t, 0.8, res.Reference)
+}
+
+func TestGetUncertaintyBudget_SingleReading(t *testing.T) {
+	ref := NewRefTemperatureHumidity(70.0, 45.0)
+
+	sensor := NewSensor(Thermometer, "temp-1")
+	sensor.SetResolution(0)
+	sensor.SetDrift(0)
+	sensor.AppendData([]string{"2000-01-01T00:00:00", "temp-1", "69"})
+
+	res := sensor.GetUncertaintyBudget(ref)
+
+	// No repeatability can be estimated from a single reading
+	assert.Equal(t, float64(0), res.Repeatability)
+	assert.Equal(t, float64(0), res.Combined)
+	assert.Equal(t, float64(0), res.Expanded)
+}
+
+func TestGetUncertaintyBudget_PressureAndCO2References(t *testing.T) {
+	ref, err := ExtractRef("reference 70.0 45.0 pressure=1013.25 co2=400 co2_uncertainty=8")
+	assert.Nil(t, err)
+
+	// The reference barometer has a default uncertainty, the CO2 analyzer the one of the log
+	pressure := NewSensor(PressureSensor, "baro-1")
+	assert.Equal(t, float64(PressureDefaultRefUncertainty), pressure.GetUncertaintyBudget(ref).Reference)
+	co2 := NewSensor(CO2Sensor, "co2-1")
+	assert.Equal(t, 8.0, co2.GetUncertaintyBudget(ref).Reference)
+}
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+/**
+ * Watch mode
+ *   Rigs append to the log during a soak, so readings of all the units are
+ *   interleaved: a reading can be for any sensor declared before it. The log is
+ *   tailed, the statistics of each sensor are updated as readings arrive and the
+ *   provisional ratings are printed periodically.
+ *   Units which can no longer reach their target are flagged so they can be pulled
+ *   early: a reading out of range can't be undone for humidity, pressure and CO2
+ *   sensors, and the standard deviation of a thermometer can't go below the one of
+ *   the readings so far spread over the expected number of readings.
+ */
+
+// How often the file is checked for new lines once all of them were read
+const WatchPollInterval = 500 * time.Millisecond
+
+// Target of thermometers (and of the temperature channel of combo sensors)
+const DefaultWatchTarget = RatingUltraPrecise
+
+type Watcher struct {
+	out    io.Writer
+	labels RatingLabels
+	table  CalibrationTable
+
+	target           Rating
+	expectedReadings int
+
+	ref           ReferenceInterface
+	sensors       []SensorInterface
+	sensorsByName map[string]SensorInterface
+	nbrReadings   int
+}
+
+func NewWatcher(out io.Writer, labels RatingLabels) *Watcher {
+	watcher := &Watcher{
+		out:    out,
+		labels: labels,
+		target: DefaultWatchTarget,
+	}
+	watcher.Reset()
+
+	return watcher
+}
+
+func (w *Watcher) SetTarget(target Rating) {
+	w.target = target
+}
+
+// Number of readings each sensor will have at the end of the run, 0 when it's unknown
+func (w *Watcher) SetExpectedReadings(expectedReadings int) {
+	w.expectedReadings = expectedReadings
+}
+
+func (w *Watcher) SetCalibrationTable(table CalibrationTable) {
+	w.table = table
+}
+
+func (w *Watcher) GetSensors() []SensorInterface {
+	return w.sensors
+}
+
+// Forgetting what was read, e.g. when the log was truncated to start a new run
+func (w *Watcher) Reset() {
+	w.ref = nil
+	w.sensors = nil
+	w.sensorsByName = make(map[string]SensorInterface)
+	w.nbrReadings = 0
+}
+
+/**
+ * Reading a new line of the log
+ */
+func (w *Watcher) ReadLine(line string) {
+	data, err := Tokenize(line)
+	if err != nil {
+		logger.Info(err.Error())
+		return
+	}
+
+	// Blank lines, comments and metadata
+	if len(data) == 0 || data[0] == MetadataKeyword {
+		return
+	}
+
+	switch {
+	case data[0] == ReferenceKeyword:
+		ref, err := ExtractRef(line)
+		if err != nil {
+			logger.Info(err.Error())
+			return
+		}
+		w.ref = ref
+	case len(data) == 2:
+		w.declare(data[0], data[1])
+	default:
+		w.addReading(data)
+	}
+}
+
+func (w *Watcher) declare(sType string, name string) {
+	if _, found := w.sensorsByName[name]; found {
+		return
+	}
+
+	sensor := NewSensor(sType, name)
+	w.table.Calibrate(sensor)
+
+	w.sensors = append(w.sensors, sensor)
+	w.sensorsByName[name] = sensor
+}
+
+func (w *Watcher) addReading(data []string) {
+	if len(data) < 2 {
+		logger.Info(Translate("Unrecognized line"))
+		return
+	}
+
+	sensor, found := w.sensorsByName[data[1]]
+	if !found {
+		logger.Info(Translate("Reading for sensor %s before any sensor declaration", data[1]))
+		return
+	}
+
+	if err := sensor.AppendData(data); err != nil {
+		logger.Info(err.Error())
+		return
+	}
+
+	w.nbrReadings++
+}
+
+/**
+ * Printing the provisional rating of each sensor
+ */
+func (w *Watcher) PrintStatus(now time.Time) {
+	fmt.Fprintln(w.out, Translate("Provisional ratings at %s (%d readings)", now.Format("15:04:05"), w.nbrReadings))
+
+	if w.ref == nil {
+		fmt.Fprintln(w.out, Translate("  waiting for the reference line"))
+		return
+	}
+
+	for _, sensor := range w.sensors {
+		stats := getWatchStats(sensor)
+		if stats.GetCount() == 0 {
+			fmt.Fprintln(w.out, Translate("  %s: no readings yet", sensor.GetName()))
+			continue
+		}
+
+		rating, err := sensor.CalculateRating(w.ref)
+		if err != nil {
+			fmt.Fprintf(w.out, "  %s: %s\n", sensor.GetName(), err)
+			continue
+		}
+
+		status := Translate("  %s: %s | %d readings | average %s | standard deviation %s", sensor.GetName(), w.labels.Label(rating), stats.GetCount(), FormatNumber(stats.GetMean(), 2), FormatStandardDeviation(stats.GetCount(), stats.GetStandardDeviation(), 2))
+		if !w.CanReachTarget(sensor) {
+			status += Translate(" | can't reach %s, pull the unit", w.labels.Label(w.getTarget(sensor)))
+		}
+		fmt.Fprintln(w.out, status)
+	}
+}
+
+/**
+ * Telling whether a sensor can still end the run with its target rating
+ */
+func (w *Watcher) CanReachTarget(sensor SensorInterface) bool {
+	switch s := sensor.(type) {
+	case *CombinedSensor:
+		return w.canReachTarget(s.temperature) && w.canReachTarget(s.humidity)
+	case *Sensor:
+		return w.canReachTarget(s)
+	}
+
+	return true
+}
+
+// Statistics of the corrected readings, kept by the sensor: the temperature channel of combo sensors
+func getWatchStats(sensor SensorInterface) *RunningStats {
+	switch s := sensor.(type) {
+	case *CombinedSensor:
+		return s.temperature.GetStats()
+	case *Sensor:
+		return s.GetStats()
+	}
+
+	return &RunningStats{}
+}
+
+func (w *Watcher) canReachTarget(sensor *Sensor) bool {
+	if sensor.GetType() != Thermometer {
+		// Accepted or rejected depends on the worst reading: a rejection is final
+		rating, err := sensor.CalculateRating(w.ref)
+		return err != nil || rating != RatingRejected
+	}
+
+	if w.expectedReadings == 0 {
+		// Any standard deviation can still be reached
+		return true
+	}
+
+	return getThermometerTier(sensor.GetStats().GetMinFinalStandardDeviation(w.expectedReadings)).IsAtLeast(w.target)
+}
+
+func (w *Watcher) getTarget(sensor SensorInterface) Rating {
+	if sensor.GetType() == Thermometer || sensor.GetType() == ComboSensor {
+		return w.target
+	}
+
+	return RatingAccepted
+}
+
+/**
+ * Tailing the log until the context is done. The status is printed every interval,
+ * and a last time before returning
+ */
+func (w *Watcher) Watch(ctx context.Context, path string, interval time.Duration) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	var offset int64
+	var partial string
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		line, err := reader.ReadString('\n')
+		offset += int64(len(line))
+
+		if err == nil {
+			w.ReadLine(partial + line)
+			partial = ""
+
+			// Keep printing the status when the log grows faster than it's read, and stop when asked to
+			select {
+			case <-ctx.Done():
+				w.PrintStatus(time.Now())
+				return nil
+			case now := <-ticker.C:
+				w.PrintStatus(now)
+			default:
+			}
+			continue
+		}
+
+		if err != io.EOF {
+			return err
+		}
+
+		// The last line is still being written
+		partial += line
+
+		select {
+		case <-ctx.Done():
+			w.PrintStatus(time.Now())
+			return nil
+		case now := <-ticker.C:
+			w.PrintStatus(now)
+		case <-time.After(WatchPollInterval):
+		}
+
+		// A log shorter than what was read was truncated to start a new run
+		if info, err := file.Stat(); err == nil && info.Size() < offset {
+			logger.Info(Translate("%s was truncated, reading it again", path))
+			if _, err := file.Seek(0, io.SeekStart); err != nil {
+				return err
+			}
+			reader.Reset(file)
+			offset, partial = 0, ""
+			w.Reset()
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var watchTime = time.Date(2007, 4, 5, 22, 30, 0, 0, time.UTC)
+
+func newTestWatcher(out *bytes.Buffer) *Watcher {
+	labels, _ := GetRatingLabels(SpecProfile)
+	return NewWatcher(out, labels)
+}
+
+func TestWatcher_InterleavedReadings(t *testing.T) {
+	var out bytes.Buffer
+	watcher := newTestWatcher(&out)
+
+	for _, line := range []string{
+		"reference 70.0 45.0",
+		"thermometer temp-1",
+		"humidity hum-1",
+		"2007-04-05T22:00 temp-1 70.1",
+		"2007-04-05T22:00 hum-1 45.2",
+		"2007-04-05T22:01 temp-1 69.9",
+		"2007-04-05T22:01 hum-9 45.2",
+		"2007-04-05T22:01 hum-1 wet",
+	} {
+		watcher.ReadLine(line)
+	}
+	watcher.PrintStatus(watchTime)
+
+	assert.Equal(t, `Provisional ratings at 22:30:00 (3 readings)
+  temp-1: ultra precise | 2 readings | average 70.00 | standard deviation 0.14
+  hum-1: OK | 1 readings | average 45.20 | standard deviation n/a
+`, out.String())
+}
+
+func TestWatcher_WaitingForData(t *testing.T) {
+	var out bytes.Buffer
+	watcher := newTestWatcher(&out)
+
+	watcher.PrintStatus(watchTime)
+	watcher.ReadLine("reference 70.0 45.0")
+	watcher.ReadLine("thermometer temp-1")
+	watcher.PrintStatus(watchTime)
+
+	assert.Equal(t, `Provisional ratings at 22:30:00 (0 readings)
+  waiting for the reference line
+Provisional ratings at 22:30:00 (0 readings)
+  temp-1: no readings yet
+`, out.String())
+}
+
+func TestWatcher_CanReachTarget(t *testing.T) {
+	var out bytes.Buffer
+	watcher := newTestWatcher(&out)
+	watcher.SetExpectedReadings(101)
+
+	for _, line := range []string{
+		"reference 70.0 45.0 pressure=1013.0",
+		"thermometer steady",
+		"thermometer noisy",
+		"humidity hum-1",
+		"pressure press-1",
+		"combo combo-1",
+		"2007-04-05T22:00 steady 70.1",
+		"2007-04-05T22:01 steady 69.9",
+		"2007-04-05T22:00 noisy 50",
+		"2007-04-05T22:01 noisy 90",
+		"2007-04-05T22:02 noisy 50",
+		"2007-04-05T22:03 noisy 90",
+		"2007-04-05T22:00 hum-1 46.0",
+		"2007-04-05T22:00 press-1 1016.0",
+		"2007-04-05T22:01 press-1 1013.0",
+		"2007-04-05T22:00 combo-1 70.0 50.0",
+	} {
+		watcher.ReadLine(line)
+	}
+
+	sensors := watcher.GetSensors()
+	assert.True(t, watcher.CanReachTarget(sensors[0]))
+	// The standard deviation of noisy can't go below 4 over 101 readings: very precise at best
+	assert.False(t, watcher.CanReachTarget(sensors[1]))
+	assert.False(t, watcher.CanReachTarget(sensors[2]))
+	assert.False(t, watcher.CanReachTarget(sensors[3]))
+	assert.False(t, watcher.CanReachTarget(sensors[4]))
+
+	watcher.SetTarget(RatingVeryPrecise)
+	assert.True(t, watcher.CanReachTarget(sensors[1]))
+
+	// Without the expected number of readings, any standard deviation can still be reached
+	watcher.SetTarget(RatingUltraPrecise)
+	watcher.SetExpectedReadings(0)
+	assert.True(t, watcher.CanReachTarget(sensors[1]))
+
+	watcher.PrintStatus(watchTime)
+	assert.Contains(t, out.String(), "  press-1: discard | 2 readings | average 1014.50 | standard deviation 2.12 | can't reach OK, pull the unit\n")
+	assert.Contains(t, out.String(), "  combo-1: discard | 1 readings | average 70.00 | standard deviation n/a | can't reach ultra precise, pull the unit\n")
+}
+
+// Buffer shared by the watcher and the test
+type syncBuffer struct {
+	mutex  sync.Mutex
+	buffer bytes.Buffer
+}
+
+func (sb *syncBuffer) Write(p []byte) (int, error) {
+	sb.mutex.Lock()
+	defer sb.mutex.Unlock()
+	return sb.buffer.Write(p)
+}
+
+func (sb *syncBuffer) String() string {
+	sb.mutex.Lock()
+	defer sb.mutex.Unlock()
+	return sb.buffer.String()
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestWatcher_Watch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "soak.log")
+	assert.Nil(t, os.WriteFile(path, []byte("reference 70.0 45.0\nthermometer temp-1\n2007-04-05T22:00 temp-1 70.1\n2007-04-05T22:01 temp"), 0644))
+
+	var out syncBuffer
+	labels, _ := GetRatingLabels(SpecProfile)
+	watcher := NewWatcher(&out, labels)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- watcher.Watch(ctx, path, 20*time.Millisecond)
+	}()
+
+	waitFor(t, func() bool { return strings.Contains(out.String(), "(1 readings)") })
+
+	// The rig finishes writing the line
+	file, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	file.WriteString("-1 69.9\n")
+	file.Close()
+
+	waitFor(t, func() bool { return strings.Contains(out.String(), "(2 readings)") })
+
+	// A new run starts in the same file
+	assert.Nil(t, os.WriteFile(path, []byte("reference 70.0 45.0\n"), 0644))
+	waitFor(t, func() bool { return strings.Contains(out.String(), "(0 readings)") })
+
+	cancel()
+	assert.Nil(t, <-done)
+}
+
+func TestWatcher_WatchStopsWhileReading(t *testing.T) {
+	log := []string{"reference 70.0 45.0", "thermometer temp-1"}
+	for i := 0; i < 1000; i++ {
+		log = append(log, "2007-04-05T22:00 temp-1 70.1")
+	}
+	path := filepath.Join(t.TempDir(), "soak.log")
+	assert.Nil(t, os.WriteFile(path, []byte(strings.Join(log, "\n")+"\n"), 0644))
+
+	var out bytes.Buffer
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// Asked to stop before reaching the end of the log
+	assert.Nil(t, newTestWatcher(&out).Watch(ctx, path, time.Hour))
+	assert.Contains(t, out.String(), "(0 readings)")
+}
+
+func TestWatcher_WatchMissingFile(t *testing.T) {
+	var out bytes.Buffer
+
+	err := newTestWatcher(&out).Watch(context.Background(), filepath.Join(t.TempDir(), "missing.log"), time.Second)
+
+	assert.NotNil(t, err)
+}